@@ -67,6 +67,7 @@ func main() {
 	srv := rpc.New()
 	defer srv.Close()
 	pb.RegisterGoStoreServer(s, srv)
+	pb.RegisterGoStoreAdminServer(s, srv.Admin())
 
 	if err = s.Serve(listener); err != nil {
 		log.Fatalf("Failed to serve %v", err)
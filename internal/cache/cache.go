@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// Cache holds the blocks read from table files by lookups, evicting the least recently
+// used once the total charge of its values exceeds its capacity.
+//
+// Values are stored decoded, so that a hit costs neither a read nor a decode. Table files
+// are never modified, so a block is never stale; blocks of deleted tables are evicted as
+// they age. A nil *Cache caches nothing, so callers never need to check for one.
+type Cache struct {
+	capacity int64                 // Largest total charge of the cached values
+	usage    int64                 // Total charge of the cached values
+	entries  map[Key]*list.Element // Element of each cached key in lru
+	lru      *list.List            // Cached entries, most recently used first
+	hits     atomic.Int64
+	misses   atomic.Int64
+	mut      sync.Mutex
+}
+
+// Key identifies a block by its file and its offset in the file
+type Key struct {
+	File   string
+	Offset uint64
+}
+
+type entry struct {
+	key    Key
+	value  any
+	charge int64
+}
+
+// Create a cache holding values with a total charge of up to capacity bytes
+func New(capacity int64) *Cache {
+	return &Cache{capacity: capacity, entries: make(map[Key]*list.Element), lru: list.New()}
+}
+
+// GetOrLoad returns the value cached under key, or calls load and caches the value it
+// returns with its charge. Errors are not cached. Concurrent misses of the same key may
+// each call load.
+func (c *Cache) GetOrLoad(key Key, load func() (any, int64, error)) (any, error) {
+	if c == nil {
+		value, _, err := load()
+		return value, err
+	}
+	if value, ok := c.get(key); ok {
+		c.hits.Add(1)
+		return value, nil
+	}
+	c.misses.Add(1)
+	value, charge, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, value, charge)
+	return value, nil
+}
+
+func (c *Cache) get(key Key) (any, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*entry).value, true
+}
+
+// Caches value, evicting the least recently used values until it fits. A value larger
+// than the whole cache is not cached.
+func (c *Cache) put(key Key, value any, charge int64) {
+	if charge > c.capacity {
+		return
+	}
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		// Loaded concurrently by another miss
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&entry{key: key, value: value, charge: charge})
+	c.usage += charge
+	for c.usage > c.capacity {
+		oldest := c.lru.Remove(c.lru.Back()).(*entry)
+		delete(c.entries, oldest.key)
+		c.usage -= oldest.charge
+	}
+}
+
+// Returns the total charge of the cached values
+func (c *Cache) Usage() int64 {
+	if c == nil {
+		return 0
+	}
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.usage
+}
+
+// Returns the number of lookups answered from the cache
+func (c *Cache) Hits() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.hits.Load()
+}
+
+// Returns the number of lookups that called load
+func (c *Cache) Misses() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.misses.Load()
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+)
+
+// Returns a load func of value with a charge of charge, counting its calls
+func loader(value string, charge int64, calls *int) func() (any, int64, error) {
+	return func() (any, int64, error) {
+		*calls++
+		return value, charge, nil
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	t.Run("Test nil cache", func(t *testing.T) {
+		var c *Cache
+		calls := 0
+		for i := 0; i < 2; i++ {
+			value, err := c.GetOrLoad(Key{File: "a"}, loader("a", 1, &calls))
+			if err != nil || value != "a" {
+				t.Fatalf("Expected the loaded value, found %v, %v", value, err)
+			}
+		}
+		if calls != 2 {
+			t.Errorf("Expected every lookup to load, found %v loads", calls)
+		}
+	})
+
+	t.Run("Test hit", func(t *testing.T) {
+		c := New(100)
+		calls := 0
+		for i := 0; i < 3; i++ {
+			value, err := c.GetOrLoad(Key{File: "a", Offset: 10}, loader("a", 10, &calls))
+			if err != nil || value != "a" {
+				t.Fatalf("Expected the loaded value, found %v, %v", value, err)
+			}
+		}
+		if calls != 1 || c.Hits() != 2 || c.Misses() != 1 {
+			t.Errorf("Expected 1 load, 2 hits and 1 miss, found %v, %v and %v", calls, c.Hits(), c.Misses())
+		}
+		if c.Usage() != 10 {
+			t.Errorf("Expected usage 10, found %v", c.Usage())
+		}
+	})
+
+	t.Run("Test evicts least recently used", func(t *testing.T) {
+		c := New(30)
+		calls := 0
+		for _, offset := range []uint64{0, 1, 2, 0, 3} {
+			if _, err := c.GetOrLoad(Key{File: "a", Offset: offset}, loader("a", 10, &calls)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Offset 1 was evicted by 3, offset 0 was used since
+		calls = 0
+		for _, offset := range []uint64{0, 2, 3} {
+			if _, err := c.GetOrLoad(Key{File: "a", Offset: offset}, loader("a", 10, &calls)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if calls != 0 {
+			t.Errorf("Expected the recently used blocks to stay cached, found %v loads", calls)
+		}
+		if _, err := c.GetOrLoad(Key{File: "a", Offset: 1}, loader("a", 10, &calls)); err != nil {
+			t.Fatal(err)
+		}
+		if calls != 1 {
+			t.Error("Expected the least recently used block to be evicted")
+		}
+		if c.Usage() != 30 {
+			t.Errorf("Expected usage 30, found %v", c.Usage())
+		}
+	})
+
+	t.Run("Test errors and oversized values are not cached", func(t *testing.T) {
+		c := New(10)
+		_, err := c.GetOrLoad(Key{File: "a"}, func() (any, int64, error) { return nil, 0, errors.New("failed") })
+		if err == nil {
+			t.Error("Expected the load error")
+		}
+		calls := 0
+		for i := 0; i < 2; i++ {
+			if _, err := c.GetOrLoad(Key{File: "b"}, loader("b", 11, &calls)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if calls != 2 || c.Usage() != 0 {
+			t.Errorf("Expected a value larger than the cache to be loaded every time, found %v loads and usage %v", calls, c.Usage())
+		}
+	})
+}
@@ -7,22 +7,25 @@ import (
 	"fmt"
 	"hash/fnv"
 	"log/slog"
-	"os"
-	"path/filepath"
+	"math"
+	"math/bits"
 
 	"github.com/dillonkmcquade/gostore/internal"
 	"github.com/dillonkmcquade/gostore/internal/assert"
 )
 
+// Default number of bits per key of a filter sized for its keys
+const DefaultBitsPerKey = 10
+
+// Most hash functions a filter uses, reached at about 43 bits per key
+const maxHashes = 30
+
+// BloomFilter sets k bits anywhere in its bit array for each key
 type BloomFilter struct {
+	File
 	bitset []uint64
-	Name   string
-	Size   uint64
-}
-
-type Opts struct {
-	Size uint64
-	Path string
+	Size   uint64 // Number of bits
+	Hashes uint32 // Number of hash functions, 0 for filters written with the original two-hash scheme
 }
 
 func GenerateUniqueBloomName() string {
@@ -34,18 +37,38 @@ func GenerateUniqueBloomName() string {
 	return fmt.Sprintf("bloom_%s.dat", string)
 }
 
-// New creates a new Bloom filter with the specified size and number of hash functions.
+// New creates a new Bloom filter of opts.Size bits, with the number of hash functions
+// that suits opts.Bits_per_key
 func New(opts *Opts) *BloomFilter {
 	gob.Register(fnv.New64a())
 	assert.True(opts.Size > 0, "Bloom filter size cannot be 0")
 	filter := &BloomFilter{
+		File:   newFile(opts),
 		bitset: make([]uint64, (opts.Size+63)/64),
-		Name:   filepath.Join(opts.Path, GenerateUniqueBloomName()),
 		Size:   opts.Size,
+		Hashes: OptimalHashes(opts.bitsPerKey()),
 	}
 	return filter
 }
 
+func (bf *BloomFilter) Metadata() Metadata {
+	return Metadata{Type: BLOOM, Name: bf.Name, Size: bf.Size, Hashes: bf.Hashes}
+}
+
+// OptimalHashes returns the number of hash functions minimising the false positive rate
+// at bitsPerKey bits per key: bitsPerKey * ln 2
+func OptimalHashes(bitsPerKey int) uint32 {
+	k := uint32(math.Round(float64(bitsPerKey) * math.Ln2))
+	return min(max(k, 1), maxHashes)
+}
+
+// FalsePositiveRate returns the expected false positive rate of a filter with bitsPerKey
+// bits per key and the optimal number of hash functions
+func FalsePositiveRate(bitsPerKey int) float64 {
+	k := float64(OptimalHashes(bitsPerKey))
+	return math.Pow(1-math.Exp(-k/float64(bitsPerKey)), k)
+}
+
 func (bf *BloomFilter) hashFunc(data []byte) uint64 {
 	h := fnv.New64()
 	n, err := h.Write(data)
@@ -67,39 +90,70 @@ func ConvertToBytes(value interface{}) ([]byte, error) {
 
 // Add adds a key to the Bloom filter.
 func (bf *BloomFilter) Add(key []byte) {
-	for _, hash := range bf.getHashes(key) {
-		bf.bitset[hash/64] |= 1 << (hash % 64)
+	if bf.Hashes == 0 {
+		for _, bit := range bf.getHashes(key) {
+			bf.bitset[bit/64] |= 1 << (bit % 64)
+		}
+		return
+	}
+	h1, h2 := bf.doubleHash(key)
+	for i := uint64(0); i < uint64(bf.Hashes); i++ {
+		bit := (h1 + i*h2) % bf.Size
+		bf.bitset[bit/64] |= 1 << (bit % 64)
 	}
-}
-
-func (bf *BloomFilter) getHashes(data []byte) [2]uint64 {
-	hash1 := bf.hashFunc(data)
-	hash2 := hash1 >> 32 // Use the upper 32 bits of hash1
-	return [2]uint64{hash1 % bf.Size, hash2 % bf.Size}
 }
 
 // Has tests whether a key is in the Bloom filter.
 func (bf *BloomFilter) Has(key []byte) bool {
 	assert.True(bf.bitset != nil, "Bitset cannot be nil")
 
-	for _, hash := range bf.getHashes(key) {
-		if (bf.bitset[hash/64] & (1 << (hash % 64))) == 0 {
+	if bf.Hashes == 0 {
+		for _, bit := range bf.getHashes(key) {
+			if (bf.bitset[bit/64] & (1 << (bit % 64))) == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	h1, h2 := bf.doubleHash(key)
+	for i := uint64(0); i < uint64(bf.Hashes); i++ {
+		bit := (h1 + i*h2) % bf.Size
+		if (bf.bitset[bit/64] & (1 << (bit % 64))) == 0 {
 			return false
 		}
 	}
 	return true
 }
 
-func (bf *BloomFilter) Load() error {
-	path := filepath.Clean(bf.Name)
-	file, err := os.Open(path)
-	if err != nil {
-		return fmt.Errorf("os.Open: %w", err)
+// Returns the two hashes that bit i of key is derived from by double hashing: h1 + i*h2
+func (bf *BloomFilter) doubleHash(key []byte) (uint64, uint64) {
+	h1 := hashKey(key)
+	// An odd step visits distinct bits when the size is a power of two
+	h2 := bits.RotateLeft64(h1, 32) | 1
+	return h1, h2
+}
+
+// Bits of key in filters written with the original two-hash scheme
+func (bf *BloomFilter) getHashes(data []byte) [2]uint64 {
+	hash1 := bf.hashFunc(data)
+	hash2 := hash1 >> 32 // Use the upper 32 bits of hash1
+	return [2]uint64{hash1 % bf.Size, hash2 % bf.Size}
+}
+
+// MarshalBinary encodes the bit array with gob, the format of the original filter files
+func (bf *BloomFilter) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	if err := encoder.Encode(bf.bitset); err != nil {
+		return nil, fmt.Errorf("encoder.Encode: %w", err)
 	}
+	return buf.Bytes(), nil
+}
+
+func (bf *BloomFilter) UnmarshalBinary(data []byte) error {
 	bitset := make([]uint64, (bf.Size+63)/64)
-	defer file.Close()
-	decoder := gob.NewDecoder(file)
-	err = decoder.Decode(&bitset)
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	err := decoder.Decode(&bitset)
 	if err != nil {
 		return fmt.Errorf("decoder.Decode: %w", err)
 	}
@@ -107,24 +161,21 @@ func (bf *BloomFilter) Load() error {
 	return nil
 }
 
-// Save saves the Bloom filter to a file.
-func (bf *BloomFilter) Save() error {
-	path := filepath.Clean(bf.Name)
-	file, err := os.Create(path)
+func (bf *BloomFilter) Load() error {
+	data, err := bf.read()
 	if err != nil {
-		return fmt.Errorf("os.Create: %w", err)
+		return err
 	}
-	defer file.Close()
+	return bf.UnmarshalBinary(data)
+}
 
-	encoder := gob.NewEncoder(file)
-	if err := encoder.Encode(bf.bitset); err != nil {
-		return fmt.Errorf("encoder.Encode: %w", err)
-	}
-	err = file.Sync()
+// Save saves the Bloom filter to a file.
+func (bf *BloomFilter) Save() error {
+	data, err := bf.MarshalBinary()
 	if err != nil {
-		return fmt.Errorf("file.Sync: %w", err)
+		return err
 	}
-	return nil
+	return bf.write(data)
 }
 
 func (bf *BloomFilter) Clear() {
@@ -1,6 +1,8 @@
 package filter
 
 import (
+	"fmt"
+	"math/bits"
 	"testing"
 )
 
@@ -55,3 +57,72 @@ func TestBloomIO(t *testing.T) {
 		}
 	})
 }
+
+func TestOptimalHashes(t *testing.T) {
+	tests := map[int]uint32{1: 1, 5: 3, 10: 7, 16: 11, 100: maxHashes}
+	for bitsPerKey, expected := range tests {
+		if k := OptimalHashes(bitsPerKey); k != expected {
+			t.Errorf("Expected %v hashes at %v bits per key, found %v", expected, bitsPerKey, k)
+		}
+	}
+}
+
+func TestNewForKeys(t *testing.T) {
+	meta := NewForKeys(&Opts{Bits_per_key: 12}, 1000).Metadata()
+	if meta.Size != 12000 {
+		t.Errorf("Expected 12000 bits, found %v", meta.Size)
+	}
+	if meta.Hashes != 8 {
+		t.Errorf("Expected 8 hashes, found %v", meta.Hashes)
+	}
+	if empty := NewForKeys(&Opts{}, 0); empty.Metadata().Size == 0 || empty.Has([]byte{1}) {
+		t.Error("Expected an empty filter to hold no keys")
+	}
+}
+
+func TestFalsePositiveRate(t *testing.T) {
+	const keys = 20000
+	const probes = 200000
+	for _, bitsPerKey := range []int{6, 10, 16} {
+		t.Run(fmt.Sprintf("Test %v bits per key", bitsPerKey), func(t *testing.T) {
+			filter := NewForKeys(&Opts{Bits_per_key: bitsPerKey}, keys)
+			for i := 0; i < keys; i++ {
+				filter.Add([]byte(fmt.Sprintf("key%08d", i)))
+			}
+			for i := 0; i < keys; i++ {
+				if !filter.Has([]byte(fmt.Sprintf("key%08d", i))) {
+					t.Fatalf("False negative for key %v", i)
+				}
+			}
+			falsePositives := 0
+			for i := 0; i < probes; i++ {
+				if filter.Has([]byte(fmt.Sprintf("absent%08d", i))) {
+					falsePositives++
+				}
+			}
+			rate := float64(falsePositives) / probes
+			target := FalsePositiveRate(bitsPerKey)
+			if rate > target*1.3 {
+				t.Errorf("Expected a false positive rate near %.4f, found %.4f", target, rate)
+			}
+			t.Logf("false positive rate %.4f, target %.4f", rate, target)
+		})
+	}
+}
+
+func TestLegacyFilter(t *testing.T) {
+	// Filters saved before the hash count was recorded keep the two-hash scheme
+	filter := New(&Opts{Size: 1000})
+	filter.Hashes = 0
+	filter.Add([]byte{42})
+	set := 0
+	for _, word := range filter.bitset {
+		set += bits.OnesCount64(word)
+	}
+	if set > 2 {
+		t.Errorf("Expected at most 2 bits set by the original scheme, found %v", set)
+	}
+	if !filter.Has([]byte{42}) {
+		t.Error("Should have key 42")
+	}
+}
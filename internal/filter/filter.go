@@ -0,0 +1,159 @@
+package filter
+
+import (
+	"encoding"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+
+	"github.com/dillonkmcquade/gostore/internal/ratelimit"
+)
+
+// Type identifies a filter implementation in table metadata
+type Type int32
+
+const (
+	BLOOM         Type = iota // BloomFilter, the original filter probing k bits anywhere in its bit array
+	BLOCKED_BLOOM             // BlockedBloomFilter, the k bits of a key share one cache line
+	XOR                       // XorFilter, a static filter of about 9.8 bits per key for bottom levels
+)
+
+// Filter answers whether a key may be in the set of keys added to it. Has never returns
+// false for an added key, and returns true for other keys at the filter's false positive rate.
+//
+// A filter is stored either in its own file by Save, or by the caller as its marshaled form.
+type Filter interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+	Add(key []byte)
+	Has(key []byte) bool
+	Metadata() Metadata // Recorded in the manifest to open the filter again
+	Path() string       // File of the filter, empty if it is stored by the caller
+	SetLimiter(limiter *ratelimit.RateLimiter)
+	Save() error
+	Load() error
+	Clear() // Releases the contents until the next Load
+}
+
+// Metadata describes a saved filter
+type Metadata struct {
+	Type   Type
+	Name   string // Path of the filter file, empty if it is stored by the caller
+	Size   uint64 // Number of bits
+	Hashes uint32 // Bloom: number of hash functions, 0 for the original two-hash scheme
+}
+
+type Opts struct {
+	Type         Type                   // Filter implementation. Defaults to BLOOM
+	Size         uint64                 // Number of bits of a BLOOM filter created by New
+	Bits_per_key int                    // Bits per key of a bloom filter sized from its number of keys. Defaults to 10
+	Path         string                 // Directory Save writes the filter to. Unused by filters stored by the caller
+	Limiter      *ratelimit.RateLimiter // Throttles Save, may be nil
+}
+
+func (opts *Opts) bitsPerKey() int {
+	if opts.Bits_per_key <= 0 {
+		return DefaultBitsPerKey
+	}
+	return opts.Bits_per_key
+}
+
+// NewForKeys creates a filter of opts.Type sized for n keys. opts.Size is ignored.
+func NewForKeys(opts *Opts, n int) Filter {
+	switch opts.Type {
+	case BLOCKED_BLOOM:
+		return newBlockedBloom(opts, n)
+	case XOR:
+		return newXor(opts, n)
+	default:
+		sized := *opts
+		sized.Size = max(uint64(n)*uint64(opts.bitsPerKey()), 64)
+		return New(&sized)
+	}
+}
+
+// Open returns the filter described by meta. Its contents are read by Load.
+func Open(meta Metadata) Filter {
+	file := File{Name: meta.Name}
+	switch meta.Type {
+	case BLOCKED_BLOOM:
+		return &BlockedBloomFilter{File: file, Size: meta.Size, Hashes: meta.Hashes}
+	case XOR:
+		xf := &XorFilter{File: file, Size: meta.Size}
+		xf.markBuilt()
+		return xf
+	default:
+		return &BloomFilter{File: file, Size: meta.Size, Hashes: meta.Hashes}
+	}
+}
+
+// File is where a filter is saved
+type File struct {
+	Name    string
+	Limiter *ratelimit.RateLimiter // Throttles Save, may be nil
+}
+
+func newFile(opts *Opts) File {
+	if opts.Path == "" {
+		return File{Limiter: opts.Limiter}
+	}
+	return File{Name: filepath.Join(opts.Path, GenerateUniqueBloomName()), Limiter: opts.Limiter}
+}
+
+func (f *File) Path() string {
+	return f.Name
+}
+
+func (f *File) SetLimiter(limiter *ratelimit.RateLimiter) {
+	f.Limiter = limiter
+}
+
+// Replaces the contents of the file with data
+func (f *File) write(data []byte) error {
+	file, err := os.Create(filepath.Clean(f.Name))
+	if err != nil {
+		return fmt.Errorf("os.Create: %w", err)
+	}
+	defer file.Close()
+
+	_, err = f.Limiter.Writer(file).Write(data)
+	if err != nil {
+		return fmt.Errorf("file.Write: %w", err)
+	}
+	err = file.Sync()
+	if err != nil {
+		return fmt.Errorf("file.Sync: %w", err)
+	}
+	return nil
+}
+
+func (f *File) read() ([]byte, error) {
+	data, err := os.ReadFile(filepath.Clean(f.Name))
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	return data, nil
+}
+
+// Returns the 64-bit hash of key that the k probes of a filter are derived from
+func hashKey(key []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(key)
+	return mix(h.Sum64())
+}
+
+// Finalizer of MurmurHash3, spreads every input bit over the whole hash
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// Maps x uniformly onto [0, n) without a division
+func reduce(x uint32, n uint32) uint32 {
+	return uint32((uint64(x) * uint64(n)) >> 32)
+}
@@ -0,0 +1,90 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+)
+
+var types = []Type{BLOOM, BLOCKED_BLOOM, XOR}
+
+func TestFilterTypes(t *testing.T) {
+	const keys = 20000
+	const probes = 200000
+	// Blocked bloom filters trade some accuracy for locality, xor filters are fixed at 1/256
+	maxRate := map[Type]float64{BLOOM: 0.011, BLOCKED_BLOOM: 0.02, XOR: 0.0055}
+	for _, typ := range types {
+		t.Run(fmt.Sprintf("Test filter type %v", typ), func(t *testing.T) {
+			filter := NewForKeys(&Opts{Type: typ}, keys)
+			for i := 0; i < keys; i++ {
+				filter.Add([]byte(fmt.Sprintf("key%08d", i)))
+			}
+			for i := 0; i < keys; i++ {
+				if !filter.Has([]byte(fmt.Sprintf("key%08d", i))) {
+					t.Fatalf("False negative for key %v", i)
+				}
+			}
+			falsePositives := 0
+			for i := 0; i < probes; i++ {
+				if filter.Has([]byte(fmt.Sprintf("absent%08d", i))) {
+					falsePositives++
+				}
+			}
+			rate := float64(falsePositives) / probes
+			if rate > maxRate[typ] {
+				t.Errorf("Expected a false positive rate under %.4f, found %.4f", maxRate[typ], rate)
+			}
+			t.Logf("false positive rate %.4f, %.2f bits per key", rate, float64(filter.Metadata().Size)/keys)
+		})
+	}
+}
+
+func TestFilterOpen(t *testing.T) {
+	for _, typ := range types {
+		t.Run(fmt.Sprintf("Test filter type %v", typ), func(t *testing.T) {
+			filter := NewForKeys(&Opts{Type: typ, Path: t.TempDir()}, 100)
+			for i := 0; i < 100; i++ {
+				filter.Add([]byte{byte(i)})
+			}
+			if err := filter.Save(); err != nil {
+				t.Fatal(err)
+			}
+			meta := filter.Metadata()
+			if meta.Type != typ {
+				t.Errorf("Expected filter type %v, found %v", typ, meta.Type)
+			}
+
+			opened := Open(meta)
+			if opened.Metadata() != meta {
+				t.Errorf("Expected an opened filter to report %+v before loading, found %+v", meta, opened.Metadata())
+			}
+			if err := opened.Load(); err != nil {
+				t.Fatal(err)
+			}
+			if opened.Metadata() != meta {
+				t.Errorf("Expected %+v, found %+v", meta, opened.Metadata())
+			}
+			for i := 0; i < 100; i++ {
+				if !opened.Has([]byte{byte(i)}) {
+					t.Errorf("Should have key %v", i)
+				}
+			}
+		})
+	}
+}
+
+func TestXorFilter(t *testing.T) {
+	t.Run("Test duplicate keys", func(t *testing.T) {
+		filter := NewForKeys(&Opts{Type: XOR}, 10)
+		for i := 0; i < 10; i++ {
+			filter.Add([]byte("key"))
+		}
+		if !filter.Has([]byte("key")) {
+			t.Error("Should have key")
+		}
+	})
+	t.Run("Test corrupt", func(t *testing.T) {
+		if err := (&XorFilter{}).UnmarshalBinary(make([]byte, 20)); err == nil {
+			t.Error("Expected an error for fingerprints that do not match the block length")
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"sync"
+
+	"github.com/dillonkmcquade/gostore/internal/assert"
+)
+
+// Default number of bits per prefix stored by a range filter
+const DefaultBitsPerPrefix = 10
+
+const (
+	rangeLevelBits   = 4    // Bits a cell adds to its parent, so a cell has 16 children
+	rangeLevels      = 8    // Cells of 1 to 2^28 consecutive suffixes are stored
+	maxRangeTopCells = 16   // Wider queries are not short range scans and are not filtered
+	maxRangeProbes   = 1024 // Probes before a query gives up and reports a possible match
+)
+
+type RangeOpts struct {
+	Bits_per_prefix int // Bits per stored prefix. Defaults to 10
+}
+
+// RangeFilter answers whether a table may hold a key in an interval, with a ladder of
+// prefix bloom filters.
+//
+// Keys are reduced to their suffix, up to 8 bytes following the common prefix of the
+// table, as wide as the longest key needs. A cell at level l holds the suffixes sharing
+// all but their low 4*l bits, and the filter stores the cells of every key at each level.
+// A query checks the few cells covering its interval at the coarsest level, then descends
+// into the children of those present, so most empty intervals are ruled out after a
+// handful of probes.
+//
+// Suffixes are buffered until the filter is built by the first query or MarshalBinary,
+// after which no key may be added.
+type RangeFilter struct {
+	prefix        []byte   // Common prefix of the keys
+	width         int      // Bytes of a suffix
+	suffixes      []uint64 // Suffixes of the keys added before the filter is built, 8 bytes wide
+	bitsPerPrefix int
+	bitset        []uint64
+	Size          uint64 // Number of bits, known once built
+	Hashes        uint32
+	build         sync.Once
+	built         bool
+}
+
+// NewRange creates a range filter for n keys in [first, last]
+func NewRange(opts *RangeOpts, first []byte, last []byte, n int) *RangeFilter {
+	bitsPerPrefix := opts.Bits_per_prefix
+	if bitsPerPrefix <= 0 {
+		bitsPerPrefix = DefaultBitsPerPrefix
+	}
+	common := 0
+	for common < min(len(first), len(last)) && first[common] == last[common] {
+		common++
+	}
+	return &RangeFilter{
+		prefix:        slices.Clone(first[:common]),
+		suffixes:      make([]uint64, 0, n),
+		bitsPerPrefix: bitsPerPrefix,
+	}
+}
+
+func (rf *RangeFilter) Add(key []byte) {
+	assert.True(!rf.built, "Cannot add to a range filter once built")
+	assert.True(bytes.HasPrefix(key, rf.prefix), "Key %v is outside of the range filter", key)
+	rf.width = min(max(rf.width, len(key)-len(rf.prefix)), 8)
+	rf.suffixes = append(rf.suffixes, rf.suffix(key, 8))
+}
+
+// Returns width bytes of key after the common prefix as a big endian integer, padded with
+// zeros. Keys with the common prefix are in the same order as their suffixes.
+func (rf *RangeFilter) suffix(key []byte, width int) uint64 {
+	var suffix [8]byte
+	copy(suffix[8-width:], key[len(rf.prefix):min(len(key), len(rf.prefix)+width)])
+	return binary.BigEndian.Uint64(suffix[:])
+}
+
+func cellHash(level int, cell uint64) uint64 {
+	return mix(cell ^ mix(uint64(level)+1))
+}
+
+// Sets the bits of the cells of the buffered suffixes, once the suffix width is known
+func (rf *RangeFilter) construct() {
+	defer func() { rf.built = true }()
+	cells := make([]uint64, 0, len(rf.suffixes)*rangeLevels)
+	for _, suffix := range rf.suffixes {
+		suffix >>= (8 - rf.width) * 8
+		for level := 0; level < rangeLevels; level++ {
+			cells = append(cells, cellHash(level, suffix>>(level*rangeLevelBits)))
+		}
+	}
+	rf.suffixes = nil
+	slices.Sort(cells)
+	// Keys sharing a cell store it once
+	cells = slices.Compact(cells)
+	rf.Size = max((uint64(len(cells))*uint64(rf.bitsPerPrefix)+63)/64*64, 64)
+	rf.Hashes = OptimalHashes(rf.bitsPerPrefix)
+	rf.bitset = make([]uint64, rf.Size/64)
+	for _, h := range cells {
+		h1, h2 := h, bits.RotateLeft64(h, 32)|1
+		for i := uint64(0); i < uint64(rf.Hashes); i++ {
+			bit := (h1 + i*h2) % rf.Size
+			rf.bitset[bit/64] |= 1 << (bit % 64)
+		}
+	}
+}
+
+func (rf *RangeFilter) has(h uint64) bool {
+	h1, h2 := h, bits.RotateLeft64(h, 32)|1
+	for i := uint64(0); i < uint64(rf.Hashes); i++ {
+		bit := (h1 + i*h2) % rf.Size
+		if rf.bitset[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MayContainRange reports whether a key in [start, end) may have been added. A nil end
+// leaves the interval open. Never returns false if such a key was added.
+func (rf *RangeFilter) MayContainRange(start []byte, end []byte) bool {
+	rf.build.Do(rf.construct)
+	assert.True(len(rf.bitset) > 0, "Range filter is not loaded")
+
+	// Bound the suffixes of the keys in the interval, every key starts with the prefix
+	lo, hi := uint64(0), uint64(math.MaxUint64)
+	switch bytes.Compare(start[:min(len(start), len(rf.prefix))], rf.prefix) {
+	case 1:
+		return false
+	case 0:
+		lo = rf.suffix(start, rf.width)
+	}
+	if end != nil {
+		switch bytes.Compare(end[:min(len(end), len(rf.prefix))], rf.prefix) {
+		case -1:
+			return false
+		case 0:
+			if len(end) == len(rf.prefix) {
+				// No key with the prefix sorts before the prefix itself
+				return false
+			}
+			hi = rf.suffix(end, rf.width)
+			if len(end) <= len(rf.prefix)+rf.width && end[len(end)-1] != 0 {
+				// Keys before end have smaller suffixes, unless they are end cut short
+				// before trailing zeros
+				hi--
+			}
+		}
+	}
+	if lo > hi {
+		return false
+	}
+	top := rangeLevels - 1
+	shift := top * rangeLevelBits
+	if hi>>shift-lo>>shift >= maxRangeTopCells {
+		return true
+	}
+	probes := 0
+	return rf.anyCell(top, lo>>shift, hi>>shift, lo, hi, &probes)
+}
+
+// Reports whether a cell of level in [from, to] that overlaps the suffixes [lo, hi] may
+// hold a key, descending into the children of every cell present
+func (rf *RangeFilter) anyCell(level int, from uint64, to uint64, lo uint64, hi uint64, probes *int) bool {
+	shift := level * rangeLevelBits
+	first, last := max(from, lo>>shift), min(to, hi>>shift)
+	for cell := first; cell <= last; cell++ {
+		*probes++
+		if *probes > maxRangeProbes {
+			return true
+		}
+		if rf.has(cellHash(level, cell)) {
+			if level == 0 {
+				return true
+			}
+			children := cell << rangeLevelBits
+			if rf.anyCell(level-1, children, children|(1<<rangeLevelBits-1), lo, hi, probes) {
+				return true
+			}
+		}
+		if cell == last {
+			// The last cell may be the largest suffix
+			break
+		}
+	}
+	return false
+}
+
+// MarshalBinary encodes the filter as the prefix length and prefix, the suffix width, the
+// hash count and the bit array, little endian
+func (rf *RangeFilter) MarshalBinary() ([]byte, error) {
+	rf.build.Do(rf.construct)
+	data := binary.LittleEndian.AppendUint32(nil, uint32(len(rf.prefix)))
+	data = append(data, rf.prefix...)
+	data = append(data, byte(rf.width))
+	data = binary.LittleEndian.AppendUint32(data, rf.Hashes)
+	for _, word := range rf.bitset {
+		data = binary.LittleEndian.AppendUint64(data, word)
+	}
+	return data, nil
+}
+
+func (rf *RangeFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("range filter of %v bytes is corrupt", len(data))
+	}
+	prefixLength := uint64(binary.LittleEndian.Uint32(data))
+	data = data[4:]
+	// The prefix, the width and hash count, and at least one word
+	header := prefixLength + 1 + 4
+	if uint64(len(data)) <= header || (uint64(len(data))-header)%8 != 0 || data[prefixLength] > 8 {
+		return fmt.Errorf("range filter of %v bytes is corrupt", len(data)+4)
+	}
+	rf.build.Do(func() {})
+	rf.built = true
+	rf.prefix = bytes.Clone(data[:prefixLength])
+	rf.width = int(data[prefixLength])
+	data = data[prefixLength+1:]
+	rf.Hashes = binary.LittleEndian.Uint32(data)
+	rf.bitset = make([]uint64, (len(data)-4)/8)
+	for i := range rf.bitset {
+		rf.bitset[i] = binary.LittleEndian.Uint64(data[4+8*i:])
+	}
+	rf.Size = uint64(len(rf.bitset)) * 64
+	return nil
+}
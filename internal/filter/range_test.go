@@ -0,0 +1,103 @@
+package filter
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// Returns a range filter of keys key00000000, key00000010, ... and the keys
+func newTestRange(t *testing.T, n int) (*RangeFilter, [][]byte) {
+	keys := [][]byte{}
+	for i := 0; i < n; i++ {
+		keys = append(keys, []byte(fmt.Sprintf("key%08d", i*10)))
+	}
+	rf := NewRange(&RangeOpts{}, keys[0], keys[len(keys)-1], len(keys))
+	for _, key := range keys {
+		rf.Add(key)
+	}
+	return rf, keys
+}
+
+func TestRangeFilter(t *testing.T) {
+	rf, keys := newTestRange(t, 10000)
+
+	t.Run("Test no false negatives", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < 20000; i++ {
+			start := []byte(fmt.Sprintf("key%08d", rng.Intn(110000)))
+			end := []byte(fmt.Sprintf("key%08d", rng.Intn(110000)))
+			if slices.Compare(start, end) > 0 {
+				start, end = end, start
+			}
+			idx := sort.Search(len(keys), func(i int) bool { return slices.Compare(keys[i], start) >= 0 })
+			holds := idx < len(keys) && slices.Compare(keys[idx], end) < 0
+			if holds && !rf.MayContainRange(start, end) {
+				t.Fatalf("False negative for [%s, %s)", start, end)
+			}
+		}
+	})
+
+	t.Run("Test short empty ranges", func(t *testing.T) {
+		falsePositives := 0
+		for i := 0; i < len(keys)-1; i++ {
+			// The 9 keys between two adjacent keys
+			start := []byte(fmt.Sprintf("key%08d", i*10+1))
+			end := []byte(fmt.Sprintf("key%08d", i*10+10))
+			if rf.MayContainRange(start, end) {
+				falsePositives++
+			}
+		}
+		// The cells of a range next to a key share its parent cell, so each of them is probed
+		rate := float64(falsePositives) / float64(len(keys)-1)
+		if rate > 0.2 {
+			t.Errorf("Expected most empty ranges to be ruled out, found a false positive rate of %.4f", rate)
+		}
+		t.Logf("false positive rate %.4f, %.1f bits per key", rate, float64(rf.Size)/float64(len(keys)))
+	})
+
+	t.Run("Test outside of the prefix", func(t *testing.T) {
+		tests := []struct {
+			start    string
+			end      string
+			expected bool
+		}{
+			{start: "a", end: "b", expected: false},
+			{start: "a", end: "key", expected: false},
+			{start: "a", end: "key00000001", expected: true},
+			{start: "kez", end: "z", expected: false},
+			{start: "k", end: "z", expected: true},
+		}
+		for _, tt := range tests {
+			if found := rf.MayContainRange([]byte(tt.start), []byte(tt.end)); found != tt.expected {
+				t.Errorf("Expected %v for [%v, %v), found %v", tt.expected, tt.start, tt.end, found)
+			}
+		}
+		if !rf.MayContainRange([]byte("key00099990"), nil) {
+			t.Error("Expected the last key in an open interval")
+		}
+	})
+
+	t.Run("Test marshal", func(t *testing.T) {
+		data, err := rf.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &RangeFilter{}
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 1000; i++ {
+			start := []byte(fmt.Sprintf("key%08d", i*7))
+			end := []byte(fmt.Sprintf("key%08d", i*7+5))
+			if decoded.MayContainRange(start, end) != rf.MayContainRange(start, end) {
+				t.Fatalf("Expected the decoded filter to agree for [%s, %s)", start, end)
+			}
+		}
+		if err := decoded.UnmarshalBinary(data[:len(data)-3]); err == nil {
+			t.Error("Expected an error for a truncated filter")
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"slices"
+	"sync"
+
+	"github.com/dillonkmcquade/gostore/internal/assert"
+)
+
+// Seeds tried before giving up on building an xor filter. A seed fails with probability
+// well under 1%, so reaching this means the hashes are broken.
+const maxXorAttempts = 100
+
+// XorFilter is a static filter storing an 8-bit fingerprint per slot, with about 1.23 slots
+// per key. A key is present if its fingerprint equals the xor of its three slots, so the
+// false positive rate is 1/256 at about 9.8 bits per key.
+//
+// Keys are buffered as hashes until the filter is built by the first Has or Save, after
+// which no key may be added.
+type XorFilter struct {
+	File
+	hashes       []uint64 // Hashes of the keys added before the filter is built
+	seed         uint64
+	blockLength  uint32 // Slots per block, one of the three slots of a key is in each block
+	fingerprints []uint8
+	Size         uint64 // Number of bits, known once built
+	build        sync.Once
+	built        bool
+}
+
+func newXor(opts *Opts, n int) *XorFilter {
+	return &XorFilter{File: newFile(opts), hashes: make([]uint64, 0, n)}
+}
+
+func (xf *XorFilter) Add(key []byte) {
+	assert.True(!xf.built, "Cannot add to an xor filter once built")
+	xf.hashes = append(xf.hashes, hashKey(key))
+}
+
+// Returns the hash of a key for the current seed
+func (xf *XorFilter) seeded(keyHash uint64) uint64 {
+	return mix(keyHash + xf.seed)
+}
+
+// Returns the fingerprint and the three slots of a seeded hash
+func (xf *XorFilter) slots(h uint64) (uint8, [3]uint32) {
+	return uint8(h ^ h>>32), [3]uint32{
+		reduce(uint32(h), xf.blockLength),
+		reduce(uint32(bits.RotateLeft64(h, 21)), xf.blockLength) + xf.blockLength,
+		reduce(uint32(bits.RotateLeft64(h, 42)), xf.blockLength) + 2*xf.blockLength,
+	}
+}
+
+func (xf *XorFilter) Has(key []byte) bool {
+	xf.build.Do(xf.construct)
+	assert.True(len(xf.fingerprints) > 0, "Xor filter is not loaded")
+
+	fingerprint, slots := xf.slots(xf.seeded(hashKey(key)))
+	return fingerprint == xf.fingerprints[slots[0]]^xf.fingerprints[slots[1]]^xf.fingerprints[slots[2]]
+}
+
+// Keys a slot is one of the three slots of, as the xor and count of their hashes
+type xorSlot struct {
+	mask  uint64
+	count uint32
+}
+
+// Builds the fingerprints from the buffered hashes.
+//
+// Slots holding a single key are peeled off repeatedly, recording the order. If every key
+// is peeled, assigning fingerprints in reverse order gives each key a slot that no later
+// key uses. Otherwise the slots of some keys form a cycle and another seed is tried.
+func (xf *XorFilter) construct() {
+	defer func() { xf.built = true }()
+	hashes := slices.Clone(xf.hashes)
+	slices.Sort(hashes)
+	// Equal hashes would share all three slots and never peel
+	hashes = slices.Compact(hashes)
+	xf.hashes = nil
+
+	xf.blockLength = (32 + uint32(len(hashes))*123/100) / 3
+	slots := make([]xorSlot, 3*xf.blockLength)
+	type peeled struct {
+		hash uint64
+		slot uint32
+	}
+	order := make([]peeled, 0, len(hashes))
+	queue := []uint32{}
+
+	rng := uint64(0x2545f4914f6cdd1d)
+	for attempt := 0; ; attempt++ {
+		assert.True(attempt < maxXorAttempts, "Failed to build xor filter of %v keys", len(hashes))
+		rng += 0x9e3779b97f4a7c15
+		xf.seed = mix(rng)
+
+		clear(slots)
+		for _, keyHash := range hashes {
+			h := xf.seeded(keyHash)
+			_, keySlots := xf.slots(h)
+			for _, slot := range keySlots {
+				slots[slot].mask ^= h
+				slots[slot].count++
+			}
+		}
+		queue = queue[:0]
+		for i := range slots {
+			if slots[i].count == 1 {
+				queue = append(queue, uint32(i))
+			}
+		}
+		order = order[:0]
+		for len(queue) > 0 {
+			i := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			if slots[i].count != 1 {
+				continue
+			}
+			// The mask of a slot holding one key is that key's hash
+			h := slots[i].mask
+			order = append(order, peeled{hash: h, slot: i})
+			_, keySlots := xf.slots(h)
+			for _, slot := range keySlots {
+				slots[slot].mask ^= h
+				slots[slot].count--
+				if slots[slot].count == 1 {
+					queue = append(queue, slot)
+				}
+			}
+		}
+		if len(order) == len(hashes) {
+			break
+		}
+	}
+
+	xf.fingerprints = make([]uint8, len(slots))
+	xf.Size = uint64(len(slots)) * 8
+	for i := len(order) - 1; i >= 0; i-- {
+		fingerprint, keySlots := xf.slots(order[i].hash)
+		xf.fingerprints[order[i].slot] = 0
+		xf.fingerprints[order[i].slot] = fingerprint ^ xf.fingerprints[keySlots[0]] ^ xf.fingerprints[keySlots[1]] ^ xf.fingerprints[keySlots[2]]
+	}
+}
+
+func (xf *XorFilter) Metadata() Metadata {
+	xf.build.Do(xf.construct)
+	return Metadata{Type: XOR, Name: xf.Name, Size: xf.Size}
+}
+
+// MarshalBinary encodes the filter as its seed and block length followed by its fingerprints
+func (xf *XorFilter) MarshalBinary() ([]byte, error) {
+	xf.build.Do(xf.construct)
+	data := make([]byte, 12, 12+len(xf.fingerprints))
+	binary.LittleEndian.PutUint64(data, xf.seed)
+	binary.LittleEndian.PutUint32(data[8:], xf.blockLength)
+	return append(data, xf.fingerprints...), nil
+}
+
+func (xf *XorFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < 12 {
+		return fmt.Errorf("xor filter of %v bytes is corrupt", len(data))
+	}
+	seed := binary.LittleEndian.Uint64(data)
+	blockLength := binary.LittleEndian.Uint32(data[8:])
+	if uint64(len(data)-12) != 3*uint64(blockLength) {
+		return fmt.Errorf("xor filter of %v bytes does not hold %v slots", len(data), 3*blockLength)
+	}
+	xf.markBuilt()
+	xf.seed, xf.blockLength = seed, blockLength
+	xf.fingerprints = bytes.Clone(data[12:])
+	xf.Size = uint64(len(xf.fingerprints)) * 8
+	return nil
+}
+
+// Marks a filter whose fingerprints are loaded rather than built from added keys
+func (xf *XorFilter) markBuilt() {
+	xf.build.Do(func() {})
+	xf.built = true
+}
+
+func (xf *XorFilter) Save() error {
+	data, err := xf.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return xf.write(data)
+}
+
+func (xf *XorFilter) Load() error {
+	data, err := xf.read()
+	if err != nil {
+		return err
+	}
+	return xf.UnmarshalBinary(data)
+}
+
+func (xf *XorFilter) Clear() {
+	xf.fingerprints = nil
+}
@@ -8,24 +8,43 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/dillonkmcquade/gostore/internal/cache"
 	"github.com/dillonkmcquade/gostore/internal/filter"
 	"github.com/dillonkmcquade/gostore/internal/manifest"
 	"github.com/dillonkmcquade/gostore/internal/memtable"
 	"github.com/dillonkmcquade/gostore/internal/ordered"
+	"github.com/dillonkmcquade/gostore/internal/ratelimit"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
 )
 
+// Bytes of table blocks the default options cache for lookups
+const defaultBlockCacheSize = 8 << 20
+
 type LSM interface {
 	io.Closer
-	Write([]byte, []byte) error  // Write the Key-Value pair to the memtable
-	Read([]byte) ([]byte, error) // Read the value from the given key.
-	Delete([]byte) error         // Delete the key from the DB
+	Write([]byte, []byte) error        // Write the Key-Value pair to the memtable
+	Read([]byte) ([]byte, error)       // Read the value from the given key.
+	Delete([]byte) error               // Delete the key from the DB
+	Flush() error                      // Write the memtable to L0 even if it is not full
+	CompactRange([]byte, []byte) error // Compact the keys in [start, end] down to the last level
+
+	GetPropertiesOfAllTables() (map[string]*sstable.Properties, error) // Properties of every live table, keyed by file name
+	ApproximateSize([]byte, []byte) (int64, error)                     // Estimated bytes of the keys in [start, end)
+	ApproximateKeyCount([]byte, []byte) (int64, error)                 // Estimated number of keys in [start, end)
+	IngestExternalFiles([]string) error                                // Add table files written by an sstable.SSTWriter
+	Checkpoint(string) error                                           // Write a consistent copy of the store to a new directory
 }
 
 type GoStore struct {
-	memTable memtable.MemTable  // The current memtable
-	manifest *manifest.Manifest // In-memory representation of on-disk data layout (levels, tables)
+	memTable memtable.MemTable        // The current memtable
+	manifest *manifest.Manifest       // In-memory representation of on-disk data layout (levels, tables)
+	limiters []*ratelimit.RateLimiter // Limiters of flushes and compactions, told the latency of reads
+	added    uint64                   // Number of flushed tables added to L0, guarded by flushed.L
+	flushed  *sync.Cond               // Broadcast when a flushed table is added to L0
 }
 
 type LSMOpts struct {
@@ -33,13 +52,14 @@ type LSMOpts struct {
 	ManifestOpts     *manifest.Opts
 	GoStorePath      string
 	SSTable_max_size int
+	Rate_limiter     *ratelimit.RateLimiter // Shared by flushes and compactions unless their opts set one. Optional
 }
 
 //	return &LSMOpts{
 //		MemTableOpts: &memtable.Opts{
 //			WalPath:          filepath.Join(gostorepath, "WAL.log"),
 //			Batch_write_size: 10,
-//			Max_size:         20000,
+//			Max_size:         4 << 20,
 //			Bloom_size:       10000,
 //			BloomPath:        filepath.Join(gostorepath, "filters"),
 //		},
@@ -53,6 +73,11 @@ type LSMOpts struct {
 //			},
 //			SSTable_max_size: 400000,
 //			BloomPath:        filepath.Join(gostorepath, "filters"),
+//			Max_manifest_size:  4 << 20,
+//			Max_compactions:    2,
+//			Max_subcompactions: 4,
+//			Dynamic_level_size: true,
+//			Max_levels:         7,
 //		},
 //		GoStorePath: gostorepath,
 //	}
@@ -64,9 +89,8 @@ func NewDefaultLSMOpts(gostorepath string) *LSMOpts {
 		MemTableOpts: &memtable.Opts{
 			WalPath:          filepath.Join(gostorepath, "WAL.log"),
 			Batch_write_size: 10,
-			Max_size:         20000,
+			Max_size:         4 << 20,
 			FilterOpts: &filter.Opts{
-				Size: 400000 * 10,
 				Path: filepath.Join(gostorepath, "filters"),
 			},
 			LevelZero: filepath.Join(gostorepath, "l0"),
@@ -79,8 +103,14 @@ func NewDefaultLSMOpts(gostorepath string) *LSMOpts {
 				filepath.Join(gostorepath, "l0"), filepath.Join(gostorepath, "l1"),
 				filepath.Join(gostorepath, "l2"), filepath.Join(gostorepath, "l3"),
 			},
-			SSTable_max_size: 400000,
-			BloomPath:        filepath.Join(gostorepath, "filters"),
+			SSTable_max_size:   400000,
+			BloomPath:          filepath.Join(gostorepath, "filters"),
+			Max_manifest_size:  4 << 20,
+			Max_compactions:    2,
+			Max_subcompactions: 4,
+			Dynamic_level_size: true,
+			Max_levels:         7,
+			Block_cache:        cache.New(defaultBlockCacheSize),
 		},
 		GoStorePath: gostorepath,
 	}
@@ -90,7 +120,7 @@ func NewDefaultLSMOpts(gostorepath string) *LSMOpts {
 //		MemTableOpts: &memtable.Opts{
 //			WalPath:          filepath.Join(gostorepath, "WAL.log"),
 //			Batch_write_size: 100,
-//			Max_size:         1000,
+//			Max_size:         1000 * 170,
 //			Bloom_size:       10000,
 //			BloomPath:        filepath.Join(gostorepath, "filters"),
 //		},
@@ -104,6 +134,9 @@ func NewDefaultLSMOpts(gostorepath string) *LSMOpts {
 //			Level0_max_size:  539375,
 //			SSTable_max_size: 1000,
 //			BloomPath:        filepath.Join(gostorepath, "filters"),
+//			Max_manifest_size:  64 << 10,
+//			Max_compactions:    2,
+//			Max_subcompactions: 4,
 //		},
 //		GoStorePath: gostorepath,
 //	}
@@ -115,9 +148,8 @@ func NewTestLSMOpts(gostorepath string) *LSMOpts {
 		MemTableOpts: &memtable.Opts{
 			Batch_write_size: 100,
 			WalPath:          filepath.Join(gostorepath, "WAL.log"),
-			Max_size:         1000,
+			Max_size:         1000 * 170, // ~1000 small entries
 			FilterOpts: &filter.Opts{
-				Size: 1000,
 				Path: filepath.Join(gostorepath, "filters"),
 			},
 			LevelZero: filepath.Join(gostorepath, "l0"),
@@ -127,11 +159,15 @@ func NewTestLSMOpts(gostorepath string) *LSMOpts {
 				filepath.Join(gostorepath, "l0"), filepath.Join(gostorepath, "l1"),
 				filepath.Join(gostorepath, "l2"), filepath.Join(gostorepath, "l3"),
 			},
-			Path:             filepath.Join(gostorepath, "manifest.txtpb"),
-			Num_levels:       4,
-			Level0_max_size:  539375,
-			SSTable_max_size: 1000,
-			BloomPath:        filepath.Join(gostorepath, "filters"),
+			Path:               filepath.Join(gostorepath, "manifest.txtpb"),
+			Num_levels:         4,
+			Level0_max_size:    539375,
+			SSTable_max_size:   1000,
+			BloomPath:          filepath.Join(gostorepath, "filters"),
+			Max_manifest_size:  64 << 10,
+			Max_compactions:    2,
+			Max_subcompactions: 4,
+			Block_cache:        cache.New(defaultBlockCacheSize),
 		},
 		GoStorePath: gostorepath,
 	}
@@ -170,10 +206,33 @@ func New(opts *LSMOpts) (LSM, error) {
 		errs = append(errs, err)
 	}
 
+	// Flushes and compactions share the store's I/O budget
+	if opts.MemTableOpts.Rate_limiter == nil {
+		opts.MemTableOpts.Rate_limiter = opts.Rate_limiter
+	}
+	if opts.ManifestOpts.Rate_limiter == nil {
+		opts.ManifestOpts.Rate_limiter = opts.Rate_limiter
+	}
+	// Level 0 tables are written by flushes
+	if opts.MemTableOpts.FilterOpts.Bits_per_key <= 0 && len(opts.ManifestOpts.Bits_per_key) > 0 {
+		opts.MemTableOpts.FilterOpts.Bits_per_key = opts.ManifestOpts.Bits_per_key[0]
+	}
+	if opts.MemTableOpts.FilterOpts.Type == filter.BLOOM && len(opts.ManifestOpts.Filter_types) > 0 {
+		opts.MemTableOpts.FilterOpts.Type = opts.ManifestOpts.Filter_types[0]
+	}
+	if opts.MemTableOpts.RangeFilterOpts == nil && opts.ManifestOpts.Range_bits_per_prefix > 0 {
+		opts.MemTableOpts.RangeFilterOpts = &filter.RangeOpts{Bits_per_prefix: opts.ManifestOpts.Range_bits_per_prefix}
+	}
+	if opts.MemTableOpts.Properties_collectors == nil {
+		opts.MemTableOpts.Properties_collectors = opts.ManifestOpts.Properties_collectors
+	}
+
 	// DATA LAYOUT
 	manifest, err := manifest.New(opts.ManifestOpts)
 	if err != nil {
 		errs = append(errs, err)
+	} else {
+		opts.MemTableOpts.Next_run = manifest.NextRun
 	}
 
 	// MEMTABLE
@@ -182,7 +241,14 @@ func New(opts *LSMOpts) (LSM, error) {
 		errs = append(errs, err)
 	}
 
-	gostore := &GoStore{memTable: mem, manifest: manifest}
+	// A limiter shared by flushes and compactions is told each latency once
+	limiters := []*ratelimit.RateLimiter{}
+	for _, limiter := range []*ratelimit.RateLimiter{opts.MemTableOpts.Rate_limiter, opts.ManifestOpts.Rate_limiter} {
+		if limiter != nil && !slices.Contains(limiters, limiter) {
+			limiters = append(limiters, limiter)
+		}
+	}
+	gostore := &GoStore{memTable: mem, manifest: manifest, limiters: limiters, flushed: sync.NewCond(&sync.Mutex{})}
 	go gostore.waitForFlush()
 	return gostore, errors.Join(errs...)
 }
@@ -194,7 +260,121 @@ func (store *GoStore) waitForFlush() {
 		if err != nil {
 			slog.Error(err.Error())
 		}
+		store.flushed.L.Lock()
+		store.added++
+		store.flushed.Broadcast()
+		store.flushed.L.Unlock()
+	}
+}
+
+// Flush writes the memtable to L0 even if it is not full, and returns once every table
+// flushed so far is in the manifest.
+func (store *GoStore) Flush() error {
+	flushes := store.memTable.Flush()
+	store.flushed.L.Lock()
+	defer store.flushed.L.Unlock()
+	for store.added < flushes {
+		store.flushed.Wait()
 	}
+	return nil
+}
+
+// CompactRange flushes the memtable, then compacts every table holding keys in
+// [start, end] down to the last level. A nil start or end leaves that side open.
+func (store *GoStore) CompactRange(start []byte, end []byte) error {
+	err := store.Flush()
+	if err != nil {
+		return fmt.Errorf("store.Flush: %w", err)
+	}
+	err = store.manifest.CompactRange(start, end)
+	if err != nil {
+		return fmt.Errorf("manifest.CompactRange: %w", err)
+	}
+	return nil
+}
+
+// GetPropertiesOfAllTables returns the properties of every table in the manifest, keyed by
+// table file name. Entries still in the memtable are not counted.
+func (store *GoStore) GetPropertiesOfAllTables() (map[string]*sstable.Properties, error) {
+	props, err := store.manifest.GetPropertiesOfAllTables()
+	if err != nil {
+		return nil, fmt.Errorf("manifest.GetPropertiesOfAllTables: %w", err)
+	}
+	return props, nil
+}
+
+// ApproximateSize estimates the bytes of the keys in [start, end), from the bounds and
+// indexes of the tables and the entries of the memtable. No data block is read. A nil start
+// or end leaves that side open.
+func (store *GoStore) ApproximateSize(start []byte, end []byte) (int64, error) {
+	size, _, err := store.approximateRange(start, end)
+	return size, err
+}
+
+// ApproximateKeyCount estimates the number of keys in [start, end) the same way as
+// ApproximateSize. Keys written more than once, or deleted, may be counted several times.
+func (store *GoStore) ApproximateKeyCount(start []byte, end []byte) (int64, error) {
+	_, count, err := store.approximateRange(start, end)
+	return count, err
+}
+
+func (store *GoStore) approximateRange(start []byte, end []byte) (int64, int64, error) {
+	version := store.manifest.Current()
+	defer version.Unref()
+	size, count, err := version.ApproximateRange(start, end)
+	if err != nil {
+		return 0, 0, fmt.Errorf("version.ApproximateRange: %w", err)
+	}
+	memSize, memCount := store.memTable.ApproximateRange(start, end)
+	return size + memSize, count + memCount, nil
+}
+
+// IngestExternalFiles adds the table files at paths, written by an sstable.SSTWriter,
+// without passing their entries through the WAL, the memtable or compactions. The files
+// are validated, then linked into the lowest level where they overlap no existing data, in
+// a single manifest edit. Later files are newer than earlier ones, and every file is newer
+// than the writes made before the call. The files at paths are left in place.
+func (store *GoStore) IngestExternalFiles(paths []string) error {
+	tables := make([]*sstable.SSTable, 0, len(paths))
+	for _, path := range paths {
+		table, err := sstable.OpenExternal(path)
+		if err != nil {
+			return fmt.Errorf("sstable.OpenExternal: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	// Writes made before the call must reach the manifest first, or they would hide the ingested keys
+	err := store.Flush()
+	if err != nil {
+		return fmt.Errorf("store.Flush: %w", err)
+	}
+	err = store.manifest.IngestTables(tables)
+	if err != nil {
+		return fmt.Errorf("manifest.IngestTables: %w", err)
+	}
+	return nil
+}
+
+// Checkpoint flushes the memtable, then writes a copy of the store to dir, which must not
+// exist yet. Table files are hard linked, so a checkpoint is cheap and holds every write made
+// before the call. The checkpoint is laid out as NewDefaultLSMOpts(dir) expects, and records
+// the compaction style and levels of the store, so that New opens it directly.
+func (store *GoStore) Checkpoint(dir string) error {
+	err := store.Flush()
+	if err != nil {
+		return fmt.Errorf("store.Flush: %w", err)
+	}
+	err = os.Mkdir(dir, 0750)
+	if err != nil {
+		return fmt.Errorf("os.Mkdir: %w", err)
+	}
+	opts := NewDefaultLSMOpts(dir)
+	err = store.manifest.Checkpoint(opts.ManifestOpts)
+	if err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("manifest.Checkpoint: %w", err)
+	}
+	return nil
 }
 
 // Write the Key-Value pair to the memtable
@@ -208,6 +388,15 @@ func (store *GoStore) Write(key []byte, val []byte) error {
 
 // Read the value from the given key. Will return error if value is not found.
 func (store *GoStore) Read(key []byte) ([]byte, error) {
+	// Reads are the foreground latency an auto-tuned limiter backs off for
+	start := time.Now()
+	defer func() {
+		latency := time.Since(start)
+		for _, limiter := range store.limiters {
+			limiter.RecordLatency(latency)
+		}
+	}()
+
 	// Read from memtable first
 	if val, ok := store.memTable.Get(key); ok {
 		return val, nil
@@ -6,6 +6,11 @@ import (
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/dillonkmcquade/gostore/internal/manifest"
+	"github.com/dillonkmcquade/gostore/internal/ratelimit"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
 )
 
 func TestLSMNew(t *testing.T) {
@@ -155,6 +160,30 @@ func TestLSMRead(t *testing.T) {
 	}
 }
 
+func TestLSMReadLatency(t *testing.T) {
+	// An auto-tuned limiter set only for compactions is told the latency of reads
+	limiter := ratelimit.New(1 << 20)
+	limiter.EnableAutoTune(ratelimit.AutoTuneOpts{Min_rate: 1 << 10, Max_rate: 1 << 20, Target_latency: time.Nanosecond, Interval: time.Nanosecond})
+	opts := NewTestLSMOpts(t.TempDir())
+	opts.ManifestOpts.Rate_limiter = limiter
+	tree, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	if err := tree.Write([]byte("key"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := tree.Read([]byte("key")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rate := limiter.Rate(); rate >= 1<<20 {
+		t.Errorf("Expected slow reads to lower the rate, found %v", rate)
+	}
+}
+
 func TestLSMFlush(t *testing.T) {
 	t.Parallel()
 	tmp := t.TempDir()
@@ -187,6 +216,304 @@ func TestLSMFlush(t *testing.T) {
 	}
 }
 
+func TestLSMForceFlush(t *testing.T) {
+	tmp := t.TempDir()
+	tree, err := New(NewTestLSMOpts(tmp))
+	if err != nil {
+		t.Error(err)
+	}
+	defer tree.Close()
+	for i := 0; i < 10; i++ {
+		err := tree.Write([]byte(fmt.Sprintf("%v", i)), []byte("test"))
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	err = tree.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := tree.(*GoStore)
+	if size := store.memTable.Size(); size != 0 {
+		t.Errorf("Memtable should be empty after flush, found %v entries", size)
+	}
+	version := store.manifest.Current()
+	defer version.Unref()
+	if len(version.Levels[0].Tables) != 1 {
+		t.Errorf("Expected the flushed table in L0, found %v tables", len(version.Levels[0].Tables))
+	}
+	if _, err := tree.Read([]byte("5")); err != nil {
+		t.Error(err)
+	}
+
+	// Nothing to flush
+	if err := tree.Flush(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLSMCompactRange(t *testing.T) {
+	tmp := t.TempDir()
+	tree, err := New(NewTestLSMOpts(tmp))
+	if err != nil {
+		t.Error(err)
+	}
+	defer tree.Close()
+	store := tree.(*GoStore)
+	for i := 0; i < 1000; i++ {
+		err := tree.Write([]byte(fmt.Sprintf("%04d", i)), []byte("test"))
+		if err != nil {
+			t.Error(err)
+		}
+		if i%250 == 249 {
+			if err := tree.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if err := tree.Delete([]byte(fmt.Sprintf("%04d", i))); err != nil {
+			t.Error(err)
+		}
+	}
+
+	err = tree.CompactRange(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := store.manifest.Current()
+	defer version.Unref()
+	last := len(version.Levels) - 1
+	for _, level := range version.Levels[:last] {
+		if len(level.Tables) != 0 {
+			t.Errorf("Expected L%v to be compacted, found %v tables", level.Number, len(level.Tables))
+		}
+	}
+	if len(version.Levels[last].Tables) == 0 {
+		t.Error("Expected every table in the last level")
+	}
+	if val, err := tree.Read([]byte("0050")); err == nil && len(val) != 0 {
+		t.Errorf("Deleted key should not be found, found %q", val)
+	}
+	if val, err := tree.Read([]byte("0500")); err != nil || string(val) != "test" {
+		t.Errorf("Expected test, found %q %v", val, err)
+	}
+}
+
+func TestLSMGetPropertiesOfAllTables(t *testing.T) {
+	tmp := t.TempDir()
+	tree, err := New(NewTestLSMOpts(tmp))
+	if err != nil {
+		t.Error(err)
+	}
+	defer tree.Close()
+	for i := 0; i < 10; i++ {
+		if err := tree.Write([]byte(fmt.Sprintf("%v", i)), []byte("test")); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := tree.Delete([]byte("0")); err != nil {
+		t.Error(err)
+	}
+	if err := tree.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	props, err := tree.GetPropertiesOfAllTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(props) != 1 {
+		t.Fatalf("Expected the properties of the flushed table, found %v tables", len(props))
+	}
+	for name, p := range props {
+		if p.NumEntries != 10 || p.NumTombstones != 1 || p.RawValueSize != 9*4 {
+			t.Errorf("Expected 10 entries, 1 tombstone and 36 value bytes in %v, found %+v", name, p)
+		}
+	}
+}
+
+func TestLSMApproximateSize(t *testing.T) {
+	tmp := t.TempDir()
+	tree, err := New(NewTestLSMOpts(tmp))
+	if err != nil {
+		t.Error(err)
+	}
+	defer tree.Close()
+	// Values large enough that estimates measured to the data block are close
+	value := make([]byte, 100)
+	for i := 0; i < 1000; i++ {
+		if err := tree.Write([]byte(fmt.Sprintf("%04d", i)), value); err != nil {
+			t.Error(err)
+		}
+		if i == 499 {
+			// Half of the keys are in tables, the rest in the memtable
+			if err := tree.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name  string
+		start []byte
+		end   []byte
+		count int64
+	}{
+		{name: "Test everything", start: nil, end: nil, count: 1000},
+		{name: "Test table", start: nil, end: []byte("0500"), count: 500},
+		{name: "Test memtable", start: []byte("0500"), end: nil, count: 500},
+		{name: "Test both", start: []byte("0250"), end: []byte("0750"), count: 500},
+		{name: "Test empty", start: []byte("1000"), end: nil, count: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := tree.ApproximateKeyCount(tt.start, tt.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count < tt.count*9/10 || count > tt.count*11/10 {
+				t.Errorf("Expected about %v keys, found %v", tt.count, count)
+			}
+			size, err := tree.ApproximateSize(tt.start, tt.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (size == 0) != (tt.count == 0) {
+				t.Errorf("Expected a size for %v keys, found %v bytes", tt.count, size)
+			}
+		})
+	}
+}
+
+func TestLSMIngestExternalFiles(t *testing.T) {
+	tmp := t.TempDir()
+	tree, err := New(NewTestLSMOpts(tmp))
+	if err != nil {
+		t.Error(err)
+	}
+	defer tree.Close()
+	for i := 0; i < 100; i++ {
+		if err := tree.Write([]byte(fmt.Sprintf("%04d", i)), []byte("old")); err != nil {
+			t.Error(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "bulk.segment")
+	w := sstable.NewSSTWriter(path, nil)
+	for i := 50; i < 150; i++ {
+		if err := w.Put([]byte(fmt.Sprintf("%04d", i)), []byte("ingested")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := w.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.IngestExternalFiles([]string{path}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Write([]byte("0060"), []byte("new")); err != nil {
+		t.Error(err)
+	}
+
+	// Ingested keys are newer than earlier writes and older than later ones
+	for key, expected := range map[string]string{"0010": "old", "0075": "ingested", "0120": "ingested", "0060": "new"} {
+		value, err := tree.Read([]byte(key))
+		if err != nil || string(value) != expected {
+			t.Errorf("Expected %v for key %v, found %s, %v", expected, key, value, err)
+		}
+	}
+	if err := tree.IngestExternalFiles([]string{filepath.Join(tmp, "missing.segment")}); err == nil {
+		t.Error("Expected an error ingesting a missing file")
+	}
+}
+
+func TestLSMCheckpoint(t *testing.T) {
+	tmp := t.TempDir()
+	tree, err := New(NewTestLSMOpts(tmp))
+	if err != nil {
+		t.Error(err)
+	}
+	defer tree.Close()
+	for i := 0; i < 300; i++ {
+		if err := tree.Write([]byte(fmt.Sprintf("%04d", i)), []byte("value")); err != nil {
+			t.Error(err)
+		}
+		if i == 99 {
+			if err := tree.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	dir := filepath.Join(t.TempDir(), "checkpoint")
+	if err := tree.Checkpoint(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Write([]byte("0300"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := tree.Checkpoint(dir); err == nil {
+		t.Error("Expected an error writing a checkpoint to an existing directory")
+	}
+
+	copied, err := New(NewDefaultLSMOpts(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copied.Close()
+	for _, key := range []string{"0000", "0150", "0299"} {
+		if _, err := copied.Read([]byte(key)); err != nil {
+			t.Errorf("Expected key %v in the checkpoint, found %v", key, err)
+		}
+	}
+	if _, err := copied.Read([]byte("0300")); err == nil {
+		t.Error("Expected writes made after the checkpoint to be missing from it")
+	}
+}
+
+func TestLSMCheckpointTiered(t *testing.T) {
+	opts := NewTestLSMOpts(t.TempDir())
+	opts.ManifestOpts.Compaction_style = manifest.TIERED
+	tree, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	// Each pass overwrites every key, so that the runs compacted into lower levels overlap
+	for pass := 0; pass < 10; pass++ {
+		for i := 0; i < 4000; i++ {
+			if err := tree.Write([]byte(fmt.Sprintf("%04d", i)), []byte(fmt.Sprintf("value%v", pass))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	dir := filepath.Join(t.TempDir(), "checkpoint")
+	if err := tree.Checkpoint(dir); err != nil {
+		t.Fatal(err)
+	}
+	copied, err := New(NewDefaultLSMOpts(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copied.Close()
+	if style := copied.(*GoStore).manifest.Compaction_style; style != manifest.TIERED {
+		t.Errorf("Expected the checkpoint to open as a tiered store, found %v", style)
+	}
+	wrong := 0
+	for i := 0; i < 4000; i++ {
+		value, err := copied.Read([]byte(fmt.Sprintf("%04d", i)))
+		if err != nil || string(value) != "value9" {
+			wrong++
+		}
+	}
+	if wrong > 0 {
+		t.Errorf("Expected the last value of every key in the checkpoint, %v of 4000 differ", wrong)
+	}
+}
+
 // func TestCompactedRead(t *testing.T) {
 // 	tmp := t.TempDir()
 //
@@ -0,0 +1,138 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// Name of the file recording the layout of a checkpoint, stored next to its manifest
+const layoutFileName = "LAYOUT"
+
+// Compaction style and number of levels of the store a checkpoint was taken from. Tiered
+// levels hold overlapping runs that a leveled manifest would search as a single run, so New
+// opens a checkpoint with the recorded layout whatever the defaults of its opts.
+type layout struct {
+	style     CompactionStyle
+	numLevels int
+}
+
+// Checkpoint writes a copy of the current version laid out as opts describes: every table
+// is hard linked into the directory of its level, the filter files of tables written before
+// filters were embedded into opts.BloomPath, and a manifest holding only the copied tables
+// is written to opts.Path beside the layout of m. A manifest opened with opts holds the same
+// data as the version.
+//
+// Table files are never modified, so links share their data with the live store. Files are
+// copied if the directories are on different file systems.
+func (m *Manifest) Checkpoint(opts *Opts) error {
+	version := m.Current()
+	// The files of the version are not deleted while it is referenced
+	defer version.Unref()
+
+	err := os.MkdirAll(filepath.Dir(opts.Path), 0750)
+	if err != nil {
+		return fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	target := &Manifest{Path: opts.Path, levelPaths: opts.LevelPaths}
+	dirs := []string{filepath.Dir(opts.Path)}
+	edit := &VersionEdit{}
+	for _, level := range version.Levels {
+		dir := target.levelPath(level.Number)
+		err = os.MkdirAll(dir, 0750)
+		if err != nil {
+			return fmt.Errorf("os.MkdirAll: %w", err)
+		}
+		dirs = append(dirs, dir)
+		for _, table := range level.Tables {
+			copied, err := checkpointTable(table, dir, opts.BloomPath)
+			if err != nil {
+				return err
+			}
+			edit.AddTable(copied, level.Number)
+		}
+	}
+	if opts.BloomPath != "" {
+		dirs = append(dirs, opts.BloomPath)
+	}
+
+	_, err = writeEdit(opts.Path, edit)
+	if err != nil {
+		return fmt.Errorf("writeEdit: %w", err)
+	}
+	for _, dir := range dirs {
+		err = syncDir(dir)
+		// The filter directory is only created if a filter file was linked
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("syncDir: %w", err)
+		}
+	}
+	err = writeLayout(filepath.Dir(opts.Path), layout{style: m.Compaction_style, numLevels: len(version.Levels)})
+	if err != nil {
+		return fmt.Errorf("writeLayout: %w", err)
+	}
+	return setCurrent(filepath.Dir(opts.Path), filepath.Base(opts.Path))
+}
+
+// Records l in dir
+func writeLayout(dir string, l layout) error {
+	return replaceFile(dir, layoutFileName, fmt.Sprintf("compaction_style %d\nnum_levels %d\n", l.style, l.numLevels))
+}
+
+// Returns the layout recorded in dir, or false if dir holds no checkpoint
+func readLayout(dir string) (layout, bool, error) {
+	b, err := os.ReadFile(filepath.Join(dir, layoutFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return layout{}, false, nil
+	}
+	if err != nil {
+		return layout{}, false, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	var l layout
+	_, err = fmt.Sscanf(string(b), "compaction_style %d\nnum_levels %d\n", &l.style, &l.numLevels)
+	if err != nil {
+		return layout{}, false, fmt.Errorf("%v: %w", layoutFileName, err)
+	}
+	return l, true, nil
+}
+
+// Links the files of table into dir, and its filter file if it has one into bloomPath, and
+// returns the table at its new location
+func checkpointTable(table *sstable.SSTable, dir string, bloomPath string) (*sstable.SSTable, error) {
+	name := filepath.Join(dir, filepath.Base(table.Name))
+	err := linkOrCopy(table.Name, name)
+	if err != nil {
+		return nil, fmt.Errorf("linkOrCopy: %w", err)
+	}
+	meta := table.FilterMetadata()
+	if meta.Name != "" {
+		err = os.MkdirAll(bloomPath, 0750)
+		if err != nil {
+			return nil, fmt.Errorf("os.MkdirAll: %w", err)
+		}
+		filterName := filepath.Join(bloomPath, filepath.Base(meta.Name))
+		err = linkOrCopy(meta.Name, filterName)
+		if err != nil {
+			return nil, fmt.Errorf("linkOrCopy: %w", err)
+		}
+		meta.Name = filterName
+	}
+	return &sstable.SSTable{
+		Name:            name,
+		Filter:          filter.Open(meta),
+		Size:            table.Size,
+		First:           table.First,
+		Last:            table.Last,
+		CreatedOn:       table.CreatedOn,
+		NumEntries:      table.NumEntries,
+		NumTombstones:   table.NumTombstones,
+		OldestTombstone: table.OldestTombstone,
+		Run:             table.Run,
+		MinSequence:     table.MinSequence,
+		MaxSequence:     table.MaxSequence,
+	}, nil
+}
@@ -0,0 +1,120 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	opts := newWorkloadOpts(t, t.TempDir())
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for level, first := range map[int]int{0: 400, 1: 200, 2: 0} {
+		if err := man.AddTable(newKeysTable(t, opts, first, 100), level); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sidecar := newSidecarTable(t, opts.LevelPaths[3], opts.BloomPath, 2000)
+	if err := man.AddTable(sidecar, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint := newObsoleteOpts(filepath.Join(t.TempDir(), "checkpoint"))
+	if err := man.Checkpoint(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	if err := man.Close(); err != nil {
+		t.Fatal(err)
+	}
+	filterName := filepath.Join(checkpoint.BloomPath, filepath.Base(sidecar.FilterMetadata().Name))
+	if !exists(filterName) {
+		t.Errorf("Expected the filter file %v in the checkpoint", filterName)
+	}
+	// The checkpoint shares no path with the store
+	if err := os.RemoveAll(filepath.Dir(opts.Path)); err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := New(checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copied.Close()
+	version := copied.Current()
+	defer version.Unref()
+	for n, expected := range []int{1, 1, 1, 1} {
+		level := version.Levels[n]
+		if len(level.Tables) != expected {
+			t.Errorf("Expected %v tables in level %v, found %v", expected, n, len(level.Tables))
+		}
+		for _, table := range level.Tables {
+			if filepath.Dir(table.Name) != checkpoint.LevelPaths[n] {
+				t.Errorf("Expected table %v in %v", table.Name, checkpoint.LevelPaths[n])
+			}
+		}
+	}
+	for _, key := range []string{fmt.Sprintf("%08d", 50), fmt.Sprintf("%08d", 250), fmt.Sprintf("%08d", 450), "2005"} {
+		if _, err := copied.Search([]byte(key)); err != nil {
+			t.Errorf("Expected key %v in the checkpoint, found %v", key, err)
+		}
+	}
+}
+
+func TestCheckpointTiered(t *testing.T) {
+	opts := newWorkloadOpts(t, t.TempDir())
+	opts.Compaction_style = TIERED
+	opts.Tier_run_trigger = 100
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Overlapping runs of level 1, the newer one added first
+	for run, value := range []string{"new", "old"} {
+		table := newExternalTable(t, opts.LevelPaths[1], 0, 100, value)
+		table.Run = uint64(2 - run)
+		if err := man.AddTable(table, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkpoint := newObsoleteOpts(filepath.Join(t.TempDir(), "checkpoint"))
+	if err := man.Checkpoint(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	if err := man.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The options of the checkpoint leave the compaction style at its default
+	copied, err := New(checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copied.Close()
+	if copied.Compaction_style != TIERED {
+		t.Errorf("Expected the compaction style of the store, found %v", copied.Compaction_style)
+	}
+	version := copied.Current()
+	defer version.Unref()
+	if !version.Levels[1].Tiered || len(version.Levels[1].Runs()) != 2 {
+		t.Errorf("Expected 2 runs in a tiered level 1")
+	}
+	for key := 0; key < 100; key++ {
+		value, err := copied.Search([]byte(fmt.Sprintf("%08d", key)))
+		if err != nil || string(value) != "new" {
+			t.Fatalf("Expected the newer run's value for key %v, found %s, %v", key, value, err)
+		}
+	}
+
+	for _, style := range []CompactionStyle{LEVELED, FIFO} {
+		other := newObsoleteOpts(filepath.Dir(checkpoint.Path))
+		other.Compaction_style = style
+		if _, err := New(other); err == nil {
+			t.Errorf("Expected an error opening the checkpoint with compaction style %v", style)
+		}
+	}
+}
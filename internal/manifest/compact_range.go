@@ -0,0 +1,107 @@
+package manifest
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// Returns true if table holds keys in [start, end]. A nil bound leaves that side open.
+func inRange(table *sstable.SSTable, start []byte, end []byte) bool {
+	return (start == nil || slices.Compare(table.Last, start) >= 0) &&
+		(end == nil || slices.Compare(table.First, end) <= 0)
+}
+
+// Returns the compaction moving the tables of level that hold keys in [start, end] into
+// the next level, or nil if there are none. The last level is only merged when tiered.
+func (m *Manifest) rangeCompaction(v *Version, level int, start []byte, end []byte) *compaction {
+	lvl := v.Levels[level]
+	if !slices.ContainsFunc(lvl.Tables, func(t *sstable.SSTable) bool { return inRange(t, start, end) }) {
+		return nil
+	}
+	last := len(v.Levels) - 1
+	if lvl.Tiered {
+		// Runs overlap each other, so the whole level is merged
+		runs := lvl.Runs()
+		if level == last && len(runs) < 2 {
+			return nil
+		}
+		return newRunsCompaction(v, level, runs, min(level+1, last))
+	}
+	if level == last {
+		return nil
+	}
+	if level == 0 {
+		// Newer L0 tables shadow older ones outside the range, so L0 is compacted whole
+		return v.level0Compaction()
+	}
+	inputs := []*sstable.SSTable{}
+	for _, table := range lvl.Tables {
+		if inRange(table, start, end) {
+			inputs = append(inputs, table)
+		}
+	}
+	overlaps := []*sstable.SSTable{}
+	for _, table := range v.Levels[level+1].Tables {
+		if slices.ContainsFunc(inputs, table.Overlaps) {
+			overlaps = append(overlaps, table)
+		}
+	}
+	return newCompaction(v, level, inputs, overlaps)
+}
+
+// Registers the compaction returned by build once it no longer conflicts with a running
+// compaction. Returns nil if build finds nothing to compact.
+func (m *Manifest) reserveCompaction(build func(v *Version) *compaction) *compaction {
+	m.schedMut.Lock()
+	defer m.schedMut.Unlock()
+	for {
+		version := m.Current()
+		c := build(version)
+		if c == nil {
+			version.Unref()
+			return nil
+		}
+		if !slices.ContainsFunc(m.running, c.conflicts) {
+			m.running = append(m.running, c)
+			m.waitForCompaction.Add(1)
+			return c
+		}
+		version.Unref()
+		m.compactionDone.Wait()
+	}
+}
+
+// CompactRange compacts every table holding keys in [start, end] down to the last level
+// and returns once done. A nil start or end leaves that side of the range open.
+//
+// Level 0 is always compacted whole. With the FIFO style nothing is merged, so CompactRange
+// does nothing.
+func (m *Manifest) CompactRange(start []byte, end []byte) error {
+	if m.Compaction_style == FIFO {
+		return nil
+	}
+	slog.Info("Compacting range", "start", start, "end", end)
+	// Compactions may add levels below the last one, so the level count is read again after each
+	for level := 0; level < m.numLevels(); level++ {
+		c := m.reserveCompaction(func(v *Version) *compaction {
+			if level >= len(v.Levels) {
+				return nil
+			}
+			return m.rangeCompaction(v, level, start, end)
+		})
+		if c == nil {
+			continue
+		}
+		m.runCompaction(c)
+	}
+	return nil
+}
+
+// Returns the number of levels of the current version
+func (m *Manifest) numLevels() int {
+	version := m.Current()
+	defer version.Unref()
+	return len(version.Levels)
+}
@@ -0,0 +1,150 @@
+package manifest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Returns the number of tables in each level of the current version
+func tableCounts(man *Manifest) []int {
+	version := man.Current()
+	defer version.Unref()
+	counts := []int{}
+	for _, level := range version.Levels {
+		counts = append(counts, len(level.Tables))
+	}
+	return counts
+}
+
+func TestCompactRange(t *testing.T) {
+	key := func(i int) []byte { return []byte(fmt.Sprintf("%08d", i)) }
+
+	t.Run("Test leveled", func(t *testing.T) {
+		opts := newWorkloadOpts(t, t.TempDir())
+		// Nothing is compacted automatically
+		opts.Level0_file_trigger = 100
+		opts.Level0_max_size = 1 << 30
+		opts.SSTable_max_size = 100
+		man, err := New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer man.Close()
+		for i := 0; i < 3; i++ {
+			if err := man.AddTable(newKeysTable(t, opts, i*50, 100), 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := man.CompactRange(nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		counts := tableCounts(man)
+		if counts[0]+counts[1]+counts[2] != 0 || counts[3] == 0 {
+			t.Fatalf("Expected every table in the last level, found %v", counts)
+		}
+		for _, i := range []int{0, 120, 199} {
+			if _, err := man.Search(key(i)); err != nil {
+				t.Errorf("Expected %v to be found, found %v", i, err)
+			}
+		}
+
+		// Only tables holding the range leave level 1. Both tables are moved to level 1 first
+		for _, first := range []int{0, 500} {
+			if err := man.AddTable(newKeysTable(t, opts, first, 100), 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		man.runCompaction(man.reserveCompaction(func(v *Version) *compaction { return v.level0Compaction() }))
+		if err := man.CompactRange(key(0), key(99)); err != nil {
+			t.Fatal(err)
+		}
+		version := man.Current()
+		defer version.Unref()
+		for _, table := range version.Levels[1].Tables {
+			if inRange(table, key(0), key(99)) {
+				t.Errorf("Expected table %s-%s to be compacted", table.First, table.Last)
+			}
+		}
+		if len(version.Levels[1].Tables) == 0 {
+			t.Error("Expected tables outside the range to stay in level 1")
+		}
+	})
+
+	t.Run("Test tiered", func(t *testing.T) {
+		opts := newWorkloadOpts(t, t.TempDir())
+		opts.Compaction_style = TIERED
+		opts.Tier_run_trigger = 100
+		man, err := New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer man.Close()
+		for i := 0; i < 3; i++ {
+			if err := man.AddTable(newKeysTable(t, opts, i*50, 100), 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := man.CompactRange(key(10), key(20)); err != nil {
+			t.Fatal(err)
+		}
+		version := man.Current()
+		defer version.Unref()
+		for _, level := range version.Levels[:3] {
+			if len(level.Tables) != 0 {
+				t.Errorf("Expected level %v to be empty, found %v tables", level.Number, len(level.Tables))
+			}
+		}
+		if runs := len(version.Levels[3].Runs()); runs != 1 {
+			t.Errorf("Expected a single run in the last level, found %v", runs)
+		}
+	})
+
+	t.Run("Test levels added while compacting", func(t *testing.T) {
+		opts := newWorkloadOpts(t, t.TempDir())
+		opts.Level0_file_trigger = 100
+		opts.Level0_max_size = 1 << 30
+		opts.Max_levels = 8
+		man, err := New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer man.Close()
+		for i := 0; i < 3; i++ {
+			if err := man.AddTable(newKeysTable(t, opts, i*50, 100), 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Levels are added below the last one while the range is compacted, as the last
+		// level outgrowing its target would
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for level := 4; level < 8; level++ {
+				man.mut.Lock()
+				err := man.ensureLevel(level)
+				man.mut.Unlock()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if err := man.AddTable(newKeysTable(t, opts, 1000*level, 10), level); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+		if err := man.CompactRange(nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		wg.Wait()
+		for _, i := range []int{0, 199, 4005, 7005} {
+			if _, err := man.Search(key(i)); err != nil {
+				t.Errorf("Expected %v to be found, found %v", i, err)
+			}
+		}
+	})
+}
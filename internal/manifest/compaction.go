@@ -1,13 +1,15 @@
 package manifest
 
 import (
-	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
-	"sync"
+	"slices"
+	"sync/atomic"
+	"time"
 
 	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/pb"
 	"github.com/dillonkmcquade/gostore/internal/sstable"
 )
 
@@ -17,8 +19,8 @@ import (
 //
 // 1. Compaction Trigger - When to re-organize the data layout?
 //		- Level saturations(size) <-
-//		- # of sorted runs
-//		- File staleness
+//		- # of sorted runs <-
+//		- File staleness <-
 //		- Space amplification
 //		- Tombstone-TTL <- Implement later
 // 2. Data Layout - How to layout data physically on storage?
@@ -32,205 +34,284 @@ import (
 //		- File
 //		- Multiple files <-
 // 4. Data Movement Policy - Which block of data to be moved during reorganization?
-//		- Round-robin
-//		- Least overlapping parent
-//		- Least overlapping grandparent
-//		- Coldest
-//		- Oldest <-
-//		- Tombstone density
-//		- Tombstone-TTL
+//		- Round-robin <-
+//		- Least overlapping parent <-
+//		- Least overlapping grandparent <-
+//		- Coldest <-
+//		- Oldest <- (default)
+//		- Tombstone density <-
+//		- Tombstone-TTL <-
+//		See FilePicker
 
+// Cumulative compaction counters
+type compactionStats struct {
+	compactions  atomic.Int64
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+}
+
+// CompactionStats is a snapshot of the compaction counters of a manifest
+type CompactionStats struct {
+	Compactions  int64 // Compactions run, including trivial moves
+	BytesRead    int64 // Bytes of input tables merged
+	BytesWritten int64 // Bytes of output tables written
+}
+
+// Returns the compaction counters accumulated since the manifest was opened
+func (man *Manifest) CompactionStats() CompactionStats {
+	return CompactionStats{
+		Compactions:  man.stats.compactions.Load(),
+		BytesRead:    man.stats.bytesRead.Load(),
+		BytesWritten: man.stats.bytesWritten.Load(),
+	}
+}
+
+// Compact schedules compactions until the manifest is closed.
+//
+// The scheduler wakes whenever a version is installed, e.g. after a flush or a finished
+// compaction, and hands the best non-conflicting compactions to a pool of workers.
 func (man *Manifest) Compact() {
+	defer close(man.stopped)
+	tasks := make(chan *compaction)
+	defer close(tasks)
+	for i := 0; i < man.Max_compactions; i++ {
+		go man.compactionWorker(tasks)
+	}
+	// Tables expire without a new version being installed
+	var expiry <-chan time.Time
+	if man.Compaction_style == FIFO && man.Fifo_ttl > 0 {
+		ticker := time.NewTicker(man.fifoCheckInterval())
+		defer ticker.Stop()
+		expiry = ticker.C
+	}
 	for {
 		select {
 		case <-man.done:
 			slog.Info("Compaction thread closed")
 			return
-		case <-man.compactionTicker.C:
-			allCompacted := true
-			for _, level := range man.Levels {
-				if man.Trigger(level) {
-					slog.Debug("Compaction triggered")
-					allCompacted = false
-					break
-				}
-			}
+		case <-man.versionReleased:
+			man.DeleteObsoleteFiles()
+		case <-man.wakeCompaction:
+			man.schedule(tasks)
+		case <-expiry:
+			man.schedule(tasks)
+		}
+	}
+}
 
-			if allCompacted {
-				continue
-			}
+// Wakes the compaction scheduler without blocking
+func (man *Manifest) wake() {
+	select {
+	case man.wakeCompaction <- struct{}{}:
+	default:
+	}
+}
 
-			for _, level := range man.Levels {
-				if man.Trigger(level) {
-					man.mut.Lock()
-					if level.Number == 0 {
-						man.level_0_compact(level)
-					} else {
-						man.lower_level_compact(level)
-					}
-					man.mut.Unlock()
-				}
-			}
+// Hands compactions to workers until every worker is busy or nothing needs compacting
+func (man *Manifest) schedule(tasks chan<- *compaction) {
+	for {
+		man.schedMut.Lock()
+		if len(man.running) >= man.Max_compactions {
+			man.schedMut.Unlock()
+			return
 		}
+		c := man.pickCompaction()
+		if c == nil {
+			man.schedMut.Unlock()
+			return
+		}
+		man.running = append(man.running, c)
+		man.waitForCompaction.Add(1)
+		man.schedMut.Unlock()
+
+		slog.Debug("Compaction scheduled", "level", c.level, "score", c.score)
+		tasks <- c
 	}
 }
 
-// Returns compaction task if level triggers a compaction
-func (m *Manifest) Trigger(level *Level) bool {
-	m.mut.Lock()
-	defer m.mut.Unlock()
-	return level.Size >= level.MaxSize
+func (man *Manifest) compactionWorker(tasks <-chan *compaction) {
+	for c := range tasks {
+		man.runCompaction(c)
+	}
+}
+
+// Runs a compaction registered in man.running, then releases it
+func (man *Manifest) runCompaction(c *compaction) {
+	switch {
+	case c.fifo:
+		man.fifo_compact(c)
+	case c.tiered:
+		man.tiered_compact(c)
+	case c.level == 0:
+		man.level_0_compact(c)
+	default:
+		man.lower_level_compact(c)
+	}
+	man.stats.compactions.Add(1)
+
+	man.schedMut.Lock()
+	man.running = slices.DeleteFunc(man.running, func(r *compaction) bool { return r == c })
+	man.compactionDone.Broadcast()
+	man.schedMut.Unlock()
+	c.version.Unref()
+	man.waitForCompaction.Done()
+
+	// Compactions that conflicted with c may run now
+	man.wake()
 }
 
-// The goal of L0 compaction is to insert the unsorted collection of sorted tables into the sorted L1.
+// Merge tables, split the output and write each split table to the output level of c.
 //
-// All tables from L0 are merged->split->sync->L1
-func (man *Manifest) level_0_compact(level *Level) {
-	man.waitForCompaction.Add(1)
-	slog.Debug("============ Level 0 Compaction =============")
-	// Merge all tables
-	merged := sstable.Merge(level.Tables...)
-
-	// Split
-	split := sstable.Split(merged, man.SSTable_max_size, &sstable.Opts{
+// Output tables are recorded as added to the output level in edit.
+func (man *Manifest) mergeInto(edit *VersionEdit, c *compaction, tables ...*sstable.SSTable) {
+	level := c.version.Levels[c.output]
+
+	// Entries do not carry timestamps, so outputs inherit the oldest tombstone of the inputs
+	// and the runs whose writes they hold
+	var inherited outputMeta
+	for i, table := range tables {
+		man.stats.bytesRead.Add(table.Size)
+		if !table.OldestTombstone.IsZero() && (inherited.oldestTombstone.IsZero() || table.OldestTombstone.Before(inherited.oldestTombstone)) {
+			inherited.oldestTombstone = table.OldestTombstone
+		}
+		first, last := table.Sequences()
+		if i == 0 || first < inherited.minSequence {
+			inherited.minSequence = first
+		}
+		inherited.maxSequence = max(inherited.maxSequence, last)
+	}
+
+	var outputs []*sstable.SSTable
+	if man.Max_subcompactions > 1 {
+		outputs = man.subcompact(c, tables, inherited)
+	} else {
+		outputs = man.writeOutputs(level, man.mergeEntries(c, tables), inherited)
+	}
+	for _, table := range outputs {
+		edit.AddTable(table, level.Number)
+	}
+}
+
+// Metadata the outputs of a compaction inherit from its inputs
+type outputMeta struct {
+	oldestTombstone time.Time // Oldest tombstone of the inputs
+	minSequence     uint64    // Oldest run whose writes the inputs hold
+	maxSequence     uint64    // Newest run whose writes the inputs hold
+}
+
+// Split entries into tables, write each table to the directory of level and return them in key order
+func (man *Manifest) writeOutputs(level *Level, entries <-chan *pb.SSTable_Entry, inherited outputMeta) []*sstable.SSTable {
+	split := sstable.Split(entries, man.SSTable_max_size, &sstable.Opts{
 		BloomOpts: &filter.Opts{
-			Size: uint64(man.SSTable_max_size * 10),
-			Path: man.BloomPath,
+			Type:         man.filterType(level.Number),
+			Bits_per_key: man.bitsPerKey(level.Number),
 		},
+		RangeOpts:     man.rangeOpts(),
+		Limiter:       man.Rate_limiter,
+		Cache:         man.Block_cache,
+		BlockSize:     man.Block_size,
+		PartitionSize: man.Index_partition_size,
+		Collectors:    man.Properties_collectors,
 	})
 
-	// Write files and add to manifest
+	outputs := []*sstable.SSTable{}
 	for splitTable := range split {
-		splitTable.Name = filepath.Join(man.Levels[1].Path, sstable.GenerateUniqueSegmentName(splitTable.CreatedOn))
+		splitTable.Name = filepath.Join(level.Path, sstable.GenerateUniqueSegmentName(splitTable.CreatedOn))
+		splitTable.OldestTombstone = inherited.oldestTombstone
+		// Written to the properties block of the table
+		splitTable.MinSequence, splitTable.MaxSequence = inherited.minSequence, inherited.maxSequence
 
-		_, err := splitTable.Sync()
+		size, err := splitTable.Sync()
 		if err != nil {
 			slog.Error("Failed to sync table", "filename", splitTable.Name)
 			panic(err)
 		}
+		man.stats.bytesWritten.Add(size)
+		outputs = append(outputs, splitTable)
+	}
+	return outputs
+}
 
-		err = splitTable.SaveFilter()
-		if err != nil {
-			slog.Error("Failed to save filter", "filename", splitTable.Filter.Name)
-			panic(err)
-		}
+// The goal of L0 compaction is to insert the unsorted collection of sorted tables into the sorted base level.
+//
+// The L0 inputs of c are merged with their overlapping base level tables->split->sync->base level.
+// The base level is L1 unless Dynamic_level_size leaves the levels above the data empty.
+// Tables flushed since c was picked are left in L0.
+func (man *Manifest) level_0_compact(c *compaction) {
+	slog.Debug("============ Level 0 Compaction =============", "output", c.output)
+	edit := &VersionEdit{}
 
-		man.Levels[1].Add(splitTable)
-		pto, err := splitTable.ToProto()
-		if err != nil {
-			slog.Error("error marshalling to protobuf")
-			panic(err)
-		}
-		entry := &ManifestEntry{Op: ADDTABLE, Table: pto, Level: 1}
-		err = man.wal.Write(entry)
-		if err != nil {
-			slog.Error("Failed to add table to level 1", "filename", splitTable.Name)
-			panic(err)
-		}
+	// The base level is older than L0 and L0 is in flush order, so later tables win during the merge
+	man.mergeInto(edit, c, append(slices.Clone(c.overlaps), c.inputs...)...)
+	for _, tbl := range c.overlaps {
+		edit.RemoveTable(tbl, c.output)
 	}
-
-	var wg sync.WaitGroup
-	for _, tbl := range level.Tables {
-		wg.Add(1)
-		go func(t *sstable.SSTable) {
-			err := os.Remove(t.Name)
-			if err != nil {
-				slog.Warn("Failure to remove table", "filename", t.Name)
-			}
-			err = os.Remove(t.Filter.Name)
-			if err != nil {
-				slog.Warn("Failure to remove filter", "filename", t.Filter.Name)
-			}
-			wg.Done()
-		}(tbl)
+	for _, tbl := range c.inputs {
+		edit.RemoveTable(tbl, 0)
 	}
-	wg.Wait()
 
-	man.Levels[0].Clear()
-	entry := &ManifestEntry{Op: CLEARTABLE, Table: nil, Level: 0}
-	err := man.wal.Write(entry)
+	// Outputs and removals become visible together, the inputs are collected as obsolete
+	err := man.LogAndApply(edit)
 	if err != nil {
-		slog.Error("Failed to clear level")
+		slog.Error("Failed to commit level 0 compaction")
 		panic(err)
 	}
-	man.waitForCompaction.Done()
 }
 
-// Merge oldest table from upper level into overlapping lower level tables
-func (man *Manifest) lower_level_compact(level *Level) {
-	// Choose oldest table
-	table := sstable.Oldest(level.Tables)
-	// find tables in lowerlevel that overlap with table in upper level
-	overlaps := sstable.Overlapping(table, man.Levels[level.Number+1].Tables)
-
-	// if lower level is empty, simply move the table from upper level to lower level
-	if len(overlaps) == 0 {
-		newLocation := filepath.Join(man.Levels[level.Number+1].Path, filepath.Base(table.Name))
-
-		err := os.Rename(table.Name, newLocation)
-		if err != nil {
-			panic(err)
-		}
+// Merge the upper level tables of c into the lower level tables they overlap
+func (man *Manifest) lower_level_compact(c *compaction) {
+	lower := c.version.Levels[c.level+1]
 
-		table.Name = newLocation
-
-		// Update manifest
-		err = man.AddTable(table, level.Number+1)
-		if err != nil {
-			panic(err)
-		}
-		err = man.RemoveTable(table, level.Number)
-		if err != nil {
-			panic(err)
-		}
-		return
+	edit := &VersionEdit{}
+	for _, table := range c.inputs {
+		edit.RemoveTable(table, c.level)
 	}
 
-	merged := sstable.Merge(append(overlaps, table)...)
-
-	// Split merged table into smaller sizes
-	split := sstable.Split(merged, man.SSTable_max_size, &sstable.Opts{
-		BloomOpts: &filter.Opts{
-			Size: uint64(man.SSTable_max_size * 10),
-			Path: man.BloomPath,
-		},
-	})
+	// if no lower level table overlaps, simply move the tables from upper level to lower level
+	if len(c.overlaps) == 0 {
+		for _, table := range c.inputs {
+			newLocation := filepath.Join(lower.Path, filepath.Base(table.Name))
 
-	// Write files and add to manifest
-	for splitTable := range split {
-		splitTable.Name = filepath.Join(man.Levels[level.Number+1].Path, fmt.Sprintf("%v.segment", splitTable.CreatedOn.Unix()))
+			// Link first so that the manifest always points at an existing file
+			err := os.Link(table.Name, newLocation)
+			if err != nil {
+				panic(err)
+			}
 
-		_, err := splitTable.Sync()
-		if err != nil {
-			slog.Error("Failed to sync table", "filename", splitTable.Name)
-			panic(err)
-		}
-		err = splitTable.SaveFilter()
-		if err != nil {
-			slog.Error("Failed to save filter", "filename", splitTable.Filter.Name)
-			panic(err)
+			moved := &sstable.SSTable{
+				Name:            newLocation,
+				Filter:          filter.Open(table.FilterMetadata()),
+				Size:            table.Size,
+				First:           table.First,
+				Last:            table.Last,
+				CreatedOn:       table.CreatedOn,
+				NumEntries:      table.NumEntries,
+				NumTombstones:   table.NumTombstones,
+				OldestTombstone: table.OldestTombstone,
+				Run:             table.Run,
+				MinSequence:     table.MinSequence,
+				MaxSequence:     table.MaxSequence,
+			}
+			edit.AddTable(moved, lower.Number)
 		}
-		err = man.AddTable(splitTable, level.Number+1)
+
+		// The old links are collected as obsolete
+		err := man.LogAndApply(edit)
 		if err != nil {
-			slog.Error("Failed to add table to level 1", "filename", splitTable.Name)
 			panic(err)
 		}
+		return
 	}
 
-	// Cleanup tables from lowerlevel
-	for _, overlapping_table := range overlaps {
-		err := man.RemoveTable(overlapping_table, level.Number+1)
-		if err != nil {
-			slog.Error("Failure to remove table", "filename", overlapping_table.Name)
-			panic(err)
-		}
+	// Upper tables are newest, merge them last so their entries win
+	man.mergeInto(edit, c, append(slices.Clone(c.overlaps), c.inputs...)...)
+	for _, overlapping_table := range c.overlaps {
+		edit.RemoveTable(overlapping_table, lower.Number)
 	}
 
-	// Cleanup table from upper level
-	err := man.RemoveTable(table, level.Number)
+	err := man.LogAndApply(edit)
 	if err != nil {
-		slog.Error("Failure to remove table", "filename", table.Name)
+		slog.Error("Failed to commit compaction", "level", c.level)
 		panic(err)
 	}
-	man.waitForCompaction.Done()
 }
@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"slices"
+
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// Decision returned by a CompactionFilter for an entry
+type FilterDecision int
+
+const (
+	KEEP    FilterDecision = iota // Write the entry unchanged
+	DROP                          // Leave the entry out of the compaction output
+	REPLACE                       // Write the entry with the returned value
+)
+
+// Describes the compaction a CompactionFilter is called from
+type CompactionInfo struct {
+	Level      int  // Level the outputs are written to
+	Bottommost bool // No older version of the keys exists outside the compaction, so a dropped entry cannot resurface
+}
+
+// CompactionFilter is called for each live entry rewritten by a compaction. It returns
+// whether to keep, drop or replace the entry, and the new value when replacing.
+//
+// Deletes are not passed to the filter. Dropping an entry in a compaction that is not
+// bottommost exposes older versions of the key, if any.
+type CompactionFilter func(info CompactionInfo, key []byte, value []byte) (FilterDecision, []byte)
+
+// Returns true if no table outside c holds keys in its range in the output level or below.
+// Newer runs of a tiered level are counted too, which only makes the answer conservative.
+func (c *compaction) bottommost() bool {
+	for _, level := range c.version.Levels[c.output:] {
+		for _, table := range level.Tables {
+			if slices.Contains(c.inputs, table) || slices.Contains(c.overlaps, table) {
+				continue
+			}
+			if inRange(table, c.first, c.last) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Passes each entry of in through filter
+func filterEntries(in <-chan *pb.SSTable_Entry, filter CompactionFilter, info CompactionInfo) <-chan *pb.SSTable_Entry {
+	out := make(chan *pb.SSTable_Entry)
+	go func() {
+		defer close(out)
+		for entry := range in {
+			if entry.Op == pb.Operation_OPERATION_DELETE {
+				out <- entry
+				continue
+			}
+			decision, value := filter(info, entry.Key, entry.Value)
+			switch decision {
+			case DROP:
+				continue
+			case REPLACE:
+				entry = &pb.SSTable_Entry{Key: entry.Key, Value: value, Op: entry.Op}
+			}
+			out <- entry
+		}
+	}()
+	return out
+}
+
+// Returns the merged entries of tables, passed through the compaction filter if one is set
+func (man *Manifest) mergeEntries(c *compaction, tables []*sstable.SSTable) <-chan *pb.SSTable_Entry {
+	return man.filtered(c, sstable.MergeLimited(man.Rate_limiter, tables...))
+}
+
+// Passes the merged entries of c through the compaction filter if one is set
+func (man *Manifest) filtered(c *compaction, merged <-chan *pb.SSTable_Entry) <-chan *pb.SSTable_Entry {
+	if man.Compaction_filter == nil {
+		return merged
+	}
+	return filterEntries(merged, man.Compaction_filter, CompactionInfo{Level: c.output, Bottommost: c.bottommost()})
+}
@@ -0,0 +1,116 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/dillonkmcquade/gostore/internal/pb"
+)
+
+// Drops the keys of tenant "b" and upgrades "v1" values
+func tenantFilter(info CompactionInfo, key []byte, value []byte) (FilterDecision, []byte) {
+	if bytes.HasPrefix(key, []byte("b/")) {
+		return DROP, nil
+	}
+	if bytes.Equal(value, []byte("v1")) {
+		return REPLACE, []byte("v2")
+	}
+	return KEEP, nil
+}
+
+func TestFilterEntries(t *testing.T) {
+	in := make(chan *pb.SSTable_Entry)
+	go func() {
+		defer close(in)
+		in <- &pb.SSTable_Entry{Key: []byte("a/1"), Value: []byte("v1"), Op: pb.Operation_OPERATION_INSERT}
+		in <- &pb.SSTable_Entry{Key: []byte("a/2"), Value: []byte("v3"), Op: pb.Operation_OPERATION_INSERT}
+		in <- &pb.SSTable_Entry{Key: []byte("b/1"), Value: []byte("v1"), Op: pb.Operation_OPERATION_INSERT}
+		in <- &pb.SSTable_Entry{Key: []byte("b/2"), Value: []byte{}, Op: pb.Operation_OPERATION_DELETE}
+	}()
+
+	out := []*pb.SSTable_Entry{}
+	for entry := range filterEntries(in, tenantFilter, CompactionInfo{}) {
+		out = append(out, entry)
+	}
+	if len(out) != 3 {
+		t.Fatalf("Expected 3 entries, found %v", len(out))
+	}
+	if string(out[0].Value) != "v2" {
+		t.Errorf("Expected replaced value v2, found %s", out[0].Value)
+	}
+	if string(out[1].Value) != "v3" {
+		t.Errorf("Expected kept value v3, found %s", out[1].Value)
+	}
+	if string(out[2].Key) != "b/2" || out[2].Op != pb.Operation_OPERATION_DELETE {
+		t.Error("Deletes should never be filtered")
+	}
+}
+
+func TestCompactionBottommost(t *testing.T) {
+	tmp := t.TempDir()
+	l1 := &Level{Number: 1}
+	l2 := &Level{Number: 2}
+	l3 := &Level{Number: 3}
+	input := newEditTable(tmp, 0, 9)
+	l1.Add(input)
+	l2.Add(newEditTable(tmp, 5, 15))
+	l3.Add(newEditTable(tmp, 20, 29))
+	man := newPickerManifest(t, &Level{Number: 0}, l1, l2, l3)
+	version := man.Current()
+	defer version.Unref()
+
+	if !version.tableCompaction(version.Levels[1], input).bottommost() {
+		t.Error("Expected bottommost, the overlapping L2 table is an input and L3 does not overlap")
+	}
+	c := version.tableCompaction(version.Levels[1], input)
+	c.overlaps = nil
+	if c.bottommost() {
+		t.Error("Expected an overlapping table left out of the compaction to make it not bottommost")
+	}
+}
+
+func TestCompactionFilter(t *testing.T) {
+	var mut sync.Mutex
+	infos := []CompactionInfo{}
+	opts := newWorkloadOpts(t, t.TempDir())
+	opts.Level0_file_trigger = 100
+	opts.Compaction_filter = func(info CompactionInfo, key []byte, value []byte) (FilterDecision, []byte) {
+		mut.Lock()
+		infos = append(infos, info)
+		mut.Unlock()
+		return tenantFilter(info, key, value)
+	}
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer man.Close()
+
+	entries := []*pb.SSTable_Entry{}
+	for _, tenant := range []string{"a", "b"} {
+		for i := 0; i < 10; i++ {
+			entries = append(entries, &pb.SSTable_Entry{Key: []byte(fmt.Sprintf("%s/%d", tenant, i)), Value: []byte("v1"), Op: pb.Operation_OPERATION_INSERT})
+		}
+	}
+	if err := man.AddTable(newFlushTable(t, opts, entries), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := man.CompactRange(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if val, err := man.Search([]byte("a/3")); err != nil || string(val) != "v2" {
+		t.Errorf("Expected the replaced value v2, found %q %v", val, err)
+	}
+	if _, err := man.Search([]byte("b/3")); err == nil {
+		t.Error("Expected the dropped tenant to be gone")
+	}
+	if len(infos) == 0 {
+		t.Fatal("Expected the filter to be called")
+	}
+	if info := infos[0]; info.Level != 1 || !info.Bottommost {
+		t.Errorf("Expected a bottommost L0 compaction into L1, found %+v", info)
+	}
+}
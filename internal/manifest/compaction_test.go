@@ -121,7 +121,9 @@ func TestLevel0Compaction(t *testing.T) {
 	man.AddTable(t2, 0)
 
 	t.Run("Level 0", func(t *testing.T) {
-		man.level_0_compact(man.Levels[0])
+		version := man.Current()
+		man.level_0_compact(version.level0Compaction())
+		version.Unref()
 		if len(man.Levels[1].Tables) != 4 {
 			t.Error("Should be 4")
 		}
@@ -137,7 +139,10 @@ func TestLevel0Compaction(t *testing.T) {
 	})
 
 	t.Run("Lower Level compact", func(t *testing.T) {
-		man.lower_level_compact(man.Levels[1])
+		version := man.Current()
+		table := sstable.Oldest(version.Levels[1].Tables)
+		man.lower_level_compact(version.tableCompaction(version.Levels[1], table))
+		version.Unref()
 		if len(man.Levels[2].Tables) != 1 || len(man.Levels[1].Tables) != 3 {
 			t.Errorf("Level 2 should contain 1 table, found %v. Level 1 should contain 3 tables, found %v", len(man.Levels[2].Tables), len(man.Levels[1].Tables))
 		}
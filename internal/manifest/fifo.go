@@ -0,0 +1,78 @@
+package manifest
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// FIFO compaction:
+//
+// Every table stays in level 0 in the order it was flushed and tables are never merged.
+// Once level 0 holds more than Fifo_max_size bytes, or its oldest table is older than
+// Fifo_ttl, the oldest tables are dropped. Suited to logs and metrics that are only
+// kept for a bounded size or time.
+
+// Returns true if table has outlived the FIFO TTL
+func (m *Manifest) expired(table *sstable.SSTable, now time.Time) bool {
+	return m.Fifo_ttl > 0 && now.Sub(table.CreatedOn) > m.Fifo_ttl
+}
+
+// Level 0 is scored by size over Fifo_max_size once over the limit, and at least 1 once
+// its oldest table expired. Other levels are never compacted.
+func (m *Manifest) fifoScore(level *Level) float64 {
+	if level.Number != 0 || len(level.Tables) == 0 {
+		return 0
+	}
+	var score float64
+	if m.Fifo_max_size > 0 && level.Size > m.Fifo_max_size {
+		score = float64(level.Size) / float64(m.Fifo_max_size)
+	}
+	if m.expired(sstable.Oldest(level.Tables), time.Now()) {
+		score = max(score, 1)
+	}
+	return score
+}
+
+// Returns the compaction dropping the oldest level 0 tables until level 0 is within
+// Fifo_max_size and holds no expired table.
+func (m *Manifest) fifoCompaction(version *Version) *compaction {
+	level := version.Levels[0]
+	now := time.Now()
+	size := level.Size
+	dropped := []*sstable.SSTable{}
+	for _, table := range sortTables(level.Tables, func(a, b *sstable.SSTable) int { return 0 }) {
+		if !m.expired(table, now) && (m.Fifo_max_size <= 0 || size <= m.Fifo_max_size) {
+			break
+		}
+		dropped = append(dropped, table)
+		size -= table.Size
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+	c := newCompaction(version, 0, dropped, nil)
+	c.output = 0
+	c.fifo = true
+	return c
+}
+
+// Remove the inputs of c from the manifest, their files are deleted once unreferenced
+func (man *Manifest) fifo_compact(c *compaction) {
+	edit := &VersionEdit{}
+	for _, tbl := range c.inputs {
+		slog.Info("Dropping table", "filename", tbl.Name, "created", tbl.CreatedOn)
+		edit.RemoveTable(tbl, 0)
+	}
+	err := man.LogAndApply(edit)
+	if err != nil {
+		slog.Error("Failed to commit FIFO compaction")
+		panic(err)
+	}
+}
+
+// Interval at which expired tables are looked for when nothing else wakes the scheduler
+func (man *Manifest) fifoCheckInterval() time.Duration {
+	return max(man.Fifo_ttl/10, time.Millisecond)
+}
@@ -0,0 +1,167 @@
+package manifest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+func TestFifoCompaction(t *testing.T) {
+	tmp := t.TempDir()
+	now := time.Now()
+	l0 := &Level{Number: 0}
+	tables := []*sstable.SSTable{}
+	for i := 0; i < 3; i++ {
+		table := newEditTable(tmp, byte(i), byte(i+10))
+		table.CreatedOn = now.Add(time.Duration(i-3) * time.Hour)
+		tables = append(tables, table)
+		l0.Add(table)
+	}
+	man := newPickerManifest(t, l0, &Level{Number: 1})
+	man.Compaction_style = FIFO
+	version := man.Current()
+	defer version.Unref()
+
+	t.Run("Test within limits", func(t *testing.T) {
+		man.Fifo_max_size = 300
+		if score := man.score(version, version.Levels[0]); score != 0 {
+			t.Errorf("Expected no compaction, found score %v", score)
+		}
+		if c := man.fifoCompaction(version); c != nil {
+			t.Errorf("Expected nothing to drop, found %v tables", len(c.inputs))
+		}
+	})
+
+	t.Run("Test oldest dropped over size", func(t *testing.T) {
+		man.Fifo_max_size = 250
+		if score := man.score(version, version.Levels[0]); score != 1.2 {
+			t.Errorf("Expected level 0 scored by size, found %v", score)
+		}
+		c := man.fifoCompaction(version)
+		if c == nil || len(c.inputs) != 1 || c.inputs[0] != tables[0] {
+			t.Fatal("Expected only the oldest table to be dropped")
+		}
+		if !c.fifo || c.output != 0 {
+			t.Error("Expected a FIFO compaction that writes nothing")
+		}
+	})
+
+	t.Run("Test expired dropped", func(t *testing.T) {
+		man.Fifo_max_size = 0
+		man.Fifo_ttl = 90 * time.Minute
+		if score := man.score(version, version.Levels[0]); score != 1 {
+			t.Errorf("Expected an expired table to trigger compaction, found score %v", score)
+		}
+		c := man.fifoCompaction(version)
+		if c == nil || len(c.inputs) != 2 || c.inputs[0] != tables[0] || c.inputs[1] != tables[1] {
+			t.Fatal("Expected the two expired tables to be dropped")
+		}
+	})
+
+	t.Run("Test lower levels never compacted", func(t *testing.T) {
+		if score := man.score(version, version.Levels[1]); score != 0 {
+			t.Errorf("Expected lower levels to score 0, found %v", score)
+		}
+	})
+}
+
+// Returns a level 0 table holding keys [first, first+count)
+func newKeysTable(t *testing.T, opts *Opts, first int, count int) *sstable.SSTable {
+	entries := []*pb.SSTable_Entry{}
+	for key := first; key < first+count; key++ {
+		entries = append(entries, &pb.SSTable_Entry{Key: []byte(fmt.Sprintf("%08d", key)), Value: []byte("value"), Op: pb.Operation_OPERATION_INSERT})
+	}
+	return newFlushTable(t, opts, entries)
+}
+
+func TestFifoManifest(t *testing.T) {
+	t.Run("Test size limit", func(t *testing.T) {
+		// Tables are about the same size, keep the 4 newest
+		opts := newWorkloadOpts(t, t.TempDir())
+		opts.Compaction_style = FIFO
+		opts.Fifo_max_size = newKeysTable(t, newWorkloadOpts(t, t.TempDir()), 0, 100).Size * 9 / 2
+		man, err := New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tables := []*sstable.SSTable{}
+		for i := 0; i < 10; i++ {
+			table := newKeysTable(t, opts, i*100, 100)
+			tables = append(tables, table)
+			if err := man.AddTable(table, 0); err != nil {
+				t.Fatal(err)
+			}
+			waitForIdle(t, man)
+		}
+
+		check := func(t *testing.T, man *Manifest) {
+			version := man.Current()
+			defer version.Unref()
+			if len(version.Levels[0].Tables) != 4 {
+				t.Errorf("Expected 4 tables in level 0, found %v", len(version.Levels[0].Tables))
+			}
+			for _, level := range version.Levels[1:] {
+				if len(level.Tables) != 0 {
+					t.Errorf("Expected nothing merged into level %v", level.Number)
+				}
+			}
+			if _, err := man.Search([]byte(fmt.Sprintf("%08d", 0))); err == nil {
+				t.Error("Expected the keys of dropped tables to be gone")
+			}
+			if _, err := man.Search([]byte(fmt.Sprintf("%08d", 950))); err != nil {
+				t.Errorf("Expected the newest keys to be kept, found %v", err)
+			}
+		}
+		check(t, man)
+		if stats := man.CompactionStats(); stats.BytesWritten != 0 {
+			t.Errorf("FIFO compaction should never write, found %v bytes", stats.BytesWritten)
+		}
+		man.DeleteObsoleteFiles()
+		for _, table := range tables[:6] {
+			if exists(table.Name) {
+				t.Errorf("Expected dropped table %v to be deleted", table.Name)
+			}
+		}
+
+		// Drops are recorded in the manifest
+		if err := man.Close(); err != nil {
+			t.Fatal(err)
+		}
+		man, err = New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer man.Close()
+		check(t, man)
+	})
+
+	t.Run("Test ttl", func(t *testing.T) {
+		opts := newWorkloadOpts(t, t.TempDir())
+		opts.Compaction_style = FIFO
+		opts.Fifo_ttl = 50 * time.Millisecond
+		man, err := New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer man.Close()
+		if err := man.AddTable(newKeysTable(t, opts, 0, 100), 0); err != nil {
+			t.Fatal(err)
+		}
+
+		// Nothing is written after the flush, the table must expire on its own
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			version := man.Current()
+			empty := len(version.Levels[0].Tables) == 0
+			version.Unref()
+			if empty {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Error("Expected the expired table to be dropped")
+	})
+}
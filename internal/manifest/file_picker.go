@@ -0,0 +1,165 @@
+package manifest
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// FilePicker is the data movement policy of a leveled compaction. It chooses which
+// table of a level is merged into the next level.
+//
+// Level 0 tables overlap each other and are always compacted together, so pickers are
+// only consulted for level 1 and below.
+type FilePicker interface {
+	// Returns the tables of level in the order they should be compacted. The first
+	// table that does not conflict with a running compaction is picked.
+	Pick(version *Version, level *Level) []*sstable.SSTable
+}
+
+// PickTracker is implemented by pickers that keep state about past compactions. Pick may
+// return tables that are never compacted, e.g. because they conflict with a running
+// compaction, so that state is only updated through Picked.
+type PickTracker interface {
+	// Called when table is scheduled to be compacted out of level
+	Picked(level int, table *sstable.SSTable)
+}
+
+// Returns a copy of tables sorted by cmp, ties broken by age so that every picker is deterministic
+func sortTables(tables []*sstable.SSTable, cmp func(a, b *sstable.SSTable) int) []*sstable.SSTable {
+	sorted := slices.Clone(tables)
+	slices.SortStableFunc(sorted, func(a, b *sstable.SSTable) int {
+		if c := cmp(a, b); c != 0 {
+			return c
+		}
+		return a.CreatedOn.Compare(b.CreatedOn)
+	})
+	return sorted
+}
+
+// Sum of the sizes of the tables in level that overlap table
+func overlappingBytes(table *sstable.SSTable, level *Level) int64 {
+	var size int64
+	for _, overlap := range sstable.Overlapping(table, level.Tables) {
+		size += overlap.Size
+	}
+	return size
+}
+
+// OldestFirst compacts the table that was created first
+type OldestFirst struct{}
+
+func (OldestFirst) Pick(version *Version, level *Level) []*sstable.SSTable {
+	return sortTables(level.Tables, func(a, b *sstable.SSTable) int { return 0 })
+}
+
+// RoundRobin cycles through the key space of each level, compacting the table after
+// the one compacted last. This spreads compactions evenly across keys.
+type RoundRobin struct {
+	cursors map[int][]byte // Last key of the table last picked on each level
+	mut     sync.Mutex
+}
+
+func NewRoundRobin() *RoundRobin {
+	return &RoundRobin{cursors: make(map[int][]byte)}
+}
+
+func (rr *RoundRobin) Pick(version *Version, level *Level) []*sstable.SSTable {
+	rr.mut.Lock()
+	defer rr.mut.Unlock()
+	if len(level.Tables) == 0 {
+		return nil
+	}
+	// Levels below 0 are sorted, start at the first table past the cursor and wrap around
+	cursor := rr.cursors[level.Number]
+	start, _ := slices.BinarySearchFunc(level.Tables, cursor, func(t *sstable.SSTable, key []byte) int {
+		if slices.Compare(t.First, key) <= 0 {
+			return -1
+		}
+		return 1
+	})
+	if start == len(level.Tables) {
+		start = 0
+	}
+	return append(slices.Clone(level.Tables[start:]), level.Tables[:start]...)
+}
+
+// Picked moves the cursor of level past table
+func (rr *RoundRobin) Picked(level int, table *sstable.SSTable) {
+	rr.mut.Lock()
+	defer rr.mut.Unlock()
+	rr.cursors[level] = table.Last
+}
+
+// LeastOverlappingParent compacts the table that overlaps the fewest bytes in the next
+// level, which minimises the data rewritten by the compaction.
+type LeastOverlappingParent struct{}
+
+func (LeastOverlappingParent) Pick(version *Version, level *Level) []*sstable.SSTable {
+	parent := version.Levels[level.Number+1]
+	return sortTables(level.Tables, func(a, b *sstable.SSTable) int {
+		return cmp.Compare(overlappingBytes(a, parent), overlappingBytes(b, parent))
+	})
+}
+
+// LeastOverlappingGrandparent compacts the table that overlaps the fewest bytes two
+// levels down, so that the outputs are cheap to compact again. Falls back to the
+// parent level when the level has no grandparent.
+type LeastOverlappingGrandparent struct{}
+
+func (LeastOverlappingGrandparent) Pick(version *Version, level *Level) []*sstable.SSTable {
+	if level.Number+2 >= len(version.Levels) {
+		return LeastOverlappingParent{}.Pick(version, level)
+	}
+	grandparent := version.Levels[level.Number+2]
+	return sortTables(level.Tables, func(a, b *sstable.SSTable) int {
+		return cmp.Compare(overlappingBytes(a, grandparent), overlappingBytes(b, grandparent))
+	})
+}
+
+// Coldest compacts the table that was read least recently, keeping hot tables in place
+type Coldest struct{}
+
+func (Coldest) Pick(version *Version, level *Level) []*sstable.SSTable {
+	return sortTables(level.Tables, func(a, b *sstable.SSTable) int {
+		return a.LastRead().Compare(b.LastRead())
+	})
+}
+
+// TombstoneDensity compacts the table with the highest fraction of deletes, reclaiming
+// the space held by deleted keys first.
+type TombstoneDensity struct{}
+
+func (TombstoneDensity) Pick(version *Version, level *Level) []*sstable.SSTable {
+	return sortTables(level.Tables, func(a, b *sstable.SSTable) int {
+		return cmp.Compare(b.TombstoneDensity(), a.TombstoneDensity())
+	})
+}
+
+// TombstoneTTL compacts tables holding deletes older than TTL first, oldest delete
+// first, so that deletes reach the last level within a bounded time. Other tables
+// follow in age order.
+type TombstoneTTL struct {
+	TTL time.Duration
+}
+
+func (p TombstoneTTL) Pick(version *Version, level *Level) []*sstable.SSTable {
+	deadline := time.Now().Add(-p.TTL)
+	expired := func(t *sstable.SSTable) bool {
+		return !t.OldestTombstone.IsZero() && t.OldestTombstone.Before(deadline)
+	}
+	return sortTables(level.Tables, func(a, b *sstable.SSTable) int {
+		switch {
+		case expired(a) && expired(b):
+			return a.OldestTombstone.Compare(b.OldestTombstone)
+		case expired(a):
+			return -1
+		case expired(b):
+			return 1
+		}
+		return 0
+	})
+}
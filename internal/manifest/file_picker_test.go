@@ -0,0 +1,233 @@
+package manifest
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+func TestFilePickers(t *testing.T) {
+	tmp := t.TempDir()
+	now := time.Now()
+	l1 := &Level{Number: 1}
+	l2 := &Level{Number: 2}
+	a := newFileTable(t, tmp, tmp, 0, 9)
+	b := newFileTable(t, tmp, tmp, 10, 19)
+	c := newFileTable(t, tmp, tmp, 20, 29)
+	for i, table := range []*sstable.SSTable{a, b, c} {
+		table.CreatedOn = now.Add(time.Duration(i) * time.Second)
+		l1.Add(table)
+	}
+	big := newEditTable(tmp, 0, 9)
+	big.Size = 1000
+	small := newEditTable(tmp, 20, 29)
+	small.Size = 50
+	l2.Add(big)
+	l2.Add(small)
+	man := newPickerManifest(t, &Level{Number: 0}, l1, l2)
+	version := man.Current()
+	defer version.Unref()
+	level := version.Levels[1]
+
+	expectFirst := func(t *testing.T, picker FilePicker, expected *sstable.SSTable) {
+		picked := picker.Pick(version, level)
+		if len(picked) != 3 {
+			t.Fatalf("Expected every table of the level, found %v", len(picked))
+		}
+		if picked[0] != expected {
+			t.Errorf("Expected %v to be picked first, found %v", expected.First, picked[0].First)
+		}
+	}
+
+	t.Run("Test oldest", func(t *testing.T) {
+		expectFirst(t, OldestFirst{}, a)
+	})
+
+	t.Run("Test round robin", func(t *testing.T) {
+		rr := NewRoundRobin()
+		expectFirst(t, rr, a)
+		// Tables returned but never compacted do not move the cursor
+		expectFirst(t, rr, a)
+		for _, next := range []*sstable.SSTable{b, c, a} {
+			rr.Picked(level.Number, rr.Pick(version, level)[0])
+			expectFirst(t, rr, next)
+		}
+	})
+
+	t.Run("Test least overlapping parent", func(t *testing.T) {
+		picked := LeastOverlappingParent{}.Pick(version, level)
+		if !slices.Equal(picked, []*sstable.SSTable{b, c, a}) {
+			t.Error("Expected tables ordered by bytes overlapped in level 2")
+		}
+	})
+
+	t.Run("Test least overlapping grandparent falls back to parent", func(t *testing.T) {
+		expectFirst(t, LeastOverlappingGrandparent{}, b)
+	})
+
+	t.Run("Test coldest", func(t *testing.T) {
+		for _, table := range []*sstable.SSTable{c, a} {
+			if _, _, err := table.Get([]byte{1}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expectFirst(t, Coldest{}, b)
+	})
+
+	t.Run("Test tombstone density", func(t *testing.T) {
+		a.NumEntries, a.NumTombstones = 10, 1
+		b.NumEntries, b.NumTombstones = 10, 0
+		c.NumEntries, c.NumTombstones = 10, 5
+		expectFirst(t, TombstoneDensity{}, c)
+	})
+
+	t.Run("Test tombstone ttl", func(t *testing.T) {
+		b.OldestTombstone = now.Add(-3 * time.Hour)
+		c.OldestTombstone = now.Add(-2 * time.Hour)
+		a.OldestTombstone = now.Add(-time.Minute)
+		picked := TombstoneTTL{TTL: time.Hour}.Pick(version, level)
+		if !slices.Equal(picked, []*sstable.SSTable{b, c, a}) {
+			t.Error("Expected expired tombstones first, oldest first")
+		}
+	})
+}
+
+// Blocks until no compaction is running and no level needs one
+func waitForIdle(tb testing.TB, man *Manifest) {
+	deadline := time.Now().Add(time.Minute)
+	for time.Now().Before(deadline) {
+		man.schedMut.Lock()
+		idle := len(man.running) == 0
+		man.schedMut.Unlock()
+		version := man.Current()
+		pending := len(man.levelsByScore(version)) > 0
+		version.Unref()
+		if idle && !pending {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	tb.Fatal("Timed out waiting for compactions")
+}
+
+// Writes entries sorted by key to a level 0 table, as a memtable flush would
+func newFlushTable(tb testing.TB, opts *Opts, entries []*pb.SSTable_Entry) *sstable.SSTable {
+	table := sstable.New(&sstable.Opts{
+		BloomOpts: &filter.Opts{Size: 5000},
+		DestDir:   opts.LevelPaths[0],
+		Entries:   entries,
+	})
+	for _, entry := range entries {
+		table.Filter.Add(entry.Key)
+	}
+	table.First = entries[0].Key
+	table.Last = entries[len(entries)-1].Key
+	if _, err := table.Sync(); err != nil {
+		tb.Fatal(err)
+	}
+	return table
+}
+
+// Returns manifest options for a store in dir, creating its directories
+func newWorkloadOpts(tb testing.TB, dir string) *Opts {
+	opts := &Opts{
+		Path: filepath.Join(dir, "manifest.txtpb"),
+		LevelPaths: []string{
+			filepath.Join(dir, "l0"), filepath.Join(dir, "l1"), filepath.Join(dir, "l2"), filepath.Join(dir, "l3"),
+		},
+		Num_levels:          4,
+		Level0_max_size:     16 << 10,
+		SSTable_max_size:    200,
+		BloomPath:           filepath.Join(dir, "filters"),
+		Level0_file_trigger: 4,
+	}
+	for _, dir := range append(opts.LevelPaths, opts.BloomPath) {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return opts
+}
+
+// Flushes the same random workload into a fresh manifest and returns the write
+// amplification: bytes written by flushes and compactions over bytes flushed.
+func writeAmplification(b *testing.B, configure func(*Opts)) float64 {
+	opts := newWorkloadOpts(b, b.TempDir())
+	configure(opts)
+	man, err := New(opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer man.Close()
+
+	rng := rand.New(rand.NewSource(1))
+	var flushed int64
+	for flush := 0; flush < 60; flush++ {
+		keys := make(map[int]struct{})
+		for len(keys) < 500 {
+			keys[rng.Intn(20000)] = struct{}{}
+		}
+		sorted := make([]int, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		slices.Sort(sorted)
+
+		entries := []*pb.SSTable_Entry{}
+		for _, key := range sorted {
+			entry := &pb.SSTable_Entry{Key: []byte(fmt.Sprintf("%08d", key)), Value: []byte("value value value value value"), Op: pb.Operation_OPERATION_INSERT}
+			if rng.Intn(10) == 0 {
+				entry.Op = pb.Operation_OPERATION_DELETE
+				entry.Value = []byte{}
+			}
+			entries = append(entries, entry)
+		}
+		table := newFlushTable(b, opts, entries)
+		size := table.Size
+		flushed += size
+		if err := man.AddTable(table, 0); err != nil {
+			b.Fatal(err)
+		}
+		waitForIdle(b, man)
+	}
+	return float64(flushed+man.CompactionStats().BytesWritten) / float64(flushed)
+}
+
+func BenchmarkWriteAmplification(b *testing.B) {
+	pickers := []struct {
+		name   string
+		picker func() FilePicker
+	}{
+		{"Oldest", func() FilePicker { return OldestFirst{} }},
+		{"RoundRobin", func() FilePicker { return NewRoundRobin() }},
+		{"LeastOverlappingParent", func() FilePicker { return LeastOverlappingParent{} }},
+		{"LeastOverlappingGrandparent", func() FilePicker { return LeastOverlappingGrandparent{} }},
+		{"Coldest", func() FilePicker { return Coldest{} }},
+		{"TombstoneDensity", func() FilePicker { return TombstoneDensity{} }},
+		{"TombstoneTTL", func() FilePicker { return TombstoneTTL{TTL: time.Millisecond} }},
+	}
+	for _, p := range pickers {
+		b.Run(p.name, func(b *testing.B) {
+			var total float64
+			for i := 0; i < b.N; i++ {
+				total += writeAmplification(b, func(opts *Opts) { opts.File_picker = p.picker() })
+			}
+			b.ReportMetric(total/float64(b.N), "write-amp")
+		})
+	}
+	b.Run("Tiered", func(b *testing.B) {
+		var total float64
+		for i := 0; i < b.N; i++ {
+			total += writeAmplification(b, func(opts *Opts) { opts.Compaction_style = TIERED })
+		}
+		b.ReportMetric(total/float64(b.N), "write-amp")
+	})
+}
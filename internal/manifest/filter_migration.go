@@ -0,0 +1,40 @@
+package manifest
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Embeds the filter files of tables written before filters were stored in table files.
+//
+// Each such table is copied to a new file with its filter embedded, and the copies replace
+// the tables in one edit. The old segments and filter files are then collected as
+// obsolete. Tables written since already embed their filter, so only the first start
+// after an upgrade has work to do. A table that cannot be copied keeps its filter file and
+// is retried on the next start.
+func (m *Manifest) migrateFilters() error {
+	edit := &VersionEdit{}
+	for _, level := range m.Levels {
+		for _, table := range level.Tables {
+			if table.FilterMetadata().Name == "" {
+				continue
+			}
+			migrated, err := table.EmbedFilter()
+			if err != nil {
+				slog.Error("Failed to embed table filter", "filename", table.Name, "cause", err)
+				continue
+			}
+			edit.RemoveTable(table, level.Number)
+			edit.AddTable(migrated, level.Number)
+		}
+	}
+	if edit.Empty() {
+		return nil
+	}
+	slog.Info("Embedded filter files into tables", "tables", len(edit.Added))
+	err := m.LogAndApply(edit)
+	if err != nil {
+		return fmt.Errorf("LogAndApply: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// Creates a table the way it was written before filters were embedded: a segment of
+// entries only, with its filter in a file of its own
+func newSidecarTable(t *testing.T, dir string, bloomDir string, first int) *sstable.SSTable {
+	if err := os.MkdirAll(bloomDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	entries := []*pb.SSTable_Entry{}
+	for key := first; key < first+10; key++ {
+		entries = append(entries, &pb.SSTable_Entry{Key: []byte(fmt.Sprintf("%04d", key)), Value: []byte("value"), Op: pb.Operation_OPERATION_INSERT})
+	}
+	table := &sstable.SSTable{
+		Entries:   entries,
+		Name:      filepath.Join(dir, sstable.GenerateUniqueSegmentName(time.Now())),
+		First:     entries[0].Key,
+		Last:      entries[len(entries)-1].Key,
+		CreatedOn: time.Now(),
+	}
+	sidecar := filter.New(&filter.Opts{Size: 1000, Path: bloomDir})
+	for _, entry := range entries {
+		sidecar.Add(entry.Key)
+	}
+	if _, err := table.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sidecar.Save(); err != nil {
+		t.Fatal(err)
+	}
+	table.Filter = filter.Open(sidecar.Metadata())
+	return table
+}
+
+func TestMigrateFilters(t *testing.T) {
+	tmp := t.TempDir()
+	opts := newObsoleteOpts(tmp)
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := []*sstable.SSTable{
+		newSidecarTable(t, opts.LevelPaths[1], opts.BloomPath, 0),
+		newSidecarTable(t, opts.LevelPaths[1], opts.BloomPath, 10),
+	}
+	for _, table := range tables {
+		if err = man.AddTable(table, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	man.Close()
+	// A lost filter file is rebuilt instead of failing the replay
+	if err = os.Remove(tables[1].Filter.Path()); err != nil {
+		t.Fatal(err)
+	}
+
+	man, err = New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := man.Current()
+	migrated := map[string]bool{}
+	for _, table := range version.Levels[1].Tables {
+		if name := table.FilterMetadata().Name; name != "" {
+			t.Errorf("Expected the filter of %v to be embedded, found filter file %v", table.Name, name)
+		}
+		migrated[table.Name] = true
+	}
+	version.Unref()
+	if len(migrated) != 2 {
+		t.Fatalf("Expected 2 tables in level 1, found %v", len(migrated))
+	}
+	for _, table := range tables {
+		if exists(table.Name) || exists(table.Filter.Path()) {
+			t.Errorf("Expected the segment and filter file of %v to be deleted", table.Name)
+		}
+	}
+	for key := 0; key < 20; key++ {
+		if _, err := man.Search([]byte(fmt.Sprintf("%04d", key))); err != nil {
+			t.Errorf("Search %v: %v", key, err)
+		}
+	}
+	if err = man.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Migrated tables are left as they are
+	man, err = New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer man.Close()
+	version = man.Current()
+	defer version.Unref()
+	for _, table := range version.Levels[1].Tables {
+		if !migrated[table.Name] {
+			t.Errorf("Expected %v to be migrated once", table.Name)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// Ingestion:
+//
+// An ingested table is newer than every table in the manifest, so it must not be placed
+// below a table holding any of its keys. It goes to the deepest level such that neither
+// that level nor any level above it overlaps the table, or to L0 if L0 already does.
+// Running compactions count as overlapping the levels they write to, since their outputs
+// are added once they finish.
+
+// IngestTables links tables built outside the manifest, e.g. by an sstable.SSTWriter, into
+// the levels and adds them in a single edit. Each table is given a new run, later tables
+// being newer than earlier ones. The original files and tables are left unchanged.
+func (m *Manifest) IngestTables(tables []*sstable.SSTable) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	// Compactions picked before the edit is applied could write over the ingested keys
+	m.schedMut.Lock()
+	defer m.schedMut.Unlock()
+
+	edit := &VersionEdit{}
+	for _, table := range tables {
+		level := m.ingestLevel(table, edit)
+		location := filepath.Join(m.Levels[level].Path, sstable.GenerateUniqueSegmentName(table.CreatedOn))
+		err := linkOrCopy(table.Name, location)
+		if err != nil {
+			removeAdded(edit)
+			return fmt.Errorf("linkOrCopy: %w", err)
+		}
+		// The tables of the caller keep pointing at the original files
+		edit.AddTable(&sstable.SSTable{
+			Name:            location,
+			Filter:          filter.Open(table.FilterMetadata()),
+			Size:            table.Size,
+			First:           table.First,
+			Last:            table.Last,
+			CreatedOn:       table.CreatedOn,
+			NumEntries:      table.NumEntries,
+			NumTombstones:   table.NumTombstones,
+			OldestTombstone: table.OldestTombstone,
+			Cache:           m.Block_cache,
+		}, level)
+	}
+	err := m.logAndApply(edit)
+	if err != nil {
+		removeAdded(edit)
+		return err
+	}
+	return nil
+}
+
+// Returns the level table is ingested into, given the tables already added to edit.
+// Caller must hold m.mut and m.schedMut.
+func (m *Manifest) ingestLevel(table *sstable.SSTable, edit *VersionEdit) int {
+	if m.Compaction_style == FIFO {
+		return 0
+	}
+	overlaps := func(level int) bool {
+		if slices.ContainsFunc(m.Levels[level].Tables, table.Overlaps) {
+			return true
+		}
+		for _, added := range edit.Added {
+			if added.Level == level && added.Table.Overlaps(table) {
+				return true
+			}
+		}
+		return slices.ContainsFunc(m.running, func(c *compaction) bool {
+			return c.output == level && slices.Compare(c.first, table.Last) <= 0 && slices.Compare(table.First, c.last) <= 0
+		})
+	}
+	for level := range m.Levels {
+		if overlaps(level) {
+			// L0 tables may overlap, the newest is searched first
+			return max(level-1, 0)
+		}
+	}
+	return len(m.Levels) - 1
+}
+
+// Hard links src to dst, copying it if they are on different file systems
+func linkOrCopy(src string, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("os.Open: %w", err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("os.OpenFile: %w", err)
+	}
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
+
+// Removes the files linked for the tables of an edit that was not applied
+func removeAdded(edit *VersionEdit) {
+	for _, change := range edit.Added {
+		os.Remove(change.Table.Name)
+	}
+}
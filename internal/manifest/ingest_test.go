@@ -0,0 +1,137 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// Writes keys [first, first+count) with value to an external table file in dir
+func newExternalTable(t *testing.T, dir string, first int, count int, value string) *sstable.SSTable {
+	w := sstable.NewSSTWriter(filepath.Join(dir, fmt.Sprintf("external_%v_%v.segment", first, value)), nil)
+	for key := first; key < first+count; key++ {
+		if err := w.Put([]byte(fmt.Sprintf("%08d", key)), []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	written, err := w.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := sstable.OpenExternal(written.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+func TestIngestTables(t *testing.T) {
+	opts := newWorkloadOpts(t, t.TempDir())
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for level, first := range map[int]int{0: 400, 1: 200, 2: 0} {
+		if err := man.AddTable(newKeysTable(t, opts, first, 100), level); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	external := t.TempDir()
+	tables := []*sstable.SSTable{
+		newExternalTable(t, external, 1000, 100, "a"), // Overlaps nothing
+		newExternalTable(t, external, 50, 10, "b"),    // Overlaps L2
+		newExternalTable(t, external, 250, 10, "c"),   // Overlaps L1
+		newExternalTable(t, external, 450, 10, "d"),   // Overlaps L0
+		newExternalTable(t, external, 1050, 10, "e"),  // Overlaps the first ingested table
+	}
+	originals := []string{}
+	for _, table := range tables {
+		originals = append(originals, table.Name)
+	}
+	if err := man.IngestTables(tables); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(t *testing.T, man *Manifest) {
+		version := man.Current()
+		defer version.Unref()
+		levels := map[string]int{}
+		runs := map[string]uint64{}
+		for _, level := range version.Levels {
+			for _, table := range level.Tables {
+				levels[string(table.First)] = level.Number
+				runs[string(table.First)] = table.Run
+			}
+		}
+		for first, expected := range map[int]int{1000: 3, 50: 1, 250: 0, 450: 0, 1050: 2} {
+			key := fmt.Sprintf("%08d", first)
+			if level, ok := levels[key]; !ok || level != expected {
+				t.Errorf("Expected the table starting at %v in level %v, found %v", first, expected, level)
+			}
+			if runs[key] <= runs[fmt.Sprintf("%08d", 0)] {
+				t.Errorf("Expected the table starting at %v to be newer than the existing tables", first)
+			}
+		}
+		for key, expected := range map[int]string{55: "b", 0: "value", 255: "c", 455: "d", 1055: "e", 1099: "a"} {
+			value, err := man.Search([]byte(fmt.Sprintf("%08d", key)))
+			if err != nil || string(value) != expected {
+				t.Errorf("Expected %v for key %v, found %s, %v", expected, key, value, err)
+			}
+		}
+	}
+	check(t, man)
+	for i, name := range originals {
+		if !exists(name) {
+			t.Errorf("Expected the external file %v to be left in place", name)
+		}
+		if tables[i].Name != name || tables[i].Run != 0 {
+			t.Errorf("Expected the ingested table %v to be left unchanged, found %v in run %v", name, tables[i].Name, tables[i].Run)
+		}
+	}
+
+	// A failed ingestion leaves the tables of the caller and the levels untouched
+	files := func() int {
+		count := 0
+		for _, dir := range opts.LevelPaths {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			count += len(entries)
+		}
+		return count
+	}
+	before := files()
+	failed := []*sstable.SSTable{newExternalTable(t, external, 2000, 10, "f"), newExternalTable(t, external, 3000, 10, "g")}
+	if err := os.Remove(failed[1].Name); err != nil {
+		t.Fatal(err)
+	}
+	name := failed[0].Name
+	if err := man.IngestTables(failed); err == nil {
+		t.Fatal("Expected an error ingesting a missing file")
+	}
+	if failed[0].Name != name || !exists(name) {
+		t.Errorf("Expected the table %v to keep its file, found %v", name, failed[0].Name)
+	}
+	if after := files(); after != before {
+		t.Errorf("Expected the linked files to be removed, found %v files instead of %v", after, before)
+	}
+	if _, err := man.Search([]byte(fmt.Sprintf("%08d", 2005))); err == nil {
+		t.Error("Expected nothing ingested by a failed ingestion")
+	}
+
+	// The ingestion is recorded in the manifest
+	if err := man.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	check(t, reopened)
+}
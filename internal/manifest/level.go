@@ -1,6 +1,7 @@
 package manifest
 
 import (
+	"cmp"
 	"slices"
 	"sort"
 
@@ -14,20 +15,27 @@ type Level struct {
 	Number  int
 	Size    int64
 	MaxSize int64
+	Tiered  bool                 // Level holds several overlapping sorted runs instead of one
+	runs    [][]*sstable.SSTable // Runs of a tiered level, cached by immutable versions
 }
 
 // Binary search the current level for table that has range overlapping key
 func (l *Level) BinarySearch(key []byte) (int, bool) {
+	return findTable(l.Tables, key)
+}
+
+// Binary search tables sorted by key for the table that has range overlapping key
+func findTable(tables []*sstable.SSTable, key []byte) (int, bool) {
 	low := 0
-	high := len(l.Tables) - 1
+	high := len(tables) - 1
 
 	for low <= high {
 		mid := low + (high-low)/2
-		if slices.Compare(l.Tables[mid].First, key) <= 0 && slices.Compare(l.Tables[mid].Last, key) >= 0 {
+		if slices.Compare(tables[mid].First, key) <= 0 && slices.Compare(tables[mid].Last, key) >= 0 {
 			return mid, true
 		}
 
-		if slices.Compare(key, l.Tables[mid].First) < 0 {
+		if slices.Compare(key, tables[mid].First) < 0 {
 			high = mid - 1
 		} else {
 			low = mid + 1
@@ -37,7 +45,9 @@ func (l *Level) BinarySearch(key []byte) (int, bool) {
 }
 
 func (l *Level) Add(table *sstable.SSTable) {
-	if len(l.Tables) == 0 {
+	// Level 0 tables overlap, keep them in flush order so the newest table is last.
+	// Tiered levels are ordered by Runs()
+	if len(l.Tables) == 0 || l.Number == 0 || l.Tiered {
 		l.Tables = append(l.Tables, table)
 		l.Size += table.Size
 		return
@@ -54,15 +64,11 @@ func (l *Level) Add(table *sstable.SSTable) {
 func (l *Level) Remove(table *sstable.SSTable) {
 	assert.True(len(l.Tables) > 0, "Expected table len > 0, found %v", len(l.Tables))
 
-	index := sort.Search(len(l.Tables), func(i int) bool {
-		case1 := slices.Compare(l.Tables[i].First, table.First) >= 0
-		case2 := slices.Compare(l.Tables[i].Last, table.Last) >= 0
-		return case1 && case2
-	})
-	assert.True(index < len(l.Tables), "Index %v out of range %v", index, len(l.Tables))
-	if index < len(l.Tables) && l.Tables[index] == table {
+	// Tables in level 0 overlap, so their order cannot be binary searched
+	index := slices.IndexFunc(l.Tables, func(t *sstable.SSTable) bool { return t.Name == table.Name })
+	if index >= 0 {
+		l.Size -= l.Tables[index].Size
 		l.Tables = remove(l.Tables, index)
-		l.Size -= table.Size
 	}
 }
 
@@ -71,3 +77,22 @@ func (l *Level) Clear() {
 	l.Tables = []*sstable.SSTable{}
 	l.Size = 0
 }
+
+// Returns the sorted runs of the level, newest first. Tables within a run are sorted by key.
+func (l *Level) Runs() [][]*sstable.SSTable {
+	tables := slices.Clone(l.Tables)
+	slices.SortStableFunc(tables, func(a, b *sstable.SSTable) int {
+		if a.Run != b.Run {
+			return cmp.Compare(b.Run, a.Run)
+		}
+		return slices.Compare(a.First, b.First)
+	})
+	runs := [][]*sstable.SSTable{}
+	for i, table := range tables {
+		if i == 0 || table.Run != tables[i-1].Run {
+			runs = append(runs, []*sstable.SSTable{})
+		}
+		runs[len(runs)-1] = append(runs[len(runs)-1], table)
+	}
+	return runs
+}
@@ -0,0 +1,130 @@
+package manifest
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Default ratio between the target sizes of adjacent levels
+const defaultLevelSizeMultiplier = 10
+
+// Returns the target size of level n when targets do not follow the data:
+// Level0_max_size * Level_size_multiplier^n
+func (m *Manifest) staticTarget(n int) int64 {
+	target := m.Level0_max_size
+	for i := 0; i < n; i++ {
+		target *= int64(m.Level_size_multiplier)
+	}
+	return target
+}
+
+// Returns the directory of level n. Levels without a configured path are kept beside the
+// last configured one, in a directory named after the level.
+func (m *Manifest) levelPath(n int) string {
+	if n < len(m.levelPaths) {
+		return m.levelPaths[n]
+	}
+	parent := filepath.Dir(m.Path)
+	if len(m.levelPaths) > 0 {
+		parent = filepath.Dir(m.levelPaths[len(m.levelPaths)-1])
+	}
+	return filepath.Join(parent, fmt.Sprintf("l%d", n))
+}
+
+// Returns level n, creating its directory if needed
+func (m *Manifest) newLevel(n int) (*Level, error) {
+	path := m.levelPath(n)
+	err := os.MkdirAll(path, 0750)
+	if err != nil {
+		return nil, fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	return &Level{
+		Number:  n,
+		MaxSize: m.staticTarget(n),
+		Path:    path,
+		Tiered:  m.Compaction_style == TIERED,
+	}, nil
+}
+
+// Adds levels until level n exists. Fails if n is not below Max_levels.
+func (m *Manifest) ensureLevel(n int) error {
+	if n >= max(m.Max_levels, len(m.Levels)) {
+		return fmt.Errorf("level %v exceeds the maximum of %v levels", n, m.Max_levels)
+	}
+	for len(m.Levels) <= n {
+		level, err := m.newLevel(len(m.Levels))
+		if err != nil {
+			return err
+		}
+		m.Levels = append(m.Levels, level)
+	}
+	return nil
+}
+
+// Adds a level below the last one while the last level holds more than its static target
+// and Max_levels allows it, then updates the target size of every level.
+//
+// Caller must hold m.mut
+func (m *Manifest) updateLevels() {
+	for m.Compaction_style != FIFO && len(m.Levels) > 0 && len(m.Levels) < m.Max_levels {
+		last := m.Levels[len(m.Levels)-1]
+		target := m.staticTarget(last.Number)
+		if target <= 0 || last.Size <= target {
+			break
+		}
+		err := m.ensureLevel(len(m.Levels))
+		if err != nil {
+			slog.Error("Failed to add level", "level", len(m.Levels), "cause", err)
+			break
+		}
+		slog.Info("Added level", "level", len(m.Levels)-1, "last level size", last.Size)
+	}
+	m.baseLevel = 1
+	if m.Dynamic_level_size {
+		m.baseLevel = m.dynamicTargets()
+	}
+}
+
+// Sets the target size of each level from the size of the largest level below L0, dividing
+// by Level_size_multiplier going up until a target would fall under Level0_max_size.
+// Levels above that have no target and are drained into the levels below.
+//
+// Returns the highest level with a target, the base level that L0 compacts into.
+// Caller must hold m.mut
+func (m *Manifest) dynamicTargets() int {
+	last := len(m.Levels) - 1
+	if last < 1 {
+		return 1
+	}
+	var bottom int64
+	for _, level := range m.Levels[1:] {
+		bottom = max(bottom, level.Size)
+	}
+	target := max(bottom, m.Level0_max_size)
+	base := last
+	m.Levels[last].MaxSize = target
+	for n := last - 1; n >= 1; n-- {
+		target /= int64(m.Level_size_multiplier)
+		if target < m.Level0_max_size {
+			target = 0
+		} else {
+			base = n
+		}
+		m.Levels[n].MaxSize = target
+	}
+	return base
+}
+
+// Returns the level that L0 compacts into. A level above the base level that still holds
+// tables is newer than the levels below it, so L0 compacts into it instead of skipping it.
+func (v *Version) baseLevel() int {
+	base := min(max(v.base, 1), len(v.Levels)-1)
+	for n := 1; n < base; n++ {
+		if len(v.Levels[n].Tables) > 0 {
+			return n
+		}
+	}
+	return base
+}
@@ -0,0 +1,134 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDynamicTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		bottom  int64
+		targets []int64
+		base    int
+	}{
+		{name: "Test empty", bottom: 0, targets: []int64{0, 0, 100}, base: 3},
+		{name: "Test under base size", bottom: 500, targets: []int64{0, 0, 500}, base: 3},
+		{name: "Test two levels", bottom: 5000, targets: []int64{0, 500, 5000}, base: 2},
+		{name: "Test every level", bottom: 20000, targets: []int64{200, 2000, 20000}, base: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manifest{Level0_max_size: 100, Level_size_multiplier: 10, Dynamic_level_size: true}
+			for n := 0; n < 4; n++ {
+				m.Levels = append(m.Levels, &Level{Number: n})
+			}
+			m.Levels[3].Size = tt.bottom
+			if base := m.dynamicTargets(); base != tt.base {
+				t.Errorf("Expected base level %v, found %v", tt.base, base)
+			}
+			for i, target := range tt.targets {
+				if m.Levels[i+1].MaxSize != target {
+					t.Errorf("Expected level %v target %v, found %v", i+1, target, m.Levels[i+1].MaxSize)
+				}
+			}
+		})
+	}
+}
+
+func TestBaseLevel(t *testing.T) {
+	tmp := t.TempDir()
+	levels := []*Level{{Number: 0}, {Number: 1}, {Number: 2}, {Number: 3}}
+	v := &Version{Levels: levels, base: 3}
+	if base := v.baseLevel(); base != 3 {
+		t.Errorf("Expected base level 3, found %v", base)
+	}
+	levels[2].Add(newEditTable(tmp, 0, 10))
+	if base := v.baseLevel(); base != 2 {
+		t.Errorf("L0 must not skip a level holding tables, expected base level 2 found %v", base)
+	}
+	if base := (&Version{Levels: levels}).baseLevel(); base != 1 {
+		t.Errorf("Expected base level 1 by default, found %v", base)
+	}
+}
+
+func TestDynamicLevelSize(t *testing.T) {
+	opts := newWorkloadOpts(t, t.TempDir())
+	opts.Dynamic_level_size = true
+	opts.Level0_max_size = 1 << 30
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer man.Close()
+
+	for i := 0; i < opts.Level0_file_trigger; i++ {
+		if err := man.AddTable(newKeysTable(t, opts, i*100, 100), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForIdle(t, man)
+
+	version := man.Current()
+	defer version.Unref()
+	for _, level := range version.Levels[:3] {
+		if len(level.Tables) > 0 {
+			t.Errorf("Expected level %v to be empty, found %v tables", level.Number, len(level.Tables))
+		}
+	}
+	if len(version.Levels[3].Tables) == 0 {
+		t.Error("Expected level 0 to compact into the last level")
+	}
+}
+
+func TestLevelGrowth(t *testing.T) {
+	dir := t.TempDir()
+	opts := newWorkloadOpts(t, dir)
+	opts.LevelPaths = opts.LevelPaths[:2]
+	opts.Num_levels = 2
+	opts.Max_levels = 4
+	opts.Level0_max_size = 1
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := man.AddTable(newKeysTable(t, opts, 0, 100), 1); err != nil {
+		t.Fatal(err)
+	}
+	waitForIdle(t, man)
+
+	// Every level outgrows its target, so the table is moved down to the last level allowed
+	version := man.Current()
+	if len(version.Levels) != 4 {
+		t.Fatalf("Expected 4 levels, found %v", len(version.Levels))
+	}
+	if len(version.Levels[3].Tables) != 1 {
+		t.Errorf("Expected the table in level 3, found %v tables", len(version.Levels[3].Tables))
+	}
+	for _, n := range []int{2, 3} {
+		path := filepath.Join(dir, fmt.Sprintf("l%d", n))
+		if version.Levels[n].Path != path {
+			t.Errorf("Expected level %v in %v, found %v", n, path, version.Levels[n].Path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+	}
+	version.Unref()
+	if err := man.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	man, err = New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer man.Close()
+	version = man.Current()
+	defer version.Unref()
+	if len(version.Levels) != 4 || len(version.Levels[3].Tables) != 1 {
+		t.Errorf("Expected the added levels to be restored on reopen, found %v levels", len(version.Levels))
+	}
+}
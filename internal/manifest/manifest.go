@@ -5,13 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"math"
 	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/dillonkmcquade/gostore/internal/cache"
+	"github.com/dillonkmcquade/gostore/internal/filter"
 	"github.com/dillonkmcquade/gostore/internal/ordered"
 	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/ratelimit"
 	"github.com/dillonkmcquade/gostore/internal/sstable"
 	"github.com/dillonkmcquade/gostore/internal/wal"
 	"google.golang.org/protobuf/proto"
@@ -23,16 +27,33 @@ const (
 	ADDTABLE ManifestOp = iota
 	REMOVETABLE
 	CLEARTABLE
+	VERSIONEDIT ManifestOp = 4 // Matches pb.ManifestEntry_OP_VERSIONEDIT
 )
 
 type ManifestEntry struct {
 	Op    ManifestOp
 	Level int
 	Table *pb.SSTable
+	Edit  *pb.VersionEdit // Set when Op is VERSIONEDIT
 }
 
+// Apply the entry to a slice of levels
 func (entry *ManifestEntry) Apply(c interface{}) error {
-	level := c.(*Level)
+	levels := c.([]*Level)
+	if entry.Op == VERSIONEDIT {
+		edit, err := VersionEditFromProto(entry.Edit)
+		if err != nil {
+			return &wal.LogApplyErr{Cause: err}
+		}
+		if err = edit.applyTo(levels); err != nil {
+			return &wal.LogApplyErr{Cause: err}
+		}
+		return nil
+	}
+	if entry.Level >= len(levels) {
+		return &wal.LogApplyErr{Cause: fmt.Errorf("level %v does not exist", entry.Level)}
+	}
+	level := levels[entry.Level]
 	switch entry.Op {
 	case ADDTABLE:
 		table, err := sstable.FromProto(entry.Table)
@@ -52,11 +73,27 @@ func (entry *ManifestEntry) Apply(c interface{}) error {
 	return nil
 }
 
+// Returns the highest level entry refers to
+func (entry *ManifestEntry) highestLevel() int {
+	if entry.Op != VERSIONEDIT {
+		return entry.Level
+	}
+	highest := 0
+	for _, change := range entry.Edit.GetAdded() {
+		highest = max(highest, int(change.GetLevel()))
+	}
+	for _, change := range entry.Edit.GetRemoved() {
+		highest = max(highest, int(change.GetLevel()))
+	}
+	return highest
+}
+
 func FromProto(p *pb.ManifestEntry) *ManifestEntry {
 	return &ManifestEntry{
 		Op:    ManifestOp(p.GetOp()),
 		Level: int(p.GetLevel()),
 		Table: p.GetTable(),
+		Edit:  p.GetEdit(),
 	}
 }
 
@@ -65,6 +102,7 @@ func (entry *ManifestEntry) MarshalProto() proto.Message {
 		Op:    pb.ManifestEntry_Op(entry.Op),
 		Level: int32(entry.Level),
 		Table: entry.Table,
+		Edit:  entry.Edit,
 	}
 	if entry.Table == nil {
 		e.Table = &pb.SSTable{}
@@ -73,71 +111,285 @@ func (entry *ManifestEntry) MarshalProto() proto.Message {
 }
 
 type Manifest struct {
-	Levels            []*Level                 // in-memory representation of levels
-	wal               *wal.WAL[*ManifestEntry] // Manifest log
-	Path              string                   // path to manifest
-	SSTable_max_size  int                      // Max size to use when splitting tables
-	BloomPath         string                   // Path to filters directory
-	waitForCompaction sync.WaitGroup           // finish compaction before exiting
-	compactionTicker  *time.Ticker             // Check if levels need compaction on an interval
-	mut               sync.RWMutex
-	done              chan bool
+	Levels                []*Level                // Working level layout, guarded by mut. Readers use Current()
+	current               atomic.Pointer[Version] // Latest installed version
+	versions              map[*Version]struct{}   // Installed versions that are still referenced
+	versionsMut           sync.Mutex
+	versionReleased       chan struct{}            // Signalled when a version is released
+	wal                   *wal.WAL[*ManifestEntry] // Manifest log
+	Path                  string                   // path to the live manifest, named by the CURRENT file
+	Max_manifest_size     int64                    // Roll over to a new manifest once the log exceeds this many bytes
+	snapshotSize          int64                    // Size of the snapshot at the start of the live manifest
+	SSTable_max_size      int                      // Max size to use when splitting tables
+	Level0_max_size       int64                    // Target size of level 0, the smallest level target
+	Level_size_multiplier int                      // Ratio between the target sizes of adjacent levels
+	Dynamic_level_size    bool                     // Level targets follow the size of the last level
+	Max_levels            int                      // Levels are added as data grows, up to this many
+	levelPaths            []string                 // Configured level directories
+	baseLevel             int                      // Level that L0 compacts into, guarded by mut
+	BloomPath             string                   // Directory of the filter files of tables written before filters were embedded
+	Bits_per_key          []int                    // Bloom filter bits per key of each level's tables
+	Filter_types          []filter.Type            // Filter implementation of each level's tables
+	Range_bits_per_prefix int                      // Bits per prefix of the range filter of each table, 0 for none
+	obsolete              []string                 // Files of removed tables that have not been deleted yet
+	Level0_file_trigger   int                      // Number of level 0 tables that triggers a compaction
+	Max_compactions       int                      // Number of compactions that may run in parallel
+	Max_subcompactions    int                      // Number of key ranges a compaction is merged in, in parallel
+	Subcompaction_size    int64                    // Bytes of input each key range is given at least
+	File_picker           FilePicker               // Chooses which table of a level is compacted
+	Compaction_filter     CompactionFilter         // Called for each entry rewritten by a compaction, may be nil
+	Rate_limiter          *ratelimit.RateLimiter   // Throttles compaction reads and writes, may be nil
+	Block_cache           *cache.Cache             // Given to every table of an installed version, may be nil
+	Block_size            int                      // Target bytes of the data blocks of compacted tables
+	Index_partition_size  int                      // Target bytes of the index partitions of compacted tables
+	Compaction_style      CompactionStyle          // Leveled, tiered or FIFO layout
+	Tier_run_trigger      int                      // Tiered: number of runs in a level that triggers a compaction
+	Tier_size_ratio       int                      // Tiered: size ratio in percent under which runs are merged together
+	Tier_min_merge_width  int                      // Tiered: minimum number of runs merged in place
+	Fifo_max_size         int64                    // FIFO: drop the oldest tables once level 0 exceeds this many bytes
+	Fifo_ttl              time.Duration            // FIFO: drop tables older than this
+	nextRun               uint64                   // Last run assigned to a table, guarded by mut
+	running               []*compaction            // Compactions handed to a worker, guarded by schedMut
+	stats                 compactionStats          // Cumulative compaction counters
+	schedMut              sync.Mutex
+	compactionDone        *sync.Cond     // Broadcast on schedMut when a compaction ends
+	wakeCompaction        chan struct{}  // Signalled when a version is installed or a compaction ends
+	waitForCompaction     sync.WaitGroup // finish compaction before exiting
+	stopped               chan struct{}  // Closed once the compaction scheduler exits
+	mut                   sync.RWMutex
+	done                  chan bool
+
+	Properties_collectors []sstable.TablePropertiesCollectorFactory // Add custom properties to compacted tables
 }
 
+// CompactionStyle selects how tables are organised into levels
+type CompactionStyle int
+
+const (
+	DEFAULT_STYLE CompactionStyle = iota // Unset: the style recorded by a checkpoint, LEVELED otherwise
+	LEVELED                              // Each level below 0 is a single sorted run
+	TIERED                               // Each level holds several sorted runs that are merged when similar in size
+	FIFO                                 // Every table stays in level 0 until dropped by size or age, nothing is merged
+)
+
 type Opts struct {
-	Path             string   // Path to manifest
-	LevelPaths       []string // Path to each level directory
-	Num_levels       int      // Number of compaction levels
-	Level0_max_size  int64    // Max size of level 0 in bytes
-	SSTable_max_size int
-	BloomPath        string
+	Path                  string   // Path to the initial manifest. Later manifests are created in the same directory
+	LevelPaths            []string // Path to each level directory
+	Num_levels            int      // Number of compaction levels. Defaults to len(LevelPaths). Levels without a path are created beside the last one
+	Level0_max_size       int64    // Max size of level 0 in bytes
+	Level_size_multiplier int      // Ratio between the target sizes of adjacent levels. Defaults to 10
+	Dynamic_level_size    bool     // Derive level targets from the size of the last level instead of Level0_max_size, so that L0 compacts into the highest level the data fills
+	Max_levels            int      // Levels are added below the last one as it outgrows its target, up to this many. Defaults to Num_levels
+	SSTable_max_size      int
+	BloomPath             string                 // Directory of the filter files of tables written before filters were embedded in table files. They are migrated on startup
+	Bits_per_key          []int                  // Bloom filter bits per key of the tables compacted into each level. The last value applies to the levels below it. Defaults to 10
+	Filter_types          []filter.Type          // Filter implementation of the tables compacted into each level, e.g. XOR for the last level. The last value applies to the levels below it. Defaults to BLOOM
+	Range_bits_per_prefix int                    // Write a range filter with this many bits per prefix into every compacted table, so that range scans skip tables holding no keys in their interval. 0 disables range filters
+	Max_manifest_size     int64                  // Roll over to a new manifest once the log exceeds this many bytes. 0 disables rollover
+	Level0_file_trigger   int                    // Number of level 0 tables that triggers a compaction. Defaults to 4
+	Max_compactions       int                    // Number of compactions that may run in parallel. Defaults to 1
+	Max_subcompactions    int                    // Number of key ranges a compaction is split into and merged in parallel. Defaults to 1
+	Subcompaction_size    int64                  // Bytes of input each key range is given at least, smaller compactions are not split. Defaults to 8MB
+	File_picker           FilePicker             // Chooses which table of a level is compacted. Defaults to OldestFirst
+	Compaction_filter     CompactionFilter       // Called for each entry rewritten by a compaction to keep, drop or replace it. Optional
+	Rate_limiter          *ratelimit.RateLimiter // Throttles compaction reads and writes. Optional, may be shared with flushes
+	Block_cache           *cache.Cache           // Caches the index and filter partitions and data blocks read by lookups. Optional
+	Block_size            int                    // Target bytes of the data blocks of compacted tables, the unit a lookup reads. Defaults to 4KB
+	Index_partition_size  int                    // Target bytes of an index partition of compacted tables. Tables with several partitions also partition their filter. Defaults to 4KB
+	Compaction_style      CompactionStyle        // Leveled, tiered or FIFO layout. Defaults to the style of the store a checkpoint was taken from, or LEVELED. A different style fails to open a checkpoint
+	Tier_run_trigger      int                    // Tiered: number of runs in a level that triggers a compaction. Defaults to 4
+	Tier_size_ratio       int                    // Tiered: runs within this percentage of the accumulated size of newer runs are merged together. Defaults to 20
+	Tier_min_merge_width  int                    // Tiered: minimum number of runs merged in place. Defaults to 2
+	Fifo_max_size         int64                  // FIFO: drop the oldest tables once level 0 exceeds this many bytes. 0 disables the limit
+	Fifo_ttl              time.Duration          // FIFO: drop tables older than this. 0 disables the limit
+
+	Properties_collectors []sstable.TablePropertiesCollectorFactory // Add custom properties to the properties block of every compacted table. Optional
 }
 
 // Create new manifest
 func New(opts *Opts) (*Manifest, error) {
 	var manifest *Manifest
-	wal, err := wal.New[*ManifestEntry](opts.Path, 1)
+	path, err := resolveCurrent(opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("resolveCurrent: %w", err)
+	}
+	// Checkpoints record the layout of the store they were taken from
+	recorded, ok, err := readLayout(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("readLayout: %w", err)
+	}
+	if ok && opts.Compaction_style != DEFAULT_STYLE && opts.Compaction_style != recorded.style {
+		return nil, fmt.Errorf("compaction style %v does not match the style %v of the checkpoint", opts.Compaction_style, recorded.style)
+	}
+	wal, err := wal.New[*ManifestEntry](path, 1)
 	if err != nil {
 		return nil, err
 	}
 	manifest = &Manifest{
-		Path:             opts.Path,
-		wal:              wal,
-		Levels:           make([]*Level, opts.Num_levels),
-		SSTable_max_size: opts.SSTable_max_size,
-		BloomPath:        opts.BloomPath,
-		compactionTicker: time.NewTicker(2 * time.Second),
-		done:             make(chan bool, 1),
-	}
-	for levelNumber := 0; levelNumber < opts.Num_levels; levelNumber++ {
-		multiplier := math.Pow(10, float64(levelNumber))
-		manifest.Levels[levelNumber] = &Level{
-			Number:  levelNumber,
-			Size:    0,
-			MaxSize: opts.Level0_max_size * int64(multiplier),
-			Path:    opts.LevelPaths[levelNumber],
-		}
+		Path:                  path,
+		Max_manifest_size:     opts.Max_manifest_size,
+		wal:                   wal,
+		SSTable_max_size:      opts.SSTable_max_size,
+		Level0_max_size:       opts.Level0_max_size,
+		Level_size_multiplier: opts.Level_size_multiplier,
+		Dynamic_level_size:    opts.Dynamic_level_size,
+		Max_levels:            opts.Max_levels,
+		levelPaths:            opts.LevelPaths,
+		BloomPath:             opts.BloomPath,
+		Bits_per_key:          opts.Bits_per_key,
+		Filter_types:          opts.Filter_types,
+		Range_bits_per_prefix: opts.Range_bits_per_prefix,
+		Level0_file_trigger:   opts.Level0_file_trigger,
+		Max_compactions:       opts.Max_compactions,
+		Max_subcompactions:    opts.Max_subcompactions,
+		Subcompaction_size:    opts.Subcompaction_size,
+		File_picker:           opts.File_picker,
+		Compaction_filter:     opts.Compaction_filter,
+		Rate_limiter:          opts.Rate_limiter,
+		Block_cache:           opts.Block_cache,
+		Block_size:            opts.Block_size,
+		Index_partition_size:  opts.Index_partition_size,
+		Compaction_style:      opts.Compaction_style,
+		Tier_run_trigger:      opts.Tier_run_trigger,
+		Tier_size_ratio:       opts.Tier_size_ratio,
+		Tier_min_merge_width:  opts.Tier_min_merge_width,
+		Fifo_max_size:         opts.Fifo_max_size,
+		Fifo_ttl:              opts.Fifo_ttl,
+		wakeCompaction:        make(chan struct{}, 1),
+		stopped:               make(chan struct{}),
+		done:                  make(chan bool, 1),
+		versions:              make(map[*Version]struct{}),
+		versionReleased:       make(chan struct{}, 1),
+
+		Properties_collectors: opts.Properties_collectors,
+	}
+	manifest.compactionDone = sync.NewCond(&manifest.schedMut)
+	if manifest.Level0_file_trigger <= 0 {
+		manifest.Level0_file_trigger = defaultLevel0FileTrigger
+	}
+	if manifest.Subcompaction_size <= 0 {
+		manifest.Subcompaction_size = defaultSubcompactionSize
+	}
+	if manifest.Max_compactions <= 0 {
+		manifest.Max_compactions = 1
+	}
+	if manifest.File_picker == nil {
+		manifest.File_picker = OldestFirst{}
+	}
+	if manifest.Tier_run_trigger <= 0 {
+		manifest.Tier_run_trigger = defaultTierRunTrigger
+	}
+	if manifest.Tier_size_ratio <= 0 {
+		manifest.Tier_size_ratio = defaultTierSizeRatio
+	}
+	if manifest.Tier_min_merge_width < 2 {
+		manifest.Tier_min_merge_width = 2
+	}
+	if manifest.Level_size_multiplier < 2 {
+		manifest.Level_size_multiplier = defaultLevelSizeMultiplier
+	}
+	numLevels := opts.Num_levels
+	if numLevels <= 0 {
+		numLevels = len(opts.LevelPaths)
+	}
+	if ok {
+		manifest.Compaction_style = recorded.style
+		numLevels = max(numLevels, recorded.numLevels)
+	}
+	if manifest.Compaction_style == DEFAULT_STYLE {
+		manifest.Compaction_style = LEVELED
+	}
+	manifest.Max_levels = max(manifest.Max_levels, numLevels)
+	err = manifest.ensureLevel(numLevels - 1)
+	if err != nil {
+		return nil, fmt.Errorf("manifest.ensureLevel: %w", err)
 	}
 	err = manifest.Replay()
 	if err != nil {
 		return nil, fmt.Errorf("manifest.Replay: %w", err)
 	}
+	if manifest.assignMissingRuns() {
+		// Persist the assigned runs so that later edits agree with them
+		err = manifest.rollover()
+		if err != nil {
+			return nil, fmt.Errorf("manifest.rollover: %w", err)
+		}
+	}
+	err = manifest.sweepOrphans(opts)
+	if err != nil {
+		return nil, fmt.Errorf("manifest.sweepOrphans: %w", err)
+	}
+	manifest.installVersion()
+	err = manifest.migrateFilters()
+	if err != nil {
+		return nil, fmt.Errorf("manifest.migrateFilters: %w", err)
+	}
+	err = manifest.maybeRollover()
+	if err != nil {
+		return nil, fmt.Errorf("manifest.maybeRollover: %w", err)
+	}
 	go manifest.Compact()
 	return manifest, nil
 }
 
+// Returns the Bloom filter bits per key of tables written to level
+func (m *Manifest) bitsPerKey(level int) int {
+	if len(m.Bits_per_key) == 0 {
+		return filter.DefaultBitsPerKey
+	}
+	return m.Bits_per_key[min(level, len(m.Bits_per_key)-1)]
+}
+
+// Returns the filter implementation of tables written to level
+func (m *Manifest) filterType(level int) filter.Type {
+	if len(m.Filter_types) == 0 {
+		return filter.BLOOM
+	}
+	return m.Filter_types[min(level, len(m.Filter_types)-1)]
+}
+
+// Returns the options of the range filters of compacted tables, nil if they have none
+func (m *Manifest) rangeOpts() *filter.RangeOpts {
+	if m.Range_bits_per_prefix <= 0 {
+		return nil
+	}
+	return &filter.RangeOpts{Bits_per_prefix: m.Range_bits_per_prefix}
+}
+
 var ErrNotFound = errors.New("not found")
 
+// GetPropertiesOfAllTables reads the properties block of every table in the current
+// version, keyed by table file name
+func (m *Manifest) GetPropertiesOfAllTables() (map[string]*sstable.Properties, error) {
+	version := m.Current()
+	defer version.Unref()
+
+	props := map[string]*sstable.Properties{}
+	for _, level := range version.Levels {
+		for _, table := range level.Tables {
+			p, err := table.ReadProperties()
+			if err != nil {
+				return nil, fmt.Errorf("table.ReadProperties: %w", err)
+			}
+			props[table.Name] = p
+		}
+	}
+	return props, nil
+}
+
 func (m *Manifest) Search(key []byte) ([]byte, error) {
 	var errs []error
 
-	if v, err := m.searchL0(key); err != nil {
+	version := m.Current()
+	defer version.Unref()
+
+	if v, err := version.searchL0(key); err != nil {
 		errs = append(errs, fmt.Errorf("level 0 search error: %w", err))
 	} else {
 		return v, nil
 	}
 
-	if v, err := m.searchLowerLevels(key); err != nil {
+	if v, err := version.searchLowerLevels(key); err != nil {
 		errs = append(errs, fmt.Errorf("lower level search error: %w", err))
 	} else {
 		return v, nil
@@ -145,51 +397,49 @@ func (m *Manifest) Search(key []byte) ([]byte, error) {
 	return []byte{}, errors.Join(errs...)
 }
 
-func (m *Manifest) searchL0(key []byte) ([]byte, error) {
-	m.mut.Lock()
-	defer m.mut.Unlock()
-
-	level0 := m.Levels[0]
+func (v *Version) searchL0(key []byte) ([]byte, error) {
+	level0 := v.Levels[0]
+	if level0.Tiered {
+		return searchRuns(level0, key)
+	}
 	for i := len(level0.Tables) - 1; i >= 0; i-- {
 		tbl := level0.Tables[i]
 
-		if tbl.Filter.Has(key) {
-			err := tbl.Open()
+		if tbl.MayContain(key) {
+			val, found, err := tbl.Get(key)
 			if err != nil {
-				slog.Error("Read: error opening table", "filename", tbl.Name)
+				slog.Error("Read: error reading table", "filename", tbl.Name)
 				slog.Error(err.Error())
-				return ordered.Node[[]byte, []byte]{}.Value, err
+				return ordered.Node[[]byte, []byte]{}.Value, fmt.Errorf("tbl.Get: %w", err)
 			}
-
-			if val, found := tbl.Search(key); found {
+			if found {
 				return val, nil
 			}
-			err = tbl.Close()
-			if err != nil {
-				slog.Error(err.Error())
-				return []byte{}, fmt.Errorf("tbl.Close: %w", err)
-			}
 		}
 	}
 	return []byte{}, ErrNotFound
 }
 
-func (m *Manifest) searchLowerLevels(key []byte) ([]byte, error) {
-	m.mut.Lock()
-	defer m.mut.Unlock()
-
+func (v *Version) searchLowerLevels(key []byte) ([]byte, error) {
 	// binary search sorted levels 1:3 sequentially
-	for _, level := range m.Levels[1:] {
+	for _, level := range v.Levels[1:] {
+		if level.Tiered {
+			val, err := searchRuns(level, key)
+			if !errors.Is(err, ErrNotFound) {
+				return val, err
+			}
+			continue
+		}
 		if i, found := level.BinarySearch(key); found {
-			if level.Tables[i].Filter.Has(key) {
-				err := level.Tables[i].Open()
+			tbl := level.Tables[i]
+			if tbl.MayContain(key) {
+				val, found, err := tbl.Get(key)
 				if err != nil {
-					slog.Error("Read: error opening table", "filename", level.Tables[i].Name)
+					slog.Error("Read: error reading table", "filename", tbl.Name)
 					slog.Error(err.Error())
-					return ordered.Node[[]byte, []byte]{}.Value, fmt.Errorf("tbl.Open: %w", err)
+					return ordered.Node[[]byte, []byte]{}.Value, fmt.Errorf("tbl.Get: %w", err)
 				}
-				defer level.Tables[i].Close()
-				if val, found := level.Tables[i].Search(key); found {
+				if found {
 					return val, nil
 				}
 			}
@@ -198,52 +448,78 @@ func (m *Manifest) searchLowerLevels(key []byte) ([]byte, error) {
 	return []byte{}, ErrNotFound
 }
 
-func (m *Manifest) AddTable(table *sstable.SSTable, level int) error {
+// LogAndApply durably records edit as a single manifest entry, then installs it
+func (m *Manifest) LogAndApply(edit *VersionEdit) error {
 	m.mut.Lock()
 	defer m.mut.Unlock()
-	m.Levels[level].Add(table)
-	pto, err := table.ToProto()
+	return m.logAndApply(edit)
+}
+
+// Caller must hold m.mut
+func (m *Manifest) logAndApply(edit *VersionEdit) error {
+	if edit.Empty() {
+		return nil
+	}
+	for _, change := range edit.Added {
+		if change.Table.Run == 0 {
+			m.nextRun++
+			change.Table.Run = m.nextRun
+		}
+	}
+	pto, err := edit.ToProto()
 	if err != nil {
 		return err
 	}
-	entry := &ManifestEntry{Op: ADDTABLE, Table: pto, Level: level}
-	err = m.wal.Write(entry)
+	err = m.wal.WriteSync(&ManifestEntry{Op: VERSIONEDIT, Edit: pto})
 	if err != nil {
-		return fmt.Errorf("wal.Write: %w", err)
+		return fmt.Errorf("wal.WriteSync: %w", err)
 	}
-	return nil
+	err = edit.applyTo(m.Levels)
+	if err != nil {
+		return err
+	}
+	m.installVersion()
+	m.queueObsolete(edit)
+	m.deleteObsoleteFiles()
+	return m.maybeRollover()
 }
 
-func (m *Manifest) RemoveTable(table *sstable.SSTable, level int) error {
+// NextRun reserves the run of a table before it is written, so that the table records it
+// in its properties block. Tables added without a run are given one when added.
+func (m *Manifest) NextRun() uint64 {
 	m.mut.Lock()
 	defer m.mut.Unlock()
-	m.Levels[level].Remove(table)
-	pto, err := table.ToProto()
-	if err != nil {
-		return err
-	}
-	entry := &ManifestEntry{Op: REMOVETABLE, Table: pto, Level: level}
-	err = m.wal.Write(entry)
-	if err != nil {
-		return fmt.Errorf("encoder.Encode: %w", err)
-	}
-	return nil
+	m.nextRun++
+	return m.nextRun
+}
+
+func (m *Manifest) AddTable(table *sstable.SSTable, level int) error {
+	edit := &VersionEdit{}
+	edit.AddTable(table, level)
+	return m.LogAndApply(edit)
+}
+
+func (m *Manifest) RemoveTable(table *sstable.SSTable, level int) error {
+	edit := &VersionEdit{}
+	edit.RemoveTable(table, level)
+	return m.LogAndApply(edit)
 }
 
 func (m *Manifest) ClearLevel(level int) error {
 	m.mut.Lock()
 	defer m.mut.Unlock()
-	m.Levels[level].Clear()
-	entry := &ManifestEntry{Op: CLEARTABLE, Table: nil, Level: level}
-	err := m.wal.Write(entry)
-	if err != nil {
-		return fmt.Errorf("encoder.Encode: %w", err)
+	edit := &VersionEdit{}
+	for _, table := range m.Levels[level].Tables {
+		edit.RemoveTable(table, level)
 	}
-	return nil
+	return m.logAndApply(edit)
 }
 
 func (m *Manifest) Close() error {
 	m.done <- true
+	<-m.stopped
+	m.waitForCompaction.Wait()
+	m.DeleteObsoleteFiles()
 	if err := m.wal.Close(); err != nil {
 		return err
 	}
@@ -267,7 +543,12 @@ func (m *Manifest) Replay() error {
 			return fmt.Errorf("proto.Unmarshal line: %s: %w", scanner.Text(), err)
 		}
 		entry := FromProto(&e)
-		err = entry.Apply(m.Levels[e.Level])
+		// Levels added as data grew are not recorded, recreate them before their tables
+		err = m.ensureLevel(entry.highestLevel())
+		if err != nil {
+			return &wal.LogApplyErr{Cause: err}
+		}
+		err = entry.Apply(m.Levels)
 		if err != nil {
 			slog.Error("log apply error", "cause", err)
 			return &wal.LogApplyErr{Cause: err}
@@ -277,15 +558,6 @@ func (m *Manifest) Replay() error {
 		slog.Error("scanner error", "cause", err)
 		return err
 	}
-	for _, level := range m.Levels {
-		for _, tbl := range level.Tables {
-			err = tbl.LoadFilter()
-			if err != nil {
-				slog.Error("Replay: error loading filter")
-				panic(err)
-			}
-		}
-	}
 	return nil
 }
 
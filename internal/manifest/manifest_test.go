@@ -1,10 +1,14 @@
 package manifest
 
 import (
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/dillonkmcquade/gostore/internal/filter"
 	"github.com/dillonkmcquade/gostore/internal/pb"
 	"github.com/dillonkmcquade/gostore/internal/sstable"
 )
@@ -140,8 +144,61 @@ func TestManifest_ClearLevel(t *testing.T) {
 	}
 }
 
-// TODO
 func TestManifestReplay(t *testing.T) {
+	tmp := t.TempDir()
+	opts := &Opts{
+		Path: filepath.Join(tmp, "manifest.txtpb"),
+		LevelPaths: []string{
+			filepath.Join(tmp, "l0"), filepath.Join(tmp, "l1"), filepath.Join(tmp, "l2"), filepath.Join(tmp, "l3"),
+		},
+		Num_levels:       4,
+		Level0_max_size:  500000,
+		SSTable_max_size: 1000,
+		BloomPath:        tmp,
+	}
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []*sstable.SSTable{}
+	for i := byte(0); i < 3; i++ {
+		table := newEditTable(tmp, i*10, i*10+9)
+		table.Filter = filter.New(&filter.Opts{Size: 100, Path: tmp})
+		err = table.Filter.Save()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = man.AddTable(table, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, table)
+	}
+
+	// Move every table from level 0 to level 1 in a single edit
+	edit := &VersionEdit{}
+	for _, table := range tables {
+		edit.RemoveTable(table, 0)
+		edit.AddTable(table, 1)
+	}
+	err = man.LogAndApply(edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	man.Close()
+
+	replayed, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayed.Close()
+	if len(replayed.Levels[0].Tables) != 0 || replayed.Levels[0].Size != 0 {
+		t.Errorf("Level 0 should be empty, found %v tables", len(replayed.Levels[0].Tables))
+	}
+	if len(replayed.Levels[1].Tables) != 3 || replayed.Levels[1].Size != 300 {
+		t.Errorf("Level 1 should contain 3 tables, found %v", len(replayed.Levels[1].Tables))
+	}
 }
 
 func newTestManifest(path string) *Manifest {
@@ -323,3 +380,147 @@ func newTestManifest(path string) *Manifest {
 		Path: filepath.Join(path, "manifest.json"),
 	}
 }
+
+func TestManifestRollover(t *testing.T) {
+	tmp := t.TempDir()
+	opts := &Opts{
+		Path: filepath.Join(tmp, "manifest.txtpb"),
+		LevelPaths: []string{
+			filepath.Join(tmp, "l0"), filepath.Join(tmp, "l1"), filepath.Join(tmp, "l2"), filepath.Join(tmp, "l3"),
+		},
+		Num_levels:        4,
+		Level0_max_size:   500000,
+		SSTable_max_size:  1000,
+		BloomPath:         tmp,
+		Max_manifest_size: 1000,
+	}
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []*sstable.SSTable{}
+	for i := byte(0); i < 20; i++ {
+		table := sstable.New(&sstable.Opts{
+			BloomOpts: &filter.Opts{Size: 100},
+			DestDir:   tmp,
+		})
+		table.First = []byte{i * 10}
+		table.Last = []byte{i*10 + 9}
+		table.Size = 100
+		err = man.AddTable(table, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, table)
+	}
+	for _, table := range tables[:10] {
+		err = man.RemoveTable(table, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if man.Path == opts.Path {
+		t.Fatal("Manifest should have rolled over")
+	}
+	if _, err := os.Stat(opts.Path); !os.IsNotExist(err) {
+		t.Error("Old manifest should be deleted")
+	}
+	current, err := os.ReadFile(filepath.Join(tmp, "CURRENT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Join(tmp, strings.TrimSpace(string(current))) != man.Path {
+		t.Errorf("CURRENT should point to %v, found %s", man.Path, current)
+	}
+	man.Close()
+
+	replayed, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayed.Close()
+	if len(replayed.Levels[1].Tables) != 10 || replayed.Levels[1].Size != 1000 {
+		t.Errorf("Expected 10 tables of total size 1000, found %v tables of size %v", len(replayed.Levels[1].Tables), replayed.Levels[1].Size)
+	}
+}
+
+func TestBitsPerKey(t *testing.T) {
+	m := &Manifest{}
+	if bits := m.bitsPerKey(2); bits != filter.DefaultBitsPerKey {
+		t.Errorf("Expected the default of %v bits per key, found %v", filter.DefaultBitsPerKey, bits)
+	}
+	m.Bits_per_key = []int{8, 12}
+	for level, expected := range []int{8, 12, 12, 12} {
+		if bits := m.bitsPerKey(level); bits != expected {
+			t.Errorf("Expected %v bits per key in level %v, found %v", expected, level, bits)
+		}
+	}
+}
+
+func TestFilterType(t *testing.T) {
+	m := &Manifest{}
+	if typ := m.filterType(2); typ != filter.BLOOM {
+		t.Errorf("Expected the default filter type %v, found %v", filter.BLOOM, typ)
+	}
+	m.Filter_types = []filter.Type{filter.BLOCKED_BLOOM, filter.BLOCKED_BLOOM, filter.XOR}
+	for level, expected := range []filter.Type{filter.BLOCKED_BLOOM, filter.BLOCKED_BLOOM, filter.XOR, filter.XOR} {
+		if typ := m.filterType(level); typ != expected {
+			t.Errorf("Expected filter type %v in level %v, found %v", expected, level, typ)
+		}
+	}
+}
+
+// Counts the entries of a table
+type countCollector struct{ entries int }
+
+func (c *countCollector) Add(key []byte, value []byte, deleted bool) { c.entries++ }
+
+func (c *countCollector) Finish() map[string]string {
+	return map[string]string{"entries": strconv.Itoa(c.entries)}
+}
+
+func TestGetPropertiesOfAllTables(t *testing.T) {
+	opts := newWorkloadOpts(t, t.TempDir())
+	opts.Properties_collectors = []sstable.TablePropertiesCollectorFactory{
+		func() sstable.TablePropertiesCollector { return &countCollector{} },
+	}
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer man.Close()
+
+	// Enough level 0 tables to compact them into level 1
+	for i := 0; i < opts.Level0_file_trigger; i++ {
+		if err := man.AddTable(newKeysTable(t, opts, i*100, 100), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForIdle(t, man)
+
+	props, err := man.GetPropertiesOfAllTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := man.Current()
+	defer version.Unref()
+	if len(version.Levels[0].Tables) != 0 || len(props) != len(version.Levels[1].Tables) {
+		t.Fatalf("Expected the properties of the level 1 tables, found %v for %v tables", len(props), len(version.Levels[1].Tables))
+	}
+	entries := 0
+	for name, p := range props {
+		collected, err := strconv.Atoi(p.User["entries"])
+		if err != nil || int64(collected) != p.NumEntries {
+			t.Errorf("Expected table %v to collect its %v entries, found %q", name, p.NumEntries, p.User["entries"])
+		}
+		entries += collected
+		if p.MinSequence != 1 || p.MaxSequence != uint64(opts.Level0_file_trigger) {
+			t.Errorf("Expected table %v to hold the writes of runs 1 to %v, found %v to %v", name, opts.Level0_file_trigger, p.MinSequence, p.MaxSequence)
+		}
+	}
+	if entries != 100*opts.Level0_file_trigger {
+		t.Errorf("Expected %v entries in all, found %v", 100*opts.Level0_file_trigger, entries)
+	}
+}
@@ -0,0 +1,161 @@
+package manifest
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Obsolete file collection:
+//
+// A table removed by a VersionEdit is queued as obsolete. Its segment, and its filter file
+// if it predates embedded filters, are deleted once neither the working levels nor any referenced Version contain them.
+// Failed deletions stay queued and are retried on the next collection.
+//
+// Files that were never queued, such as the outputs of a compaction that crashed before
+// its edit was committed, are found by the startup sweep instead.
+
+// Queue the files of the tables removed by edit for deletion. Caller must hold m.mut.
+func (m *Manifest) queueObsolete(edit *VersionEdit) {
+	for _, change := range edit.Removed {
+		m.obsolete = append(m.obsolete, change.Table.Name)
+		if name := change.Table.FilterMetadata().Name; name != "" {
+			m.obsolete = append(m.obsolete, name)
+		}
+	}
+}
+
+// DeleteObsoleteFiles deletes every queued file that is no longer referenced
+func (m *Manifest) DeleteObsoleteFiles() {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.deleteObsoleteFiles()
+}
+
+// Caller must hold m.mut
+func (m *Manifest) deleteObsoleteFiles() {
+	if len(m.obsolete) == 0 {
+		return
+	}
+	working := fileSet(m.Levels)
+	inUse := m.filesInUse()
+	pending := m.obsolete[:0]
+	for _, path := range m.obsolete {
+		abs := absPath(path)
+		if _, ok := working[abs]; ok || path == "" {
+			// Still part of the layout, e.g. a table moved between levels
+			continue
+		}
+		if _, ok := inUse[abs]; ok {
+			pending = append(pending, path)
+			continue
+		}
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			slog.Warn("Failure to remove obsolete file", "filename", path, "cause", err)
+			pending = append(pending, path)
+		}
+	}
+	m.obsolete = pending
+}
+
+// Returns the absolute path of every file referenced by a version that is still in use
+func (m *Manifest) filesInUse() map[string]struct{} {
+	m.versionsMut.Lock()
+	defer m.versionsMut.Unlock()
+	files := make(map[string]struct{})
+	for v := range m.versions {
+		addFiles(files, v.Levels)
+	}
+	return files
+}
+
+// Returns the absolute path of every segment and filter referenced by levels
+func fileSet(levels []*Level) map[string]struct{} {
+	files := make(map[string]struct{})
+	addFiles(files, levels)
+	return files
+}
+
+func addFiles(files map[string]struct{}, levels []*Level) {
+	for _, level := range levels {
+		for _, table := range level.Tables {
+			files[absPath(table.Name)] = struct{}{}
+			if name := table.FilterMetadata().Name; name != "" {
+				files[absPath(name)] = struct{}{}
+			}
+		}
+	}
+}
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// Deletes files left behind by crashes: segments and filters not referenced by the
+// replayed levels, and manifests other than the live one.
+//
+// Must run after Replay and before any table is flushed.
+func (m *Manifest) sweepOrphans(opts *Opts) error {
+	live := fileSet(m.Levels)
+
+	for _, level := range m.Levels {
+		err := sweepDir(level.Path, live, func(name string) bool {
+			return strings.HasSuffix(name, ".segment")
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.BloomPath != "" {
+		err := sweepDir(m.BloomPath, live, func(name string) bool {
+			return strings.HasPrefix(name, "bloom_") && strings.HasSuffix(name, ".dat")
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Older manifests are the log segments of the manifest, only the live one is kept
+	live[absPath(m.Path)] = struct{}{}
+	initial := filepath.Base(opts.Path)
+	return sweepDir(filepath.Dir(m.Path), live, func(name string) bool {
+		return name == initial ||
+			name == currentFileName+".tmp" ||
+			name == layoutFileName+".tmp" ||
+			(strings.HasPrefix(name, "manifest_") && strings.HasSuffix(name, ".txtpb"))
+	})
+}
+
+// Removes the files in dir matched by owned that are not live
+func sweepDir(dir string, live map[string]struct{}, owned func(name string) bool) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("os.ReadDir: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !owned(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if _, ok := live[absPath(path)]; ok {
+			continue
+		}
+		slog.Info("Removing orphaned file", "filename", path)
+		err = os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("os.Remove: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+func newObsoleteOpts(tmp string) *Opts {
+	return &Opts{
+		Path: filepath.Join(tmp, "manifest.txtpb"),
+		LevelPaths: []string{
+			filepath.Join(tmp, "l0"), filepath.Join(tmp, "l1"), filepath.Join(tmp, "l2"), filepath.Join(tmp, "l3"),
+		},
+		Num_levels:       4,
+		Level0_max_size:  500000,
+		SSTable_max_size: 1000,
+		BloomPath:        filepath.Join(tmp, "filters"),
+	}
+}
+
+// Creates a table with an empty segment and a saved filter on disk
+func newFileTable(t *testing.T, dir string, bloomDir string, first byte, last byte) *sstable.SSTable {
+	for _, d := range []string{dir, bloomDir} {
+		if err := os.MkdirAll(d, 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	table := newEditTable(dir, first, last)
+	table.Filter = filter.New(&filter.Opts{Size: 100, Path: bloomDir})
+	if err := table.Filter.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(table.Name, []byte{}, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestDeleteObsoleteFiles(t *testing.T) {
+	tmp := t.TempDir()
+	opts := newObsoleteOpts(tmp)
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer man.Close()
+
+	t.Run("Test removed table is deleted", func(t *testing.T) {
+		table := newFileTable(t, opts.LevelPaths[0], opts.BloomPath, 0, 9)
+		if err := man.AddTable(table, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := man.RemoveTable(table, 0); err != nil {
+			t.Fatal(err)
+		}
+		if exists(table.Name) || exists(table.Filter.Path()) {
+			t.Error("Segment and filter should be deleted")
+		}
+	})
+
+	t.Run("Test table in referenced version is kept", func(t *testing.T) {
+		table := newFileTable(t, opts.LevelPaths[0], opts.BloomPath, 10, 19)
+		if err := man.AddTable(table, 0); err != nil {
+			t.Fatal(err)
+		}
+		version := man.Current()
+		if err := man.RemoveTable(table, 0); err != nil {
+			t.Fatal(err)
+		}
+		if !exists(table.Name) {
+			t.Fatal("Segment should be kept while a reader holds a version containing it")
+		}
+		version.Unref()
+		man.DeleteObsoleteFiles()
+		if exists(table.Name) || exists(table.Filter.Path()) {
+			t.Error("Segment and filter should be deleted once released")
+		}
+	})
+
+	t.Run("Test moved table is kept", func(t *testing.T) {
+		table := newFileTable(t, opts.LevelPaths[1], opts.BloomPath, 20, 29)
+		if err := man.AddTable(table, 1); err != nil {
+			t.Fatal(err)
+		}
+		edit := &VersionEdit{}
+		edit.RemoveTable(table, 1)
+		edit.AddTable(table, 2)
+		if err := man.LogAndApply(edit); err != nil {
+			t.Fatal(err)
+		}
+		if !exists(table.Name) || !exists(table.Filter.Path()) {
+			t.Error("A table still referenced by another level should be kept")
+		}
+	})
+}
+
+func TestSweepOrphans(t *testing.T) {
+	tmp := t.TempDir()
+	opts := newObsoleteOpts(tmp)
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := newFileTable(t, opts.LevelPaths[1], opts.BloomPath, 0, 9)
+	if err = man.AddTable(live, 1); err != nil {
+		t.Fatal(err)
+	}
+	man.Close()
+
+	// Files left behind by a crash before their edit was committed
+	orphan := newFileTable(t, opts.LevelPaths[0], opts.BloomPath, 10, 19)
+	staleManifest := filepath.Join(tmp, generateUniqueManifestName())
+	unowned := filepath.Join(opts.LevelPaths[0], "notes.txt")
+	for _, name := range []string{staleManifest, unowned} {
+		if err = os.WriteFile(name, []byte{}, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	man, err = New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer man.Close()
+
+	if exists(orphan.Name) || exists(orphan.Filter.Path()) {
+		t.Error("Orphaned segment and filter should be deleted")
+	}
+	if exists(staleManifest) {
+		t.Error("Stale manifest should be deleted")
+	}
+	// The live table was copied with its filter embedded on reopen
+	version := man.Current()
+	defer version.Unref()
+	if len(version.Levels[1].Tables) != 1 || !exists(version.Levels[1].Tables[0].Name) || !exists(man.Path) {
+		t.Error("Live files should be kept")
+	}
+	if !exists(unowned) {
+		t.Error("Files not created by the store should be kept")
+	}
+}
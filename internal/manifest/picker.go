@@ -0,0 +1,170 @@
+package manifest
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// Default number of level 0 tables that triggers a level 0 compaction
+const defaultLevel0FileTrigger = 4
+
+// A compaction chosen by the picker
+type compaction struct {
+	version  *Version           // Version the inputs were picked from, referenced until the compaction ends
+	level    int                // Level the inputs are taken from
+	output   int                // Level the outputs are written to
+	tiered   bool               // Merges whole runs of a tiered level
+	fifo     bool               // Drops inputs without merging
+	score    float64            // Score of level when the compaction was picked
+	inputs   []*sstable.SSTable // Tables from level
+	overlaps []*sstable.SSTable // Tables from level+1 overlapping inputs
+	first    []byte             // Smallest key of inputs and overlaps
+	last     []byte             // Largest key of inputs and overlaps
+}
+
+func newCompaction(version *Version, level int, inputs []*sstable.SSTable, overlaps []*sstable.SSTable) *compaction {
+	c := &compaction{version: version, level: level, output: level + 1, inputs: inputs, overlaps: overlaps}
+	for _, table := range append(slices.Clone(inputs), overlaps...) {
+		if c.first == nil || slices.Compare(table.First, c.first) < 0 {
+			c.first = table.First
+		}
+		if c.last == nil || slices.Compare(table.Last, c.last) > 0 {
+			c.last = table.Last
+		}
+	}
+	return c
+}
+
+// Two compactions conflict if they could read or write the same keys of the same level.
+// Only one level 0 compaction may run at a time, so that L0 is always compacted oldest first.
+// Tiered compactions merge whole runs, so they conflict with any compaction sharing a level.
+func (c *compaction) conflicts(other *compaction) bool {
+	if c.level == 0 && other.level == 0 {
+		return true
+	}
+	sharesLevel := c.level == other.level || c.level == other.output || c.output == other.level || c.output == other.output
+	if c.tiered || other.tiered {
+		return sharesLevel
+	}
+	return sharesLevel &&
+		slices.Compare(c.first, other.last) <= 0 &&
+		slices.Compare(other.first, c.last) <= 0
+}
+
+// Score of level in version. A score of 1 or more means the level needs compaction.
+//
+// L0 is scored by table count since every L0 table is searched on a read,
+// lower levels by size over their target size.
+func (m *Manifest) score(version *Version, level *Level) float64 {
+	if m.Compaction_style == FIFO {
+		return m.fifoScore(level)
+	}
+	if level.Tiered {
+		return m.tieredScore(level)
+	}
+	if level.Number == len(version.Levels)-1 {
+		// The last level has nowhere to compact to
+		return 0
+	}
+	if level.Number == 0 {
+		return float64(len(level.Tables)) / float64(m.Level0_file_trigger)
+	}
+	if level.MaxSize <= 0 {
+		if m.Dynamic_level_size && level.Size > 0 {
+			// Levels above the base level have no target and are drained into the next level
+			return 1 + float64(level.Size)/float64(max(m.Level0_max_size, 1))
+		}
+		return 0
+	}
+	return float64(level.Size) / float64(level.MaxSize)
+}
+
+// Returns every level that needs compaction, highest score first
+func (m *Manifest) levelsByScore(version *Version) []*Level {
+	scores := make(map[*Level]float64)
+	levels := []*Level{}
+	for _, level := range version.Levels {
+		if score := m.score(version, level); score >= 1 {
+			scores[level] = score
+			levels = append(levels, level)
+		}
+	}
+	slices.SortStableFunc(levels, func(a, b *Level) int {
+		return cmp.Compare(scores[b], scores[a])
+	})
+	return levels
+}
+
+// Compacts every table in level 0 and the tables they overlap in the base level
+func (v *Version) level0Compaction() *compaction {
+	base := v.baseLevel()
+	inputs := v.Levels[0].Tables
+	overlaps := []*sstable.SSTable{}
+	for _, tbl := range v.Levels[base].Tables {
+		if slices.ContainsFunc(inputs, tbl.Overlaps) {
+			overlaps = append(overlaps, tbl)
+		}
+	}
+	c := newCompaction(v, 0, inputs, overlaps)
+	c.output = base
+	return c
+}
+
+// Compacts table from level into the tables it overlaps in the next level
+func (v *Version) tableCompaction(level *Level, table *sstable.SSTable) *compaction {
+	overlaps := sstable.Overlapping(table, v.Levels[level.Number+1].Tables)
+	return newCompaction(v, level.Number, []*sstable.SSTable{table}, overlaps)
+}
+
+// Candidate compactions for level, in the order chosen by picker
+func (m *Manifest) candidates(v *Version, level *Level, picker FilePicker) []*compaction {
+	if m.Compaction_style == FIFO {
+		if c := m.fifoCompaction(v); c != nil {
+			return []*compaction{c}
+		}
+		return nil
+	}
+	if level.Tiered {
+		if c := m.tieredCompaction(v, level); c != nil {
+			return []*compaction{c}
+		}
+		return nil
+	}
+	if level.Number == 0 {
+		if len(level.Tables) == 0 {
+			return nil
+		}
+		return []*compaction{v.level0Compaction()}
+	}
+	tables := picker.Pick(v, level)
+	candidates := make([]*compaction, 0, len(tables))
+	for _, table := range tables {
+		candidates = append(candidates, v.tableCompaction(level, table))
+	}
+	return candidates
+}
+
+// Picks the compaction of the highest scoring level that does not conflict with a
+// running compaction. Returns nil if there is nothing to do.
+//
+// The returned compaction holds a reference to its version. Caller must hold m.schedMut.
+func (m *Manifest) pickCompaction() *compaction {
+	version := m.Current()
+	for _, level := range m.levelsByScore(version) {
+		for _, c := range m.candidates(version, level, m.File_picker) {
+			if slices.ContainsFunc(m.running, c.conflicts) {
+				continue
+			}
+			c.score = m.score(version, level)
+			if tracker, ok := m.File_picker.(PickTracker); ok && c.level > 0 && !c.tiered && !c.fifo {
+				// Only leveled compactions of level 1 and below are chosen by the picker
+				tracker.Picked(c.level, c.inputs[0])
+			}
+			return c
+		}
+	}
+	version.Unref()
+	return nil
+}
@@ -0,0 +1,116 @@
+package manifest
+
+import (
+	"sync"
+	"testing"
+)
+
+func newPickerManifest(t *testing.T, levels ...*Level) *Manifest {
+	m := &Manifest{
+		Level0_file_trigger: 2,
+		File_picker:         OldestFirst{},
+		versions:            make(map[*Version]struct{}),
+		versionReleased:     make(chan struct{}, 1),
+		wakeCompaction:      make(chan struct{}, 1),
+	}
+	m.compactionDone = sync.NewCond(&m.schedMut)
+	m.Levels = levels
+	m.installVersion()
+	return m
+}
+
+func TestCompactionScore(t *testing.T) {
+	tmp := t.TempDir()
+	l0 := &Level{Number: 0}
+	l1 := &Level{Number: 1, MaxSize: 400}
+	l2 := &Level{Number: 2, MaxSize: 100}
+	for i := byte(0); i < 3; i++ {
+		l0.Add(newEditTable(tmp, i, i+10))
+		l1.Add(newEditTable(tmp, i*10, i*10+9))
+		l2.Add(newEditTable(tmp, i*10, i*10+9))
+	}
+	man := newPickerManifest(t, l0, l1, l2)
+	version := man.Current()
+	defer version.Unref()
+
+	if score := man.score(version, version.Levels[0]); score != 1.5 {
+		t.Errorf("Level 0 should be scored by table count, expected 1.5 found %v", score)
+	}
+	if score := man.score(version, version.Levels[1]); score != 0.75 {
+		t.Errorf("Level 1 should be scored by size, expected 0.75 found %v", score)
+	}
+	if score := man.score(version, version.Levels[2]); score != 0 {
+		t.Errorf("The last level should never be compacted, found score %v", score)
+	}
+}
+
+func TestPickCompaction(t *testing.T) {
+	tmp := t.TempDir()
+	l0 := &Level{Number: 0}
+	l1 := &Level{Number: 1, MaxSize: 100}
+	l2 := &Level{Number: 2, MaxSize: 10000}
+	l3 := &Level{Number: 3}
+	l0.Add(newEditTable(tmp, 0, 9))
+	l1.Add(newEditTable(tmp, 20, 29))
+	l1.Add(newEditTable(tmp, 30, 39))
+	l1.Add(newEditTable(tmp, 40, 49))
+	man := newPickerManifest(t, l0, l1, l2, l3)
+
+	t.Run("Test highest score first", func(t *testing.T) {
+		c := man.pickCompaction()
+		if c == nil || c.level != 1 {
+			t.Fatalf("Expected level 1 to be picked, found %v", c)
+		}
+		man.running = append(man.running, c)
+	})
+
+	t.Run("Test non-overlapping compactions run in parallel", func(t *testing.T) {
+		c := man.pickCompaction()
+		if c == nil || c.level != 1 {
+			t.Fatalf("Expected a second level 1 compaction, found %v", c)
+		}
+		if c.inputs[0] == man.running[0].inputs[0] {
+			t.Error("The running compaction's input should not be picked twice")
+		}
+		man.running = append(man.running, c)
+	})
+
+	t.Run("Test conflicting compactions are skipped", func(t *testing.T) {
+		l0 := &compaction{level: 0, output: 1, first: []byte{0}, last: []byte{9}}
+		if !l0.conflicts(&compaction{level: 0, output: 1, first: []byte{50}, last: []byte{59}}) {
+			t.Error("Level 0 compactions should always conflict")
+		}
+		if !l0.conflicts(&compaction{level: 1, output: 2, first: []byte{5}, last: []byte{15}}) {
+			t.Error("A level 1 compaction overlapping a level 0 compaction should conflict")
+		}
+		if l0.conflicts(&compaction{level: 2, output: 3, first: []byte{0}, last: []byte{9}}) {
+			t.Error("Compactions on disjoint levels should not conflict")
+		}
+	})
+
+	for _, c := range man.running {
+		c.version.Unref()
+	}
+}
+
+func TestRoundRobinScheduling(t *testing.T) {
+	tmp := t.TempDir()
+	l1 := &Level{Number: 1, MaxSize: 100}
+	l1.Add(newEditTable(tmp, 20, 29))
+	l1.Add(newEditTable(tmp, 30, 39))
+	l1.Add(newEditTable(tmp, 40, 49))
+	man := newPickerManifest(t, &Level{Number: 0}, l1, &Level{Number: 2})
+	rr := NewRoundRobin()
+	man.File_picker = rr
+
+	// The first table past the cursor conflicts, the next one is scheduled
+	man.running = append(man.running, &compaction{level: 1, output: 2, first: []byte{20}, last: []byte{29}})
+	c := man.pickCompaction()
+	if c == nil || c.inputs[0].First[0] != 30 {
+		t.Fatalf("Expected the table starting at 30 to be picked, found %v", c)
+	}
+	defer c.version.Unref()
+	if cursor := rr.cursors[1]; len(cursor) != 1 || cursor[0] != 39 {
+		t.Errorf("Expected the cursor at the scheduled table, found %v", cursor)
+	}
+}
@@ -0,0 +1,190 @@
+package manifest
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dillonkmcquade/gostore/internal"
+	"github.com/dillonkmcquade/gostore/internal/wal"
+)
+
+// Name of the file pointing to the live manifest, stored next to the manifest
+const currentFileName = "CURRENT"
+
+// Generates a manifest filename in the format manifest_UNIQUESTRING.txtpb
+func generateUniqueManifestName() string {
+	uniqueString, err := internal.GenerateRandomString(8)
+	if err != nil {
+		slog.Error("generateUniqueManifestName: error generating random string")
+		panic(err)
+	}
+	return fmt.Sprintf("manifest_%v.txtpb", uniqueString)
+}
+
+// Returns the path of the live manifest.
+//
+// The CURRENT file in the same directory as path names the live manifest. If it
+// does not exist yet, path itself is the live manifest and CURRENT is created.
+func resolveCurrent(path string) (string, error) {
+	dir := filepath.Dir(path)
+	b, err := os.ReadFile(filepath.Join(dir, currentFileName))
+	if os.IsNotExist(err) {
+		return path, setCurrent(dir, filepath.Base(path))
+	}
+	if err != nil {
+		return "", fmt.Errorf("os.ReadFile: %w", err)
+	}
+	name := strings.TrimSpace(string(b))
+	if name == "" {
+		return "", fmt.Errorf("%v is empty", currentFileName)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// Atomically points CURRENT at the manifest with the given base name
+func setCurrent(dir string, name string) error {
+	return replaceFile(dir, currentFileName, name+"\n")
+}
+
+// Atomically replaces the file dir/name with content
+func replaceFile(dir string, name string, content string) error {
+	tmp := filepath.Join(dir, name+".tmp")
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("os.OpenFile: %w", err)
+	}
+	if _, err = file.WriteString(content); err != nil {
+		file.Close()
+		return fmt.Errorf("file.WriteString: %w", err)
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("file.Sync: %w", err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("file.Close: %w", err)
+	}
+	if err = os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("os.Rename: %w", err)
+	}
+	return syncDir(dir)
+}
+
+// Persist directory entries (renames, creates) to stable storage
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("os.Open: %w", err)
+	}
+	defer d.Close()
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("dir.Sync: %w", err)
+	}
+	return nil
+}
+
+// Writes a snapshot of every level to a new file and returns its size in bytes
+func (m *Manifest) writeSnapshot(path string) (int64, error) {
+	edit := &VersionEdit{}
+	for _, level := range m.Levels {
+		for _, table := range level.Tables {
+			edit.AddTable(table, level.Number)
+		}
+	}
+	return writeEdit(path, edit)
+}
+
+// Writes a new manifest holding edit as its only entry and returns its size in bytes
+func writeEdit(path string, edit *VersionEdit) (int64, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("os.OpenFile: %w", err)
+	}
+	defer file.Close()
+
+	pto, err := edit.ToProto()
+	if err != nil {
+		return 0, err
+	}
+	writer := wal.NewBatchWriter(file)
+	writer.Write(&ManifestEntry{Op: VERSIONEDIT, Edit: pto})
+	if err = writer.Err(); err != nil {
+		return 0, fmt.Errorf("writer.Write: %w", err)
+	}
+	if err = file.Sync(); err != nil {
+		return 0, fmt.Errorf("file.Sync: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("file.Stat: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// Rollover writes the current level layout to a new manifest, switches CURRENT to it
+// and deletes the old manifest, so that Replay never has to apply stale history.
+func (m *Manifest) Rollover() error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.rollover()
+}
+
+// Rolls over once the edits appended since the last snapshot exceed Max_manifest_size.
+// Caller must hold m.mut.
+func (m *Manifest) maybeRollover() error {
+	if m.Max_manifest_size <= 0 {
+		return nil
+	}
+	size, err := m.wal.Size()
+	if err != nil {
+		return fmt.Errorf("wal.Size: %w", err)
+	}
+	if size-m.snapshotSize < m.Max_manifest_size {
+		return nil
+	}
+	slog.Debug("Manifest exceeded max size, rolling over", "size", size, "max", m.Max_manifest_size)
+	return m.rollover()
+}
+
+// Caller must hold m.mut
+func (m *Manifest) rollover() error {
+	dir := filepath.Dir(m.Path)
+	name := generateUniqueManifestName()
+	path := filepath.Join(dir, name)
+
+	snapshotSize, err := m.writeSnapshot(path)
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("writeSnapshot: %w", err)
+	}
+
+	newWal, err := wal.New[*ManifestEntry](path, 1)
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("wal.New: %w", err)
+	}
+
+	// The snapshot is complete, switching CURRENT commits the rollover
+	err = setCurrent(dir, name)
+	if err != nil {
+		newWal.Close()
+		os.Remove(path)
+		return fmt.Errorf("setCurrent: %w", err)
+	}
+
+	err = m.wal.Close()
+	if err != nil {
+		slog.Warn("Failure to close old manifest", "cause", err)
+	}
+	old := m.Path
+	m.wal = newWal
+	m.Path = path
+	m.snapshotSize = snapshotSize
+	if err = os.Remove(old); err != nil {
+		slog.Warn("Failure to remove old manifest", "filename", old)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package manifest
+
+import (
+	"bytes"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// Bytes of input each subcompaction is given at least, unless set in Opts
+const defaultSubcompactionSize = 8 << 20
+
+// Merges tables as up to Max_subcompactions disjoint key ranges in parallel and returns
+// the output tables in key order.
+//
+// Compactions of less than two Subcompaction_size worth of input are merged as a single
+// range. Otherwise the key space is split at the last keys of the data blocks of the inputs,
+// read from their indexes, into ranges holding a similar number of blocks, so that every
+// range streams its own merge of the inputs and writes non-overlapping output tables.
+func (man *Manifest) subcompact(c *compaction, tables []*sstable.SSTable, inherited outputMeta) []*sstable.SSTable {
+	level := c.version.Levels[c.output]
+	var size int64
+	for _, table := range tables {
+		size += table.Size
+	}
+	n := min(int64(man.Max_subcompactions), size/max(man.Subcompaction_size, 1))
+	var bounds [][]byte
+	if n > 1 {
+		bounds = subcompactionBounds(blockKeys(tables), int(n))
+	}
+	if len(bounds) == 0 {
+		return man.writeOutputs(level, man.mergeEntries(c, tables), inherited)
+	}
+	slog.Debug("Subcompactions", "level", c.level, "ranges", len(bounds)+1, "bytes", size)
+
+	results := make([][]*sstable.SSTable, len(bounds)+1)
+	var wg sync.WaitGroup
+	for i := range results {
+		var start, end []byte
+		if i > 0 {
+			start = bounds[i-1]
+		}
+		if i < len(bounds) {
+			end = bounds[i]
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			merged := sstable.MergeRange(man.Rate_limiter, start, end, tables...)
+			results[i] = man.writeOutputs(level, man.filtered(c, merged), inherited)
+		}()
+	}
+	wg.Wait()
+	return slices.Concat(results...)
+}
+
+// Returns the last key of every data block of tables
+func blockKeys(tables []*sstable.SSTable) [][]byte {
+	keys := [][]byte{}
+	for _, table := range tables {
+		tableKeys, err := table.BlockKeys()
+		if err != nil {
+			slog.Error("subcompact: error reading table index", "filename", table.Name)
+			panic(err)
+		}
+		keys = append(keys, tableKeys...)
+	}
+	return keys
+}
+
+// Returns up to n-1 increasing keys that split the keys into n ranges of
+// similar size. Returns none if n < 2.
+func subcompactionBounds(keys [][]byte, n int) [][]byte {
+	if n < 2 || len(keys) == 0 {
+		return nil
+	}
+	keys = slices.Clone(keys)
+	slices.SortFunc(keys, bytes.Compare)
+	keys = slices.CompactFunc(keys, bytes.Equal)
+
+	bounds := [][]byte{}
+	for i := 1; i < n; i++ {
+		key := keys[i*len(keys)/n]
+		// The first range must not be empty and bounds must increase
+		if bytes.Equal(key, keys[0]) || (len(bounds) > 0 && bytes.Compare(key, bounds[len(bounds)-1]) <= 0) {
+			continue
+		}
+		bounds = append(bounds, key)
+	}
+	return bounds
+}
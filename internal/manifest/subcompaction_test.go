@@ -0,0 +1,149 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+func TestSubcompactionBounds(t *testing.T) {
+	keys := [][]byte{}
+	for table := 0; table < 3; table++ {
+		for key := table * 100; key < table*100+400; key++ {
+			keys = append(keys, []byte(fmt.Sprintf("%08d", key)))
+		}
+	}
+
+	if bounds := subcompactionBounds(keys, 1); len(bounds) != 0 {
+		t.Errorf("Expected no bounds for a single range, found %v", len(bounds))
+	}
+
+	bounds := subcompactionBounds(keys, 4)
+	if len(bounds) != 3 {
+		t.Fatalf("Expected 3 bounds, found %v", len(bounds))
+	}
+	if !slices.IsSortedFunc(bounds, bytes.Compare) {
+		t.Error("Expected increasing bounds")
+	}
+	for i := 0; i <= len(bounds); i++ {
+		size := 0
+		for _, key := range keys {
+			if (i == 0 || bytes.Compare(key, bounds[i-1]) >= 0) && (i == len(bounds) || bytes.Compare(key, bounds[i]) < 0) {
+				size++
+			}
+		}
+		if size < 200 || size > 400 {
+			t.Errorf("Expected about 300 entries in range %v, found %v", i, size)
+		}
+	}
+
+	same := [][]byte{{1}, {1}}
+	if bounds := subcompactionBounds(same, 4); len(bounds) != 0 {
+		t.Errorf("Expected a single key to give a single range, found %v bounds", len(bounds))
+	}
+}
+
+func TestBlockKeys(t *testing.T) {
+	opts := newWorkloadOpts(t, t.TempDir())
+	entries := []*pb.SSTable_Entry{}
+	for key := 0; key < 1000; key++ {
+		entries = append(entries, &pb.SSTable_Entry{Key: []byte(fmt.Sprintf("%08d", key)), Value: []byte("value"), Op: pb.Operation_OPERATION_INSERT})
+	}
+	table := newFlushTable(t, opts, entries)
+
+	tableKeys, err := table.BlockKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := blockKeys([]*sstable.SSTable{table, table})
+	if len(tableKeys) < 2 || len(keys) != 2*len(tableKeys) {
+		t.Errorf("Expected the keys of every block of both tables, found %v of %v", len(keys), len(tableKeys))
+	}
+	if !slices.IsSortedFunc(keys[:len(keys)/2], bytes.Compare) {
+		t.Error("Expected each table's keys in key order")
+	}
+	if !bytes.Equal(keys[len(keys)-1], table.Last) {
+		t.Errorf("Expected the keys to end at the last key, found %s", keys[len(keys)-1])
+	}
+}
+
+// Merges tables into level 1 of man and returns the output tables and their entries
+func compactInto(t *testing.T, man *Manifest, tables []*sstable.SSTable) ([]*sstable.SSTable, []*pb.SSTable_Entry) {
+	version := man.Current()
+	defer version.Unref()
+	edit := &VersionEdit{}
+	man.mergeInto(edit, newCompaction(version, 0, tables, nil), tables...)
+	outputs := []*sstable.SSTable{}
+	entries := []*pb.SSTable_Entry{}
+	for _, added := range edit.Added {
+		outputs = append(outputs, added.Table)
+		tableEntries, err := added.Table.ReadEntries()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, tableEntries...)
+	}
+	return outputs, entries
+}
+
+func TestSubcompact(t *testing.T) {
+	opts := newWorkloadOpts(t, t.TempDir())
+	opts.Level0_file_trigger = 100
+	opts.SSTable_max_size = 100
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer man.Close()
+
+	// Overlapping tables, later tables overwrite earlier ones
+	tables := []*sstable.SSTable{}
+	for i := 0; i < 4; i++ {
+		entries := []*pb.SSTable_Entry{}
+		for key := i * 50; key < i*50+300; key++ {
+			entries = append(entries, &pb.SSTable_Entry{Key: []byte(fmt.Sprintf("%08d", key)), Value: []byte(fmt.Sprintf("v%d", i)), Op: pb.Operation_OPERATION_INSERT})
+		}
+		tables = append(tables, newFlushTable(t, opts, entries))
+	}
+
+	// Each range ends with a partial output table
+	partial := func(outputs []*sstable.SSTable) int {
+		count := 0
+		for _, table := range outputs {
+			if table.NumEntries < int64(opts.SSTable_max_size) {
+				count++
+			}
+		}
+		return count
+	}
+
+	man.Max_subcompactions = 1
+	_, expected := compactInto(t, man, tables)
+	man.Max_subcompactions = 4
+	if outputs, _ := compactInto(t, man, tables); partial(outputs) > 1 {
+		t.Errorf("Expected a compaction under Subcompaction_size to be merged as one range, found %v ranges", partial(outputs))
+	}
+	man.Subcompaction_size = 1
+	outputs, entries := compactInto(t, man, tables)
+	if partial(outputs) < 2 {
+		t.Errorf("Expected the compaction to be split, found %v ranges", partial(outputs))
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %v entries, found %v", len(expected), len(entries))
+	}
+	for i := range entries {
+		if !bytes.Equal(entries[i].Key, expected[i].Key) || !bytes.Equal(entries[i].Value, expected[i].Value) {
+			t.Fatalf("Expected %s=%s, found %s=%s", expected[i].Key, expected[i].Value, entries[i].Key, entries[i].Value)
+		}
+	}
+	for i := 1; i < len(outputs); i++ {
+		if bytes.Compare(outputs[i-1].Last, outputs[i].First) >= 0 {
+			t.Errorf("Expected outputs in key order without overlap, %s >= %s", outputs[i-1].Last, outputs[i].First)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package manifest
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// Tiered compaction:
+//
+// Every flush adds a new sorted run to level 0. A tiered level keeps several runs that
+// may overlap, trading read and space amplification for lower write amplification.
+//
+// Once a level holds Tier_run_trigger runs it is compacted. Starting from the newest run,
+// older runs are added to the merge while their size is within Tier_size_ratio percent of
+// the runs already picked. If that window holds at least Tier_min_merge_width runs but
+// not the whole level, it is merged in place into one run. Otherwise every run of the
+// level is merged into a single run in the next level, or in place on the last level.
+//
+// Runs are numbered in the order their data was written, so a read searches the runs of
+// each level newest first. A merged run takes the number of its newest input, which keeps
+// it between the runs older and newer than its inputs.
+
+const (
+	defaultTierRunTrigger = 4
+	defaultTierSizeRatio  = 20
+)
+
+// Numbers the tables written before runs were recorded below every recorded run, deepest
+// level first. Returns true if any table was numbered. Caller must hold m.mut.
+func (m *Manifest) assignMissingRuns() bool {
+	legacy := []*sstable.SSTable{}
+	recorded := []*sstable.SSTable{}
+	for i := len(m.Levels) - 1; i >= 0; i-- {
+		for _, table := range m.Levels[i].Tables {
+			if table.Run == 0 {
+				legacy = append(legacy, table)
+			} else {
+				recorded = append(recorded, table)
+			}
+		}
+	}
+	if len(legacy) == 0 {
+		for _, table := range recorded {
+			m.nextRun = max(m.nextRun, table.Run)
+		}
+		return false
+	}
+
+	slices.SortStableFunc(recorded, func(a, b *sstable.SSTable) int { return cmp.Compare(a.Run, b.Run) })
+	var run uint64
+	for _, table := range legacy {
+		run++
+		table.Run = run
+	}
+	// Recorded tables keep their relative order and grouping
+	var previous uint64
+	for _, table := range recorded {
+		if table.Run != previous {
+			previous = table.Run
+			run++
+		}
+		table.Run = run
+	}
+	m.nextRun = run
+	return true
+}
+
+// Search the runs of a tiered level, newest first
+func searchRuns(level *Level, key []byte) ([]byte, error) {
+	runs := level.runs
+	if runs == nil {
+		runs = level.Runs()
+	}
+	for _, run := range runs {
+		i, found := findTable(run, key)
+		if !found || !run[i].MayContain(key) {
+			continue
+		}
+		val, found, err := run[i].Get(key)
+		if err != nil {
+			slog.Error("Read: error reading table", "filename", run[i].Name)
+			return []byte{}, fmt.Errorf("tbl.Get: %w", err)
+		}
+		if found {
+			return val, nil
+		}
+	}
+	return []byte{}, ErrNotFound
+}
+
+func runSize(run []*sstable.SSTable) int64 {
+	var size int64
+	for _, table := range run {
+		size += table.Size
+	}
+	return size
+}
+
+// Tiered levels are scored by run count
+func (m *Manifest) tieredScore(level *Level) float64 {
+	runs := level.runs
+	if runs == nil {
+		runs = level.Runs()
+	}
+	return float64(len(runs)) / float64(m.Tier_run_trigger)
+}
+
+// Returns the compaction of a tiered level: a window of similarly sized runs merged in
+// place, or every run merged into the next level.
+func (m *Manifest) tieredCompaction(version *Version, level *Level) *compaction {
+	runs := level.Runs()
+	if len(runs) == 0 {
+		return nil
+	}
+
+	// runs are newest first, grow the window while the next older run is of similar size
+	accumulated := runSize(runs[0])
+	width := 1
+	for ; width < len(runs); width++ {
+		size := runSize(runs[width])
+		if float64(size) > float64(accumulated)*float64(100+m.Tier_size_ratio)/100 {
+			break
+		}
+		accumulated += size
+	}
+
+	output := min(level.Number+1, len(version.Levels)-1)
+	if width >= m.Tier_min_merge_width && width < len(runs) {
+		output = level.Number
+	} else {
+		width = len(runs)
+	}
+	if width < 2 && output == level.Number {
+		// A single run on the last level is already fully merged
+		return nil
+	}
+
+	return newRunsCompaction(version, level.Number, runs[:width], output)
+}
+
+// Returns the compaction merging runs of level into one run of output
+func newRunsCompaction(version *Version, level int, runs [][]*sstable.SSTable, output int) *compaction {
+	inputs := []*sstable.SSTable{}
+	for _, run := range runs {
+		inputs = append(inputs, run...)
+	}
+	c := newCompaction(version, level, inputs, nil)
+	c.output = output
+	c.tiered = true
+	return c
+}
+
+// Merge the runs of c into one run of the output level
+func (man *Manifest) tiered_compact(c *compaction) {
+	slog.Debug("============ Tiered Compaction =============", "level", c.level, "output", c.output, "tables", len(c.inputs))
+
+	// Merge oldest run first so that newer entries win
+	inputs := slices.Clone(c.inputs)
+	slices.SortStableFunc(inputs, func(a, b *sstable.SSTable) int { return cmp.Compare(a.Run, b.Run) })
+	run := inputs[len(inputs)-1].Run
+
+	edit := &VersionEdit{}
+	man.mergeInto(edit, c, inputs...)
+	for _, added := range edit.Added {
+		added.Table.Run = run
+	}
+	for _, tbl := range c.inputs {
+		edit.RemoveTable(tbl, c.level)
+	}
+
+	err := man.LogAndApply(edit)
+	if err != nil {
+		slog.Error("Failed to commit tiered compaction", "level", c.level)
+		panic(err)
+	}
+}
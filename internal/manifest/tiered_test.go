@@ -0,0 +1,193 @@
+package manifest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// Returns a table of run with the given size
+func newRunTable(dir string, first byte, last byte, run uint64, size int64) *sstable.SSTable {
+	table := newEditTable(dir, first, last)
+	table.Run = run
+	table.Size = size
+	return table
+}
+
+func TestLevelRuns(t *testing.T) {
+	tmp := t.TempDir()
+	level := &Level{Number: 0, Tiered: true}
+	old1 := newRunTable(tmp, 10, 19, 1, 100)
+	old2 := newRunTable(tmp, 0, 9, 1, 100)
+	recent := newRunTable(tmp, 5, 15, 2, 100)
+	for _, table := range []*sstable.SSTable{old1, recent, old2} {
+		level.Add(table)
+	}
+
+	runs := level.Runs()
+	if len(runs) != 2 {
+		t.Fatalf("Expected 2 runs, found %v", len(runs))
+	}
+	if len(runs[0]) != 1 || runs[0][0] != recent {
+		t.Error("Expected the newest run first")
+	}
+	if len(runs[1]) != 2 || runs[1][0] != old2 || runs[1][1] != old1 {
+		t.Error("Expected the tables of a run sorted by key")
+	}
+}
+
+func TestAssignMissingRuns(t *testing.T) {
+	tmp := t.TempDir()
+	legacy := newRunTable(tmp, 0, 9, 0, 100)
+	recorded1 := newRunTable(tmp, 0, 9, 7, 100)
+	recorded2 := newRunTable(tmp, 10, 19, 7, 100)
+	recorded3 := newRunTable(tmp, 0, 9, 9, 100)
+	m := &Manifest{Levels: []*Level{{Number: 0}, {Number: 1}}}
+	m.Levels[0].Add(recorded3)
+	m.Levels[1].Add(legacy)
+	m.Levels[1].Add(recorded1)
+	m.Levels[1].Add(recorded2)
+
+	if !m.assignMissingRuns() {
+		t.Fatal("Expected the legacy table to be numbered")
+	}
+	if legacy.Run != 1 {
+		t.Errorf("Expected the legacy table to be the oldest run, found %v", legacy.Run)
+	}
+	if recorded1.Run != 2 || recorded2.Run != 2 || recorded3.Run != 3 {
+		t.Errorf("Expected recorded runs to keep their order, found %v %v %v", recorded1.Run, recorded2.Run, recorded3.Run)
+	}
+	if m.nextRun != 3 {
+		t.Errorf("Expected next run to follow the last numbered run, found %v", m.nextRun)
+	}
+	if m.assignMissingRuns() {
+		t.Error("Expected nothing to number once every table has a run")
+	}
+}
+
+func TestTieredCompaction(t *testing.T) {
+	tmp := t.TempDir()
+
+	t.Run("Test similar runs are merged in place", func(t *testing.T) {
+		l0 := &Level{Number: 0, Tiered: true}
+		l0.Add(newRunTable(tmp, 0, 9, 1, 1000))
+		for run := uint64(2); run <= 4; run++ {
+			l0.Add(newRunTable(tmp, 0, 9, run, 100))
+		}
+		man := newPickerManifest(t, l0, &Level{Number: 1, Tiered: true})
+		man.Tier_run_trigger, man.Tier_size_ratio, man.Tier_min_merge_width = 4, 20, 2
+		version := man.Current()
+		defer version.Unref()
+
+		c := man.tieredCompaction(version, version.Levels[0])
+		if c == nil || c.output != 0 || len(c.inputs) != 3 {
+			t.Fatalf("Expected the 3 small runs merged into level 0, found %+v", c)
+		}
+		for _, input := range c.inputs {
+			if input.Run == 1 {
+				t.Error("The large run should not be merged")
+			}
+		}
+	})
+
+	t.Run("Test whole level is merged down", func(t *testing.T) {
+		l0 := &Level{Number: 0, Tiered: true}
+		for run := uint64(1); run <= 4; run++ {
+			l0.Add(newRunTable(tmp, 0, 9, run, 100))
+		}
+		man := newPickerManifest(t, l0, &Level{Number: 1, Tiered: true})
+		man.Tier_run_trigger, man.Tier_size_ratio, man.Tier_min_merge_width = 4, 20, 2
+		version := man.Current()
+		defer version.Unref()
+
+		if score := man.score(version, version.Levels[0]); score != 1 {
+			t.Errorf("Expected tiered level scored by run count, found %v", score)
+		}
+		c := man.tieredCompaction(version, version.Levels[0])
+		if c == nil || c.output != 1 || len(c.inputs) != 4 {
+			t.Fatalf("Expected every run merged into level 1, found %+v", c)
+		}
+	})
+
+	t.Run("Test single run on the last level", func(t *testing.T) {
+		l1 := &Level{Number: 1, Tiered: true}
+		l1.Add(newRunTable(tmp, 0, 9, 1, 100))
+		man := newPickerManifest(t, &Level{Number: 0, Tiered: true}, l1)
+		man.Tier_run_trigger, man.Tier_size_ratio, man.Tier_min_merge_width = 4, 20, 2
+		version := man.Current()
+		defer version.Unref()
+
+		if c := man.tieredCompaction(version, version.Levels[1]); c != nil {
+			t.Errorf("Expected no compaction, found %+v", c)
+		}
+	})
+}
+
+func TestTieredManifest(t *testing.T) {
+	opts := newWorkloadOpts(t, t.TempDir())
+	opts.Compaction_style = TIERED
+	opts.Tier_run_trigger = 3
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every flush overwrites the same keys, the last flush deletes key 0
+	const flushes = 10
+	for flush := 0; flush < flushes; flush++ {
+		entries := []*pb.SSTable_Entry{}
+		for key := 0; key < 50; key++ {
+			entry := &pb.SSTable_Entry{Key: []byte(fmt.Sprintf("%08d", key)), Value: []byte(fmt.Sprintf("value %d", flush)), Op: pb.Operation_OPERATION_INSERT}
+			if flush == flushes-1 && key == 0 {
+				entry.Op = pb.Operation_OPERATION_DELETE
+				entry.Value = []byte{}
+			}
+			entries = append(entries, entry)
+		}
+		if err := man.AddTable(newFlushTable(t, opts, entries), 0); err != nil {
+			t.Fatal(err)
+		}
+		waitForIdle(t, man)
+	}
+	if man.CompactionStats().Compactions == 0 {
+		t.Fatal("Expected runs to be compacted")
+	}
+
+	check := func(t *testing.T, man *Manifest) {
+		val, err := man.Search([]byte(fmt.Sprintf("%08d", 1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := fmt.Sprintf("value %d", flushes-1); string(val) != expected {
+			t.Errorf("Expected the newest value %q, found %q", expected, val)
+		}
+		if val, err := man.Search([]byte(fmt.Sprintf("%08d", 0))); len(val) != 0 || (err != nil && err != ErrNotFound) {
+			t.Errorf("Expected the deleted key to be hidden, found %q %v", val, err)
+		}
+		version := man.Current()
+		defer version.Unref()
+		for _, level := range version.Levels {
+			if runs := len(level.Runs()); runs >= opts.Tier_run_trigger {
+				t.Errorf("Expected level %v to be compacted, found %v runs", level.Number, runs)
+			}
+		}
+	}
+
+	t.Run("Test search returns newest values", func(t *testing.T) {
+		check(t, man)
+	})
+
+	t.Run("Test runs survive replay", func(t *testing.T) {
+		if err := man.Close(); err != nil {
+			t.Fatal(err)
+		}
+		man, err = New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, man)
+	})
+	man.Close()
+}
@@ -0,0 +1,146 @@
+package manifest
+
+import (
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// Version is an immutable snapshot of the level layout.
+//
+// A new version is installed after every applied VersionEdit. Readers take a reference
+// to the current version without locking, so a compaction never blocks them, and the
+// files of a removed table are only deleted once every version referencing it is released.
+type Version struct {
+	Levels   []*Level     // Level layout, must not be modified
+	base     int          // Level that L0 compacts into
+	refs     atomic.Int32 // The manifest holds one reference while the version is current
+	manifest *Manifest    // Notified when the last reference is released
+}
+
+func newVersion(m *Manifest, levels []*Level) *Version {
+	v := &Version{Levels: make([]*Level, len(levels)), base: m.baseLevel, manifest: m}
+	for i, level := range levels {
+		clone := *level
+		clone.Tables = slices.Clone(level.Tables)
+		for _, table := range clone.Tables {
+			if table.Cache == nil && m.Block_cache != nil {
+				// Lookups reach a table through a version, so it is not read before its first one
+				table.Cache = m.Block_cache
+			}
+		}
+		if clone.Tiered {
+			clone.runs = clone.Runs()
+		}
+		v.Levels[i] = &clone
+	}
+	v.refs.Store(1)
+	return v
+}
+
+// Takes a reference unless the version has already been released
+func (v *Version) tryRef() bool {
+	for {
+		refs := v.refs.Load()
+		if refs <= 0 {
+			return false
+		}
+		if v.refs.CompareAndSwap(refs, refs+1) {
+			return true
+		}
+	}
+}
+
+// Unref releases a reference returned by Manifest.Current
+func (v *Version) Unref() {
+	if v.refs.Add(-1) == 0 {
+		v.manifest.releaseVersion(v)
+	}
+}
+
+// Current returns the live version with a reference held.
+//
+// *** You must call Unref() once done with the version
+func (m *Manifest) Current() *Version {
+	for {
+		v := m.current.Load()
+		if v.tryRef() {
+			return v
+		}
+	}
+}
+
+// Publishes the working levels as the current version. Caller must hold m.mut.
+func (m *Manifest) installVersion() {
+	m.updateLevels()
+	v := newVersion(m, m.Levels)
+	m.versionsMut.Lock()
+	m.versions[v] = struct{}{}
+	m.versionsMut.Unlock()
+	if old := m.current.Swap(v); old != nil {
+		old.Unref()
+	}
+	m.wake()
+}
+
+// Forgets a version once nothing references it and wakes the obsolete file collector
+func (m *Manifest) releaseVersion(v *Version) {
+	m.versionsMut.Lock()
+	delete(m.versions, v)
+	m.versionsMut.Unlock()
+	select {
+	case m.versionReleased <- struct{}{}:
+	default:
+	}
+}
+
+// TablesInRange returns the tables of the version that may hold keys in [start, end),
+// oldest first as sstable.MergeRange expects. Tables whose bounds or range filter rule the
+// interval out are skipped. A nil end leaves the interval open.
+func (v *Version) TablesInRange(start []byte, end []byte) []*sstable.SSTable {
+	tables := []*sstable.SSTable{}
+	add := func(candidates []*sstable.SSTable) {
+		for _, table := range candidates {
+			if table.MayContainRange(start, end) {
+				tables = append(tables, table)
+			}
+		}
+	}
+	// Lower levels hold older data
+	for n := len(v.Levels) - 1; n >= 0; n-- {
+		level := v.Levels[n]
+		if !level.Tiered {
+			// L0 tables are in flush order, the tables of other levels do not overlap
+			add(level.Tables)
+			continue
+		}
+		runs := level.runs
+		if runs == nil {
+			runs = level.Runs()
+		}
+		for i := len(runs) - 1; i >= 0; i-- {
+			add(runs[i])
+		}
+	}
+	return tables
+}
+
+// ApproximateRange estimates the bytes and the number of entries of the version in
+// [start, end) from the bounds and indexes of its tables. Keys written in several tables
+// are counted once per table. A nil start or end leaves that side open.
+func (v *Version) ApproximateRange(start []byte, end []byte) (int64, int64, error) {
+	var size, count int64
+	for _, level := range v.Levels {
+		for _, table := range level.Tables {
+			tableSize, tableCount, err := table.ApproximateRange(start, end)
+			if err != nil {
+				return 0, 0, fmt.Errorf("table.ApproximateRange: %w", err)
+			}
+			size += tableSize
+			count += tableCount
+		}
+	}
+	return size, count, nil
+}
@@ -0,0 +1,93 @@
+package manifest
+
+import (
+	"fmt"
+
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+// A table added to or removed from a level
+type LevelTable struct {
+	Level int
+	Table *sstable.SSTable
+}
+
+// VersionEdit bundles every table added and removed by one flush or compaction.
+//
+// It is written to the manifest as a single record, so after a crash either all of
+// its changes are replayed or none of them are.
+type VersionEdit struct {
+	Added   []LevelTable
+	Removed []LevelTable
+}
+
+// Record table as added to level
+func (edit *VersionEdit) AddTable(table *sstable.SSTable, level int) {
+	edit.Added = append(edit.Added, LevelTable{Level: level, Table: table})
+}
+
+// Record table as removed from level
+func (edit *VersionEdit) RemoveTable(table *sstable.SSTable, level int) {
+	edit.Removed = append(edit.Removed, LevelTable{Level: level, Table: table})
+}
+
+// Returns true if the edit contains no changes
+func (edit *VersionEdit) Empty() bool {
+	return len(edit.Added) == 0 && len(edit.Removed) == 0
+}
+
+// Removals are applied before additions so that a table can move between levels
+func (edit *VersionEdit) applyTo(levels []*Level) error {
+	for _, change := range edit.Removed {
+		if change.Level >= len(levels) {
+			return fmt.Errorf("remove %v: level %v does not exist", change.Table.Name, change.Level)
+		}
+		levels[change.Level].Remove(change.Table)
+	}
+	for _, change := range edit.Added {
+		if change.Level >= len(levels) {
+			return fmt.Errorf("add %v: level %v does not exist", change.Table.Name, change.Level)
+		}
+		levels[change.Level].Add(change.Table)
+	}
+	return nil
+}
+
+func (edit *VersionEdit) ToProto() (*pb.VersionEdit, error) {
+	p := &pb.VersionEdit{}
+	for _, change := range edit.Added {
+		pto, err := change.Table.ToProto()
+		if err != nil {
+			return nil, err
+		}
+		p.Added = append(p.Added, &pb.VersionEdit_LevelTable{Level: int32(change.Level), Table: pto})
+	}
+	for _, change := range edit.Removed {
+		pto, err := change.Table.ToProto()
+		if err != nil {
+			return nil, err
+		}
+		p.Removed = append(p.Removed, &pb.VersionEdit_LevelTable{Level: int32(change.Level), Table: pto})
+	}
+	return p, nil
+}
+
+func VersionEditFromProto(p *pb.VersionEdit) (*VersionEdit, error) {
+	edit := &VersionEdit{}
+	for _, change := range p.GetAdded() {
+		table, err := sstable.FromProto(change.GetTable())
+		if err != nil {
+			return nil, err
+		}
+		edit.AddTable(table, int(change.GetLevel()))
+	}
+	for _, change := range p.GetRemoved() {
+		table, err := sstable.FromProto(change.GetTable())
+		if err != nil {
+			return nil, err
+		}
+		edit.RemoveTable(table, int(change.GetLevel()))
+	}
+	return edit, nil
+}
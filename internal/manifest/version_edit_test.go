@@ -0,0 +1,78 @@
+package manifest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+func newEditTable(dir string, first byte, last byte) *sstable.SSTable {
+	return &sstable.SSTable{
+		Name:      filepath.Join(dir, sstable.GenerateUniqueSegmentName(time.Now())),
+		First:     []byte{first},
+		Last:      []byte{last},
+		Size:      100,
+		CreatedOn: time.Now(),
+		Filter:    filter.Open(filter.Metadata{Name: filepath.Join(dir, filter.GenerateUniqueBloomName()), Size: 100}),
+	}
+}
+
+func TestVersionEditApply(t *testing.T) {
+	tmp := t.TempDir()
+	levels := []*Level{{Number: 0}, {Number: 1}}
+	t1 := newEditTable(tmp, 0, 9)
+	t2 := newEditTable(tmp, 10, 19)
+	levels[0].Add(t1)
+
+	// Move t1 down a level and add t2 in the same edit
+	edit := &VersionEdit{}
+	edit.RemoveTable(t1, 0)
+	edit.AddTable(t1, 1)
+	edit.AddTable(t2, 1)
+	err := edit.applyTo(levels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(levels[0].Tables) != 0 || levels[0].Size != 0 {
+		t.Error("Level 0 should be empty")
+	}
+	if len(levels[1].Tables) != 2 || levels[1].Size != 200 {
+		t.Errorf("Level 1 should contain 2 tables, found %v", len(levels[1].Tables))
+	}
+
+	err = (&VersionEdit{Added: []LevelTable{{Level: 5, Table: t1}}}).applyTo(levels)
+	if err == nil {
+		t.Error("Should not apply edit to a level that does not exist")
+	}
+}
+
+func TestVersionEditProto(t *testing.T) {
+	tmp := t.TempDir()
+	edit := &VersionEdit{}
+	edit.AddTable(newEditTable(tmp, 0, 9), 1)
+	edit.AddTable(newEditTable(tmp, 10, 19), 1)
+	edit.RemoveTable(newEditTable(tmp, 0, 19), 0)
+
+	pto, err := edit.ToProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := VersionEditFromProto(pto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Added) != 2 || len(decoded.Removed) != 1 {
+		t.Fatalf("Expected 2 added and 1 removed, found %v and %v", len(decoded.Added), len(decoded.Removed))
+	}
+	for i, change := range decoded.Added {
+		if change.Level != 1 || change.Table.Name != edit.Added[i].Table.Name || !change.Table.CreatedOn.Equal(edit.Added[i].Table.CreatedOn) {
+			t.Errorf("Added table %v does not match", i)
+		}
+	}
+	if decoded.Removed[0].Level != 0 || decoded.Removed[0].Table.Name != edit.Removed[0].Table.Name {
+		t.Error("Removed table does not match")
+	}
+}
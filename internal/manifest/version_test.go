@@ -0,0 +1,162 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dillonkmcquade/gostore/internal/cache"
+	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
+)
+
+func TestVersionSnapshot(t *testing.T) {
+	tmp := t.TempDir()
+	man, err := New(newObsoleteOpts(tmp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer man.Close()
+
+	before := man.Current()
+	err = man.AddTable(newEditTable(tmp, 0, 9), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := man.Current()
+	defer after.Unref()
+
+	if len(before.Levels[0].Tables) != 0 {
+		t.Error("A version should not change after it is installed")
+	}
+	if len(after.Levels[0].Tables) != 1 {
+		t.Errorf("Current version should contain the added table, found %v tables", len(after.Levels[0].Tables))
+	}
+
+	before.Unref()
+	if before.tryRef() {
+		t.Error("A released version should not be referenced again")
+	}
+}
+
+func TestVersionBlockCache(t *testing.T) {
+	opts := newWorkloadOpts(t, t.TempDir())
+	opts.Block_cache = cache.New(1 << 20)
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer man.Close()
+
+	table := newKeysTable(t, opts, 0, 100)
+	if err := man.AddTable(table, 0); err != nil {
+		t.Fatal(err)
+	}
+	if table.Cache != opts.Block_cache {
+		t.Fatal("Expected an installed table to use the block cache")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := man.Search([]byte(fmt.Sprintf("%08d", 42))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if opts.Block_cache.Hits() == 0 {
+		t.Error("Expected the second search to read cached blocks")
+	}
+}
+
+func TestSearchDoesNotBlockOnManifestLock(t *testing.T) {
+	tmp := t.TempDir()
+	opts := newObsoleteOpts(tmp)
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer man.Close()
+
+	table := newEditTable(opts.LevelPaths[0], 1, 1)
+	table.Entries = []*pb.SSTable_Entry{{Op: pb.Operation_OPERATION_INSERT, Key: []byte{1}, Value: []byte("value")}}
+	table.Filter = filter.New(&filter.Opts{Size: 100})
+	table.Filter.Add([]byte{1})
+	if _, err = table.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err = man.AddTable(table, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a long running edit
+	man.mut.Lock()
+	defer man.mut.Unlock()
+
+	result := make(chan error, 1)
+	go func() {
+		val, err := man.Search([]byte{1})
+		if err == nil && string(val) != "value" {
+			err = errors.New("unexpected value " + string(val))
+		}
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Search blocked on the manifest lock")
+	}
+}
+
+func TestTablesInRange(t *testing.T) {
+	tmp := t.TempDir()
+	opts := newObsoleteOpts(tmp)
+	opts.Range_bits_per_prefix = 16
+	man, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer man.Close()
+
+	newTable := func(level int, keys ...int) *sstable.SSTable {
+		entries := []*pb.SSTable_Entry{}
+		for _, key := range keys {
+			entries = append(entries, &pb.SSTable_Entry{Key: []byte(fmt.Sprintf("%04d", key)), Value: []byte("value"), Op: pb.Operation_OPERATION_INSERT})
+		}
+		table := sstable.New(&sstable.Opts{BloomOpts: &filter.Opts{}, RangeOpts: man.rangeOpts(), DestDir: opts.LevelPaths[level], Entries: entries})
+		table.First, table.Last = entries[0].Key, entries[len(entries)-1].Key
+		if _, err := table.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		if err := man.AddTable(table, level); err != nil {
+			t.Fatal(err)
+		}
+		return table
+	}
+	lower := newTable(1, 0, 10, 20, 30, 40, 50, 60, 70, 80, 90)
+	upper := newTable(0, 5, 25)
+
+	tests := []struct {
+		name     string
+		start    int
+		end      int
+		expected []*sstable.SSTable
+	}{
+		{name: "Test gap in every table", start: 1, end: 5, expected: []*sstable.SSTable{}},
+		{name: "Test older table first", start: 5, end: 11, expected: []*sstable.SSTable{lower, upper}},
+		{name: "Test one table", start: 60, end: 61, expected: []*sstable.SSTable{lower}},
+		{name: "Test after every table", start: 91, end: 99, expected: []*sstable.SSTable{}},
+	}
+	version := man.Current()
+	defer version.Unref()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables := version.TablesInRange([]byte(fmt.Sprintf("%04d", tt.start)), []byte(fmt.Sprintf("%04d", tt.end)))
+			if !slices.Equal(tables, tt.expected) {
+				t.Errorf("Expected %v tables, found %v", len(tt.expected), len(tables))
+			}
+		})
+	}
+}
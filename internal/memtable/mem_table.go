@@ -2,17 +2,21 @@ package memtable
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"hash/maphash"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"slices"
 	"sync"
+	"sync/atomic"
 
 	"github.com/dillonkmcquade/gostore/internal/filter"
 	"github.com/dillonkmcquade/gostore/internal/ordered"
 	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/ratelimit"
 	"github.com/dillonkmcquade/gostore/internal/sstable"
 	"github.com/dillonkmcquade/gostore/internal/wal"
 	"google.golang.org/protobuf/proto"
@@ -25,61 +29,134 @@ type MemTable interface {
 	Get([]byte) ([]byte, bool) // Get returns a value associated with the key
 	Delete([]byte)             // Insert a node marked as delete
 	Size() uint                // Number of entries
+	MemoryUsage() int64        // Approximate bytes used by keys, values and node overhead
 	Clear()                    // Wipe the memtable
+	Flush() uint64             // Flush the memtable even if it is not full. Returns the number of tables flushed so far
+
+	ApproximateRange(start []byte, end []byte) (int64, int64) // Key and value bytes and number of entries in [start, end)
 
 	FlushedTables() <-chan *sstable.SSTable
 }
 
+// Ordered data structure backing the memtable
+type CollectionType byte
+
+const (
+	REDBLACKTREE CollectionType = iota // Writes are serialized
+	SKIPLIST                           // Concurrent inserts and wait-free reads
+)
+
 type GostoreMemTable struct {
-	rbt       ordered.Collection[[]byte, *pb.SSTable_Entry] // Ordered in-memory data structure
-	wal       *wal.WAL[*pb.SSTable_Entry]                   // Log of all rbt operations
-	max_size  uint                                          // Max number of elements before flushing
-	bloomOpts *filter.Opts                                  // Opts for creating a filter when a new table is created
-	level0Dir string                                        // Path to l0 directory
-	flushChan chan *sstable.SSTable                         // Flushed sstables that have not been added to L0 yet
-	writeChan chan *pb.SSTable_Entry                        // Process incoming write/delete requests
-	mut       sync.RWMutex
-	wg        sync.WaitGroup
+	table      ordered.Collection[[]byte, *pb.SSTable_Entry] // Ordered in-memory data structure
+	concurrent bool                                          // table supports concurrent inserts and lock-free reads
+	wal        *wal.WAL[*pb.SSTable_Entry]                   // Log of all table operations
+	max_size   int64                                         // Max approximate memory usage in bytes before flushing
+	usage      atomic.Int64                                  // Approximate memory usage in bytes
+	wbm        *WriteBufferManager                           // Shared cap on memory across memtables, may be nil
+	bloomOpts  *filter.Opts                                  // Opts for creating a filter when a new table is created
+	rangeOpts  *filter.RangeOpts                             // Opts for creating the range filter of a new table, nil for none
+	level0Dir  string                                        // Path to l0 directory
+	flushChan  chan *sstable.SSTable                         // Flushed sstables that have not been added to L0 yet
+	flushes    atomic.Uint64                                 // Number of tables sent over flushChan
+	limiter    *ratelimit.RateLimiter                        // Throttles flushes, may be nil
+	collectors []sstable.TablePropertiesCollectorFactory     // Add custom properties to flushed tables
+	nextRun    func() uint64                                 // Reserves the run of a flushed table, may be nil
+	closed     bool                                          // Set by Close, guarded by mut
+	mut        sync.RWMutex                                  // Held exclusively while flushing
+
+	keySeed  maphash.Seed               // Hashes keys to their key lock
+	keyLocks [keyLockStripes]sync.Mutex // Concurrent writers of the same key hold its lock
 }
 type Opts struct {
-	Batch_write_size int
-	WalPath          string
-	Max_size         uint
-	FilterOpts       *filter.Opts
-	LevelZero        string
+	Batch_write_size   int
+	WalPath            string
+	Max_size           int64 // Max approximate memory usage in bytes before flushing
+	FilterOpts         *filter.Opts
+	RangeFilterOpts    *filter.RangeOpts // Range filter of flushed tables. Optional
+	LevelZero          string
+	Collection         CollectionType         // Defaults to REDBLACKTREE
+	WriteBufferManager *WriteBufferManager    // Optional cap on memory shared with other memtables
+	Rate_limiter       *ratelimit.RateLimiter // Optional limit on flush I/O, may be shared with compactions
+	Next_run           func() uint64          // Reserves the run of each flushed table, recorded in its properties. Optional
+
+	Properties_collectors []sstable.TablePropertiesCollectorFactory // Add custom properties to flushed tables. Optional
+}
+
+// Approximate per-entry memory overhead in bytes: the pb.SSTable_Entry struct,
+// the pointer to it, and the collection node holding it.
+const entryOverhead = 160
+
+// Returns the approximate memory used by entry once inserted in the table
+func entrySize(entry *pb.SSTable_Entry) int64 {
+	return int64(len(entry.Key) + len(entry.Value) + entryOverhead)
 }
 
+// Returns the collection selected in opts, and whether it is safe for concurrent writers
+func newCollection(collection CollectionType) (ordered.Collection[[]byte, *pb.SSTable_Entry], bool) {
+	switch collection {
+	case SKIPLIST:
+		return ordered.NewSkipList[[]byte, *pb.SSTable_Entry](slices.Compare[[]byte]), true
+	default:
+		return ordered.Rbt[[]byte, *pb.SSTable_Entry](slices.Compare[[]byte]), false
+	}
+}
+
+// Number of locks the keys of a concurrent memtable are striped across
+const keyLockStripes = 64
+
 func New(opts *Opts) (MemTable, error) {
 	wal, err := wal.New[*pb.SSTable_Entry](opts.WalPath, opts.Batch_write_size)
 	if err != nil {
 		return nil, fmt.Errorf("newWal: %w", err)
 	}
+	table, concurrent := newCollection(opts.Collection)
 	memtable := &GostoreMemTable{
-		rbt:       ordered.Rbt[[]byte, *pb.SSTable_Entry](slices.Compare[[]byte]),
-		max_size:  opts.Max_size,
-		wal:       wal,
-		bloomOpts: opts.FilterOpts,
-		level0Dir: opts.LevelZero,
-		writeChan: make(chan *pb.SSTable_Entry),
-		flushChan: make(chan *sstable.SSTable),
+		table:      table,
+		concurrent: concurrent,
+		max_size:   opts.Max_size,
+		wbm:        opts.WriteBufferManager,
+		wal:        wal,
+		bloomOpts:  opts.FilterOpts,
+		rangeOpts:  opts.RangeFilterOpts,
+		level0Dir:  opts.LevelZero,
+		flushChan:  make(chan *sstable.SSTable),
+		limiter:    opts.Rate_limiter,
+		collectors: opts.Properties_collectors,
+		nextRun:    opts.Next_run,
+		keySeed:    maphash.MakeSeed(),
 	}
 	err = memtable.replay(opts.WalPath)
 	if err != nil {
 		return nil, err
 	}
-	go memtable.processWrites()
+	if memtable.wbm != nil {
+		memtable.wbm.register(memtable)
+	}
 	return memtable, nil
 }
 
-// Write memTable to disk as SSTable
-func (mem *GostoreMemTable) flush() {
-	slog.Debug("Flushing")
-	if !mem.shouldFlush() {
-		slog.Warn("Attempt to flush memtable that should not flush")
+// Write memTable to disk as SSTable. Unless force is set, only flushes if the memtable is full.
+func (mem *GostoreMemTable) flush(force bool) {
+	mem.mut.Lock()
+	defer mem.mut.Unlock()
+	if mem.closed {
+		// A write buffer manager picked the memtable before it was closed
+		return
+	}
+	if !force && !mem.shouldFlush() {
+		// Another writer flushed first
 		return
 	}
+	if mem.table.Size() == 0 {
+		return
+	}
+	slog.Debug("Flushing")
 	// create sstable
 	snapshot := mem.Snapshot()
+	if mem.nextRun != nil {
+		// Flushes are serialized, so runs follow the order tables are added to L0
+		snapshot.Run = mem.nextRun()
+	}
 
 	// save to file
 	_, err := snapshot.Sync()
@@ -87,15 +164,10 @@ func (mem *GostoreMemTable) flush() {
 		slog.Error("flush: error syncing snapshot", "filename", snapshot.Name)
 		panic(err)
 	}
-	err = snapshot.SaveFilter()
-	if err != nil {
-		slog.Error("flush: error saving filter", "filename", snapshot.Filter.Name)
-		panic(err)
-	}
 
 	slog.Debug("Sending snapshot over flushChan")
+	mem.flushes.Add(1)
 	mem.flushChan <- snapshot
-	mem.wg.Done()
 
 	// Discard memTable & write-ahead log
 	mem.Clear()
@@ -104,7 +176,7 @@ func (mem *GostoreMemTable) flush() {
 // Restores database state from Write-Ahead-Log
 func (mem *GostoreMemTable) replay(filename string) error {
 	path := filepath.Clean(filename)
-	mem.rbt.Clear()
+	mem.table.Clear()
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("os.Open: %w", err)
@@ -119,11 +191,12 @@ func (mem *GostoreMemTable) replay(filename string) error {
 		if err != nil {
 			return fmt.Errorf("proto.Unmarshal: %w", err)
 		}
-		err = e.Apply(mem.rbt)
+		err = e.Apply(mem.table)
 		if err != nil {
 			slog.Error("log apply error", "cause", err)
 			return &wal.LogApplyErr{Cause: err}
 		}
+		mem.reserve(entrySize(&e))
 
 	}
 	if err := scanner.Err(); err != nil {
@@ -133,65 +206,134 @@ func (mem *GostoreMemTable) replay(filename string) error {
 	return nil
 }
 
+// Tables are received from FlushedTables in flush order, so every table flushed before
+// Flush returns has been received once the returned number of tables has.
+func (mem *GostoreMemTable) Flush() uint64 {
+	mem.flush(true)
+	return mem.flushes.Load()
+}
+
 func (mem *GostoreMemTable) FlushedTables() <-chan *sstable.SSTable {
 	return mem.flushChan
 }
 
 // Returns an SSTable filled with entries, with no size
 func (mem *GostoreMemTable) Snapshot() *sstable.SSTable {
+	entries := make([]*pb.SSTable_Entry, 0, mem.table.Size())
+	for node := range mem.table.Values() {
+		entries = append(entries, node)
+	}
 	sstable := sstable.New(&sstable.Opts{
-		DestDir:   mem.level0Dir,
-		BloomOpts: mem.bloomOpts,
-		Entries:   make([]*pb.SSTable_Entry, 0, mem.rbt.Size()),
+		DestDir:    mem.level0Dir,
+		BloomOpts:  mem.bloomOpts,
+		RangeOpts:  mem.rangeOpts,
+		Entries:    entries,
+		Limiter:    mem.limiter,
+		Collectors: mem.collectors,
 	})
-
-	for node := range mem.rbt.Values() {
-		sstable.Entries = append(sstable.Entries, node)
-		sstable.Filter.Add(node.Key)
-	}
 	sstable.First = sstable.Entries[0].Key
 	sstable.Last = sstable.Entries[len(sstable.Entries)-1].Key
 	return sstable
 }
 
-func (mem *GostoreMemTable) processWrites() {
-	for entry := range mem.writeChan {
+// Acquire the lock required to insert into the table
+func (mem *GostoreMemTable) lockWrites() {
+	if mem.concurrent {
+		mem.mut.RLock()
+	} else {
 		mem.mut.Lock()
-		mem.rbt.Put(entry.Key, entry)
-		err := mem.wal.Write(entry)
-		if err != nil {
-			panic(fmt.Errorf("wal.Write: %w", err))
-		}
-		if mem.shouldFlush() {
-			mem.wg.Add(1)
-			mem.flush()
-		}
-		mem.wg.Done()
+	}
+}
+
+func (mem *GostoreMemTable) unlockWrites() {
+	if mem.concurrent {
+		mem.mut.RUnlock()
+	} else {
 		mem.mut.Unlock()
 	}
 }
 
+// Serializes concurrent writers of key and returns the func releasing it. Writes of the
+// same key must reach the log and the table in the same order, or replay would restore a
+// value that readers did not see last. Writers of a serialized table already hold mut.
+func (mem *GostoreMemTable) lockKey(key []byte) func() {
+	if !mem.concurrent {
+		return func() {}
+	}
+	keyLock := &mem.keyLocks[maphash.Bytes(mem.keySeed, key)%keyLockStripes]
+	keyLock.Lock()
+	return keyLock.Unlock
+}
+
+// Log entry and insert it into the table, flushing if the table or the write buffer is full
+func (mem *GostoreMemTable) write(entry *pb.SSTable_Entry) error {
+	mem.lockWrites()
+	unlockKey := mem.lockKey(entry.Key)
+	err := mem.wal.Write(entry)
+	if err != nil {
+		unlockKey()
+		mem.unlockWrites()
+		return fmt.Errorf("wal.Write: %w", err)
+	}
+	mem.table.Put(entry.Key, entry)
+	mem.reserve(entrySize(entry))
+	full := mem.shouldFlush()
+	unlockKey()
+	mem.unlockWrites()
+	if full {
+		mem.flush(false)
+	}
+	if mem.wbm != nil && mem.wbm.exceeded() {
+		mem.wbm.flushLargest()
+	}
+	return nil
+}
+
+// Charge bytes against this memtable and its write buffer manager. Nothing is charged once
+// the memtable is closed, its usage has been freed. Caller must hold the write lock.
+//
+// Overwrites are charged in full, the same way an append-only arena would be.
+func (mem *GostoreMemTable) reserve(bytes int64) {
+	if mem.closed {
+		return
+	}
+	mem.usage.Add(bytes)
+	if mem.wbm != nil {
+		mem.wbm.reserve(bytes)
+	}
+}
+
+// Release everything charged against this memtable
+func (mem *GostoreMemTable) release() {
+	bytes := mem.usage.Swap(0)
+	if mem.wbm != nil {
+		mem.wbm.free(bytes)
+	}
+}
+
 func (mem *GostoreMemTable) shouldFlush() bool {
-	return mem.rbt.Size() >= mem.max_size
+	return mem.usage.Load() >= mem.max_size
 }
 
 func (mem *GostoreMemTable) Put(key []byte, val []byte) error {
 	entry := &pb.SSTable_Entry{Key: key, Value: val, Op: pb.Operation_OPERATION_INSERT}
-	mem.wg.Add(1)
-	mem.writeChan <- entry
-	return nil
+	return mem.write(entry)
 }
 
 func (mem *GostoreMemTable) Delete(key []byte) {
 	placeholder := &pb.SSTable_Entry{Key: key, Value: []byte{}, Op: pb.Operation_OPERATION_DELETE}
-	mem.wg.Add(1)
-	mem.writeChan <- placeholder
+	err := mem.write(placeholder)
+	if err != nil {
+		panic(err)
+	}
 }
 
 func (mem *GostoreMemTable) Get(key []byte) ([]byte, bool) {
-	mem.mut.RLock()
-	defer mem.mut.RUnlock()
-	if entry, found := mem.rbt.Get(key); found {
+	if !mem.concurrent {
+		mem.mut.RLock()
+		defer mem.mut.RUnlock()
+	}
+	if entry, found := mem.table.Get(key); found {
 		if entry.Op == pb.Operation_OPERATION_DELETE {
 			return []byte{}, false
 		}
@@ -203,11 +345,33 @@ func (mem *GostoreMemTable) Get(key []byte) ([]byte, bool) {
 func (mem *GostoreMemTable) Size() uint {
 	mem.mut.RLock()
 	defer mem.mut.RUnlock()
-	return mem.rbt.Size()
+	return mem.table.Size()
+}
+
+// ApproximateRange returns the key and value bytes and the number of entries with keys
+// in [start, end), deletes included. A nil start or end leaves that side open.
+func (mem *GostoreMemTable) ApproximateRange(start []byte, end []byte) (int64, int64) {
+	mem.mut.RLock()
+	defer mem.mut.RUnlock()
+	var size, count int64
+	for entry := range mem.table.Values() {
+		// The traversal is drained so that it does not block
+		if bytes.Compare(entry.Key, start) < 0 || (end != nil && bytes.Compare(entry.Key, end) >= 0) {
+			continue
+		}
+		size += int64(len(entry.Key) + len(entry.Value))
+		count++
+	}
+	return size, count
+}
+
+func (mem *GostoreMemTable) MemoryUsage() int64 {
+	return mem.usage.Load()
 }
 
 func (mem *GostoreMemTable) Clear() {
-	mem.rbt.Clear()
+	mem.table.Clear()
+	mem.release()
 	err := mem.wal.Discard()
 	if err != nil {
 		panic(err)
@@ -215,8 +379,16 @@ func (mem *GostoreMemTable) Clear() {
 }
 
 func (mem *GostoreMemTable) Close() error {
-	mem.wg.Wait()
-	close(mem.writeChan)
+	// Wait for in-flight writes and flushes, later writes are not charged
+	mem.mut.Lock()
+	mem.closed = true
+	mem.mut.Unlock()
+	if mem.wbm != nil {
+		mem.wbm.unregister(mem)
+		mem.wbm.free(mem.usage.Swap(0))
+	}
+	mem.mut.Lock()
+	defer mem.mut.Unlock()
 	close(mem.flushChan)
 	if err := mem.wal.Close(); err != nil {
 		return err
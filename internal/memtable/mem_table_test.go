@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"path/filepath"
 	"slices"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/sstable"
 )
 
 func TestNewMemTable(t *testing.T) {
@@ -36,7 +38,7 @@ func TestMemTableCRUD(t *testing.T) {
 	mem, err := New(&Opts{
 		Batch_write_size: 10,
 		WalPath:          wal,
-		Max_size:         2000,
+		Max_size:         1 << 20,
 		LevelZero:        filepath.Join(tmp, "l0"),
 		FilterOpts: &filter.Opts{
 			Path: filepath.Join(tmp, "filters"),
@@ -120,7 +122,7 @@ func TestMemTableIO(t *testing.T) {
 	mem, err := New(&Opts{
 		Batch_write_size: 10,
 		WalPath:          wal,
-		Max_size:         1000,
+		Max_size:         1 << 20,
 		LevelZero:        filepath.Join(tmp, "l0"),
 		FilterOpts: &filter.Opts{
 			Path: filepath.Join(tmp, "filters"),
@@ -143,7 +145,7 @@ func TestMemTableIO(t *testing.T) {
 	t.Run("Test Replay", func(t *testing.T) {
 		mem2, err := New(&Opts{
 			WalPath:          wal,
-			Max_size:         1000,
+			Max_size:         1 << 20,
 			Batch_write_size: 10,
 			LevelZero:        filepath.Join(tmp, "l0"),
 			FilterOpts: &filter.Opts{
@@ -182,3 +184,179 @@ func TestMemTableIO(t *testing.T) {
 	// 	}
 	// })
 }
+
+func TestMemTableSkipList(t *testing.T) {
+	tmp := t.TempDir()
+
+	mem, err := New(&Opts{
+		Batch_write_size: 10,
+		WalPath:          filepath.Join(tmp, "wal.dat"),
+		Max_size:         1 << 20,
+		LevelZero:        filepath.Join(tmp, "l0"),
+		Collection:       SKIPLIST,
+		FilterOpts: &filter.Opts{
+			Path: filepath.Join(tmp, "filters"),
+			Size: 1000,
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	defer mem.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := mem.Put([]byte(fmt.Sprintf("%v", i)), []byte("TESTVALUE"))
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if mem.Size() != 100 {
+		t.Errorf("Size should be 100, found %v", mem.Size())
+	}
+	for i := 0; i < 100; i++ {
+		val, found := mem.Get([]byte(fmt.Sprintf("%v", i)))
+		if !found || slices.Compare(val, []byte("TESTVALUE")) != 0 {
+			t.Errorf("Should be found: %v", i)
+		}
+	}
+
+	mem.Delete([]byte(fmt.Sprintf("%v", 0)))
+	if _, found := mem.Get([]byte(fmt.Sprintf("%v", 0))); found {
+		t.Errorf("Should have been deleted: %v", 0)
+	}
+}
+
+func TestMemTableSkipListReplayOrder(t *testing.T) {
+	tmp := t.TempDir()
+	opts := &Opts{
+		Batch_write_size: 10,
+		WalPath:          filepath.Join(tmp, "wal.dat"),
+		Max_size:         1 << 20,
+		LevelZero:        filepath.Join(tmp, "l0"),
+		Collection:       SKIPLIST,
+		FilterOpts: &filter.Opts{
+			Path: filepath.Join(tmp, "filters"),
+			Size: 1000,
+		},
+	}
+	mem, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent writes of the same keys race between the table and the log
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := mem.Put([]byte(fmt.Sprintf("%v", j%2)), []byte(fmt.Sprintf("%v-%v", i, j))); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	expected := map[string][]byte{}
+	for key := 0; key < 2; key++ {
+		expected[fmt.Sprintf("%v", key)], _ = mem.Get([]byte(fmt.Sprintf("%v", key)))
+	}
+	if err := mem.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayed.Close()
+	for key, value := range expected {
+		if found, _ := replayed.Get([]byte(key)); !slices.Equal(found, value) {
+			t.Errorf("Expected replay to restore %s for key %v, found %s", value, key, found)
+		}
+	}
+}
+
+func TestMemTableApproximateRange(t *testing.T) {
+	for _, collection := range []CollectionType{REDBLACKTREE, SKIPLIST} {
+		t.Run(fmt.Sprintf("Test collection %v", collection), func(t *testing.T) {
+			tmp := t.TempDir()
+			mem, err := New(&Opts{
+				Batch_write_size: 10,
+				WalPath:          filepath.Join(tmp, "wal.dat"),
+				Max_size:         1 << 20,
+				LevelZero:        filepath.Join(tmp, "l0"),
+				Collection:       collection,
+				FilterOpts:       &filter.Opts{},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer mem.Close()
+			for i := 0; i < 100; i++ {
+				if err := mem.Put([]byte(fmt.Sprintf("%03d", i)), []byte("TESTVALUE")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			tests := []struct {
+				start []byte
+				end   []byte
+				count int64
+			}{
+				{start: nil, end: nil, count: 100},
+				{start: []byte("010"), end: []byte("020"), count: 10},
+				{start: []byte("090"), end: nil, count: 10},
+				{start: []byte("100"), end: nil, count: 0},
+			}
+			for _, tt := range tests {
+				size, count := mem.ApproximateRange(tt.start, tt.end)
+				if count != tt.count || size != tt.count*int64(len("000TESTVALUE")) {
+					t.Errorf("Expected %v entries in [%s, %s), found %v entries of %v bytes", tt.count, tt.start, tt.end, count, size)
+				}
+			}
+		})
+	}
+}
+
+func TestMemTableFlushRun(t *testing.T) {
+	tmp := t.TempDir()
+	mem, err := New(&Opts{
+		Batch_write_size: 10,
+		WalPath:          filepath.Join(tmp, "wal.dat"),
+		Max_size:         1 << 20,
+		LevelZero:        tmp,
+		FilterOpts:       &filter.Opts{},
+		Next_run:         func() uint64 { return 7 },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+	if err := mem.Put([]byte("key"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	flushed := make(chan *sstable.SSTable, 1)
+	go func() { flushed <- <-mem.FlushedTables() }()
+	mem.Flush()
+	table := <-flushed
+	if table.Run != 7 {
+		t.Errorf("Expected the flushed table to hold run 7, found %v", table.Run)
+	}
+	// The run is recorded in the file, not only in the table handed to the manifest
+	props, err := (&sstable.SSTable{Name: table.Name, Size: table.Size}).ReadProperties()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if props.MinSequence != 7 || props.MaxSequence != 7 {
+		t.Errorf("Expected sequences 7 and 7 from the properties block, found %v and %v", props.MinSequence, props.MaxSequence)
+	}
+}
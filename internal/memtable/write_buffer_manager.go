@@ -0,0 +1,109 @@
+package memtable
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// WriteBufferManager caps the total memory used by every memtable that shares it.
+//
+// A single manager may be passed to the memtables of several stores. When a write
+// pushes the combined usage past the limit, the largest memtable is flushed.
+type WriteBufferManager struct {
+	buffer_size int64                         // Max combined memtable memory in bytes
+	usage       atomic.Int64                  // Combined approximate memory usage in bytes
+	memtables   map[*GostoreMemTable]struct{} // Memtables charged against this manager
+	flushing    map[*GostoreMemTable]int64    // Memtables being flushed by the manager and the bytes each flush frees
+	mut         sync.Mutex
+}
+
+// Create a manager that caps combined memtable memory at bufferSize bytes
+func NewWriteBufferManager(bufferSize int64) *WriteBufferManager {
+	return &WriteBufferManager{
+		buffer_size: bufferSize,
+		memtables:   make(map[*GostoreMemTable]struct{}),
+		flushing:    make(map[*GostoreMemTable]int64),
+	}
+}
+
+// Returns the combined approximate memory usage of all registered memtables
+func (wbm *WriteBufferManager) MemoryUsage() int64 {
+	return wbm.usage.Load()
+}
+
+// Returns the configured limit in bytes
+func (wbm *WriteBufferManager) BufferSize() int64 {
+	return wbm.buffer_size
+}
+
+func (wbm *WriteBufferManager) register(mem *GostoreMemTable) {
+	wbm.mut.Lock()
+	defer wbm.mut.Unlock()
+	wbm.memtables[mem] = struct{}{}
+}
+
+func (wbm *WriteBufferManager) unregister(mem *GostoreMemTable) {
+	wbm.mut.Lock()
+	defer wbm.mut.Unlock()
+	delete(wbm.memtables, mem)
+}
+
+func (wbm *WriteBufferManager) reserve(bytes int64) {
+	wbm.usage.Add(bytes)
+}
+
+func (wbm *WriteBufferManager) free(bytes int64) {
+	wbm.usage.Add(-bytes)
+}
+
+func (wbm *WriteBufferManager) exceeded() bool {
+	return wbm.buffer_size > 0 && wbm.usage.Load() >= wbm.buffer_size
+}
+
+// Flushes the largest memtables until combined usage is back under the limit.
+//
+// The lock is not held while flushing: a flush blocks until the store owning the memtable
+// takes the flushed table, and writers of the other stores must not wait for it. Memory
+// that a running flush frees is not flushed again.
+func (wbm *WriteBufferManager) flushLargest() {
+	for {
+		largest := wbm.pickLargest()
+		if largest == nil {
+			return
+		}
+		slog.Debug("Write buffer full, flushing largest memtable", "usage", wbm.usage.Load(), "limit", wbm.buffer_size)
+		largest.flush(true)
+		wbm.mut.Lock()
+		delete(wbm.flushing, largest)
+		wbm.mut.Unlock()
+	}
+}
+
+// Returns the largest memtable that is not being flushed and marks it as flushing, nil if
+// usage is under the limit once running flushes finish
+func (wbm *WriteBufferManager) pickLargest() *GostoreMemTable {
+	wbm.mut.Lock()
+	defer wbm.mut.Unlock()
+	usage := wbm.usage.Load()
+	for _, bytes := range wbm.flushing {
+		usage -= bytes
+	}
+	if wbm.buffer_size <= 0 || usage < wbm.buffer_size {
+		return nil
+	}
+	var largest *GostoreMemTable
+	for mem := range wbm.memtables {
+		if _, ok := wbm.flushing[mem]; ok {
+			continue
+		}
+		if largest == nil || mem.MemoryUsage() > largest.MemoryUsage() {
+			largest = mem
+		}
+	}
+	if largest == nil || largest.MemoryUsage() == 0 {
+		return nil
+	}
+	wbm.flushing[largest] = largest.MemoryUsage()
+	return largest
+}
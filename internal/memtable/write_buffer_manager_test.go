@@ -0,0 +1,186 @@
+package memtable
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dillonkmcquade/gostore/internal/filter"
+)
+
+func newManagedMemTable(t *testing.T, maxSize int64, wbm *WriteBufferManager) MemTable {
+	tmp := t.TempDir()
+	mem, err := New(&Opts{
+		Batch_write_size:   10,
+		WalPath:            filepath.Join(tmp, "wal.dat"),
+		Max_size:           maxSize,
+		LevelZero:          tmp,
+		WriteBufferManager: wbm,
+		FilterOpts: &filter.Opts{
+			Path: tmp,
+			Size: 1000,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Drain flushed tables, normally done by the LSM
+	go func() {
+		for range mem.FlushedTables() {
+		}
+	}()
+	return mem
+}
+
+func TestMemTableMemoryUsage(t *testing.T) {
+	mem := newManagedMemTable(t, 1<<20, nil)
+	defer mem.Close()
+
+	err := mem.Put([]byte("small"), []byte{1})
+	if err != nil {
+		t.Error(err)
+	}
+	small := mem.MemoryUsage()
+	err = mem.Put([]byte("large"), make([]byte, 4096))
+	if err != nil {
+		t.Error(err)
+	}
+	large := mem.MemoryUsage() - small
+	if large-small < 4095 {
+		t.Errorf("Expected a 4096 byte value to use ~4KB more than a 1 byte value, found %v vs %v", large, small)
+	}
+}
+
+func TestMemTableFlushBySize(t *testing.T) {
+	mem := newManagedMemTable(t, 10000, nil)
+	defer mem.Close()
+
+	// Each entry is well over 1000 bytes, the memtable should never hold more than ~10
+	for i := 0; i < 50; i++ {
+		err := mem.Put([]byte(fmt.Sprintf("%v", i)), make([]byte, 1000))
+		if err != nil {
+			t.Error(err)
+		}
+		if mem.Size() > 10 {
+			t.Fatalf("Memtable should have flushed, found %v entries", mem.Size())
+		}
+	}
+}
+
+func TestWriteBufferManager(t *testing.T) {
+	wbm := NewWriteBufferManager(20000)
+	mem1 := newManagedMemTable(t, 1<<20, wbm)
+	mem2 := newManagedMemTable(t, 1<<20, wbm)
+	defer mem1.Close()
+	defer mem2.Close()
+
+	for i := 0; i < 100; i++ {
+		err := mem1.Put([]byte(fmt.Sprintf("%v", i)), make([]byte, 500))
+		if err != nil {
+			t.Error(err)
+		}
+		err = mem2.Put([]byte(fmt.Sprintf("%v", i)), make([]byte, 500))
+		if err != nil {
+			t.Error(err)
+		}
+		if wbm.MemoryUsage() >= wbm.BufferSize() {
+			t.Fatalf("Combined usage %v should stay under %v", wbm.MemoryUsage(), wbm.BufferSize())
+		}
+		if wbm.MemoryUsage() != mem1.MemoryUsage()+mem2.MemoryUsage() {
+			t.Fatalf("Manager usage %v should equal the sum of memtable usage %v", wbm.MemoryUsage(), mem1.MemoryUsage()+mem2.MemoryUsage())
+		}
+	}
+}
+
+func TestWriteBufferManagerBlockedFlush(t *testing.T) {
+	wbm := NewWriteBufferManager(20000)
+	tmp := t.TempDir()
+	// Flushed tables of blocked are not taken until the end of the test
+	blocked, err := New(&Opts{
+		Batch_write_size:   10,
+		WalPath:            filepath.Join(tmp, "wal.dat"),
+		Max_size:           1 << 20,
+		LevelZero:          tmp,
+		WriteBufferManager: wbm,
+		FilterOpts:         &filter.Opts{Path: tmp, Size: 1000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newManagedMemTable(t, 1<<20, wbm)
+	defer other.Close()
+
+	var writes sync.WaitGroup
+	writes.Add(1)
+	go func() {
+		defer writes.Done()
+		for i := 0; i < 50; i++ {
+			if err := blocked.Put([]byte(fmt.Sprintf("%v", i)), make([]byte, 500)); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(time.Millisecond) {
+		wbm.mut.Lock()
+		flushing := len(wbm.flushing)
+		wbm.mut.Unlock()
+		if flushing > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the write buffer to flush the blocked memtable")
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- other.Put([]byte("key"), []byte("value")) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Writers of another memtable should not wait for a blocked flush")
+	}
+
+	go func() {
+		for range blocked.FlushedTables() {
+		}
+	}()
+	writes.Wait()
+	if err := blocked.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if wbm.MemoryUsage() != other.MemoryUsage() {
+		t.Errorf("Expected a closed memtable to be freed once, found usage %v for %v remaining", wbm.MemoryUsage(), other.MemoryUsage())
+	}
+	// Nothing is left to release after Close
+	blocked.(*GostoreMemTable).release()
+	if wbm.MemoryUsage() != other.MemoryUsage() {
+		t.Errorf("Expected no bytes freed twice, found usage %v for %v remaining", wbm.MemoryUsage(), other.MemoryUsage())
+	}
+}
+
+func TestWriteBufferManagerClose(t *testing.T) {
+	wbm := NewWriteBufferManager(20000)
+	mem := newManagedMemTable(t, 1<<20, wbm).(*GostoreMemTable)
+	if err := mem.Put([]byte("key"), make([]byte, 500)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if wbm.MemoryUsage() != 0 {
+		t.Errorf("Expected Close to free the memtable usage, found %v", wbm.MemoryUsage())
+	}
+
+	// A write that reserves after the usage was freed is not charged
+	mem.lockWrites()
+	mem.reserve(500)
+	mem.unlockWrites()
+	if wbm.MemoryUsage() != 0 || mem.MemoryUsage() != 0 {
+		t.Errorf("Expected no usage charged after Close, found %v and %v", wbm.MemoryUsage(), mem.MemoryUsage())
+	}
+}
@@ -0,0 +1,179 @@
+package ordered
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"sync/atomic"
+
+	"github.com/dillonkmcquade/gostore/internal/assert"
+)
+
+// Maximum number of levels a skiplist node may have. With a branching factor
+// of 4 this comfortably supports billions of entries.
+const maxSkipListHeight = 16
+
+// SkipList is an ordered key-value collection that supports concurrent
+// inserts and wait-free reads.
+//
+// Nodes are linked in with compare-and-swap on each level and are never
+// unlinked, so readers only ever follow atomically published pointers and
+// never block or retry. Updating an existing key atomically swaps its value.
+//
+// Clear is not safe to call concurrently with Put or Delete.
+type SkipList[K any, V any] struct {
+	head       atomic.Pointer[skipListNode[K, V]]
+	height     atomic.Int32
+	size       atomic.Uint64
+	comparator func(K, K) int
+}
+
+type skipListNode[K any, V any] struct {
+	key   K
+	value atomic.Pointer[V]
+	next  []atomic.Pointer[skipListNode[K, V]]
+}
+
+func NewSkipList[K any, V any](comp func(K, K) int) *SkipList[K, V] {
+	list := &SkipList[K, V]{comparator: comp}
+	list.head.Store(newSkipListNode[K, V](Node[K, V]{}.Key, maxSkipListHeight))
+	list.height.Store(1)
+	return list
+}
+
+func newSkipListNode[K any, V any](key K, height int) *skipListNode[K, V] {
+	return &skipListNode[K, V]{key: key, next: make([]atomic.Pointer[skipListNode[K, V]], height)}
+}
+
+// Returns a random height in [1, maxSkipListHeight] with a branching factor of 4
+func randomHeight() int {
+	height := 1 + bits.TrailingZeros64(rand.Uint64())/2
+	return min(height, maxSkipListHeight)
+}
+
+// Finds the nodes on either side of key at every level. If a node with an
+// equal key exists it is returned.
+func (list *SkipList[K, V]) findSplice(head *skipListNode[K, V], key K, prev, next *[maxSkipListHeight]*skipListNode[K, V]) *skipListNode[K, V] {
+	var found *skipListNode[K, V]
+	node := head
+	height := int(list.height.Load())
+	for level := maxSkipListHeight - 1; level >= height; level-- {
+		prev[level] = head
+		next[level] = nil
+	}
+	for level := height - 1; level >= 0; level-- {
+		succ := node.next[level].Load()
+		for succ != nil {
+			cmp := list.comparator(key, succ.key)
+			if cmp <= 0 {
+				if cmp == 0 {
+					found = succ
+				}
+				break
+			}
+			node = succ
+			succ = node.next[level].Load()
+		}
+		prev[level] = node
+		next[level] = succ
+	}
+	return found
+}
+
+// Insert or update value at key. Safe for concurrent use.
+func (list *SkipList[K, V]) Put(key K, val V) {
+	var prev, next [maxSkipListHeight]*skipListNode[K, V]
+	head := list.head.Load()
+
+	if found := list.findSplice(head, key, &prev, &next); found != nil {
+		found.value.Store(&val)
+		return
+	}
+
+	height := randomHeight()
+	node := newSkipListNode[K, V](key, height)
+	node.value.Store(&val)
+
+	for {
+		current := list.height.Load()
+		if int32(height) <= current || list.height.CompareAndSwap(current, int32(height)) {
+			break
+		}
+	}
+
+	for level := 0; level < height; level++ {
+		for {
+			node.next[level].Store(next[level])
+			if prev[level].next[level].CompareAndSwap(next[level], node) {
+				break
+			}
+			// Lost the race on this level, recompute the splice
+			if found := list.findSplice(head, key, &prev, &next); found != nil && found != node {
+				// Another writer inserted the same key first
+				assert.True(level == 0, "skiplist: duplicate key linked at level %v", level)
+				found.value.Store(&val)
+				return
+			}
+		}
+	}
+	list.size.Add(1)
+}
+
+// Insert node with the zero value
+func (list *SkipList[K, V]) Delete(key K) {
+	list.Put(key, Node[K, V]{}.Value)
+}
+
+// Get value from key. Never blocks.
+func (list *SkipList[K, V]) Get(key K) (V, bool) {
+	node := list.head.Load()
+	for level := int(list.height.Load()) - 1; level >= 0; level-- {
+		succ := node.next[level].Load()
+		for succ != nil {
+			cmp := list.comparator(key, succ.key)
+			if cmp == 0 {
+				return *succ.value.Load(), true
+			}
+			if cmp < 0 {
+				break
+			}
+			node = succ
+			succ = node.next[level].Load()
+		}
+	}
+	return Node[K, V]{}.Value, false
+}
+
+func (list *SkipList[K, V]) Size() uint {
+	return uint(list.size.Load())
+}
+
+// Replaces the list with an empty one. Readers holding the old head are unaffected.
+func (list *SkipList[K, V]) Clear() {
+	list.head.Store(newSkipListNode[K, V](Node[K, V]{}.Key, maxSkipListHeight))
+	list.height.Store(1)
+	list.size.Store(0)
+}
+
+func (list *SkipList[K, V]) Values() <-chan V {
+	ch := make(chan V)
+	head := list.head.Load()
+	go func() {
+		defer close(ch)
+		for node := head.next[0].Load(); node != nil; node = node.next[0].Load() {
+			ch <- *node.value.Load()
+		}
+	}()
+	return ch
+}
+
+func (list *SkipList[K, V]) Keys() <-chan K {
+	ch := make(chan K)
+	head := list.head.Load()
+	go func() {
+		defer close(ch)
+		for node := head.next[0].Load(); node != nil; node = node.next[0].Load() {
+			ch <- node.key
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,155 @@
+package ordered
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestSkipList(t *testing.T) {
+	t.Run("Test insert", func(t *testing.T) {
+		list := NewSkipList[int, any](cmp.Compare[int])
+		list.Put(5, "value")
+		list.Put(6, "value")
+		if list.Size() != 2 {
+			t.Errorf("Expected size to be 2, got %d", list.Size())
+		}
+	})
+
+	t.Run("Test insert duplicates", func(t *testing.T) {
+		list := NewSkipList[int, string](cmp.Compare[int])
+		list.Put(5, "value")
+		list.Put(5, "changed")
+		if list.Size() != 1 {
+			t.Error("Should be 1")
+		}
+		if val, _ := list.Get(5); val != "changed" {
+			t.Errorf("Expected 'changed', found %v", val)
+		}
+	})
+
+	t.Run("Test get", func(t *testing.T) {
+		list := NewSkipList[int, int](cmp.Compare[int])
+		for i := 0; i < 1000; i++ {
+			list.Put(i, i*2)
+		}
+		for i := 0; i < 1000; i++ {
+			val, found := list.Get(i)
+			if !found || val != i*2 {
+				t.Errorf("Expected %v, found %v", i*2, val)
+			}
+		}
+		if _, found := list.Get(1000); found {
+			t.Error("Should not be found")
+		}
+	})
+
+	t.Run("Test ordered iteration", func(t *testing.T) {
+		list := NewSkipList[int, int](cmp.Compare[int])
+		for _, i := range rand.Perm(500) {
+			list.Put(i, i)
+		}
+		keys := []int{}
+		for key := range list.Keys() {
+			keys = append(keys, key)
+		}
+		if len(keys) != 500 || !slices.IsSorted(keys) {
+			t.Error("Keys should be sorted")
+		}
+		values := []int{}
+		for val := range list.Values() {
+			values = append(values, val)
+		}
+		if !slices.Equal(keys, values) {
+			t.Error("Values should be in key order")
+		}
+	})
+
+	t.Run("Test clear", func(t *testing.T) {
+		list := NewSkipList[int, int](cmp.Compare[int])
+		list.Put(1, 1)
+		list.Clear()
+		if _, found := list.Get(1); found || list.Size() != 0 {
+			t.Error("Should be empty")
+		}
+	})
+
+	t.Run("Test concurrent inserts", func(t *testing.T) {
+		list := NewSkipList[int, int](cmp.Compare[int])
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				// Every writer inserts every key so duplicates race with each other
+				for i := 0; i < 2000; i++ {
+					list.Put(i, w)
+				}
+			}(w)
+		}
+		wg.Wait()
+		if list.Size() != 2000 {
+			t.Errorf("Expected size to be 2000, got %d", list.Size())
+		}
+		keys := []int{}
+		for key := range list.Keys() {
+			keys = append(keys, key)
+		}
+		if len(keys) != 2000 || !slices.IsSorted(keys) {
+			t.Errorf("Expected 2000 sorted keys, found %v", len(keys))
+		}
+	})
+}
+
+// Runs a mixed load of writePercent writes and (100 - writePercent) reads
+// against a keyspace that is half populated up front.
+func benchmarkMixed(b *testing.B, put func([]byte, []byte), get func([]byte), writePercent int) {
+	keys := make([][]byte, 100000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("%08d", i))
+	}
+	for i := 0; i < len(keys); i += 2 {
+		put(keys[i], []byte("value"))
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			key := keys[r.Intn(len(keys))]
+			if r.Intn(100) < writePercent {
+				put(key, []byte("value"))
+			} else {
+				get(key)
+			}
+		}
+	})
+}
+
+func BenchmarkMixedLoad(b *testing.B) {
+	for _, writePercent := range []int{10, 50, 90} {
+		b.Run(fmt.Sprintf("RedBlackTree/%v%%_writes", writePercent), func(b *testing.B) {
+			// The red-black tree is not safe for concurrent use, guard it the same way the memtable does
+			var mut sync.RWMutex
+			tree := Rbt[[]byte, []byte](slices.Compare[[]byte])
+			put := func(key []byte, val []byte) {
+				mut.Lock()
+				tree.Put(key, val)
+				mut.Unlock()
+			}
+			get := func(key []byte) {
+				mut.RLock()
+				tree.Get(key)
+				mut.RUnlock()
+			}
+			benchmarkMixed(b, put, get, writePercent)
+		})
+		b.Run(fmt.Sprintf("SkipList/%v%%_writes", writePercent), func(b *testing.B) {
+			list := NewSkipList[[]byte, []byte](slices.Compare[[]byte])
+			get := func(key []byte) { list.Get(key) }
+			benchmarkMixed(b, list.Put, get, writePercent)
+		})
+	}
+}
@@ -6,7 +6,7 @@ import (
 )
 
 func (e *SSTable_Entry) Apply(c interface{}) error {
-	rbt := c.(*ordered.RedBlackTree[[]byte, *SSTable_Entry])
+	rbt := c.(ordered.Collection[[]byte, *SSTable_Entry])
 	if e.Op == Operation_OPERATION_INSERT {
 		rbt.Put(e.Key, e)
 	}
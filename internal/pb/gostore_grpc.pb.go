@@ -211,3 +211,125 @@ var GoStore_ServiceDesc = grpc.ServiceDesc{
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "gostore.proto",
 }
+
+// GoStoreAdminClient is the client API for GoStoreAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GoStoreAdminClient interface {
+	Flush(ctx context.Context, in *FlushRequest, opts ...grpc.CallOption) (*AdminReply, error)
+	CompactRange(ctx context.Context, in *CompactRangeRequest, opts ...grpc.CallOption) (*AdminReply, error)
+}
+
+type goStoreAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGoStoreAdminClient(cc grpc.ClientConnInterface) GoStoreAdminClient {
+	return &goStoreAdminClient{cc}
+}
+
+func (c *goStoreAdminClient) Flush(ctx context.Context, in *FlushRequest, opts ...grpc.CallOption) (*AdminReply, error) {
+	out := new(AdminReply)
+	err := c.cc.Invoke(ctx, "/gostore.proto.GoStoreAdmin/Flush", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goStoreAdminClient) CompactRange(ctx context.Context, in *CompactRangeRequest, opts ...grpc.CallOption) (*AdminReply, error) {
+	out := new(AdminReply)
+	err := c.cc.Invoke(ctx, "/gostore.proto.GoStoreAdmin/CompactRange", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GoStoreAdminServer is the server API for GoStoreAdmin service.
+// All implementations must embed UnimplementedGoStoreAdminServer
+// for forward compatibility
+type GoStoreAdminServer interface {
+	Flush(context.Context, *FlushRequest) (*AdminReply, error)
+	CompactRange(context.Context, *CompactRangeRequest) (*AdminReply, error)
+	mustEmbedUnimplementedGoStoreAdminServer()
+}
+
+// UnimplementedGoStoreAdminServer must be embedded to have forward compatible implementations.
+type UnimplementedGoStoreAdminServer struct {
+}
+
+func (UnimplementedGoStoreAdminServer) Flush(context.Context, *FlushRequest) (*AdminReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Flush not implemented")
+}
+func (UnimplementedGoStoreAdminServer) CompactRange(context.Context, *CompactRangeRequest) (*AdminReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompactRange not implemented")
+}
+func (UnimplementedGoStoreAdminServer) mustEmbedUnimplementedGoStoreAdminServer() {}
+
+// UnsafeGoStoreAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GoStoreAdminServer will
+// result in compilation errors.
+type UnsafeGoStoreAdminServer interface {
+	mustEmbedUnimplementedGoStoreAdminServer()
+}
+
+func RegisterGoStoreAdminServer(s grpc.ServiceRegistrar, srv GoStoreAdminServer) {
+	s.RegisterService(&GoStoreAdmin_ServiceDesc, srv)
+}
+
+func _GoStoreAdmin_Flush_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FlushRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoStoreAdminServer).Flush(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gostore.proto.GoStoreAdmin/Flush",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoStoreAdminServer).Flush(ctx, req.(*FlushRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoStoreAdmin_CompactRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompactRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoStoreAdminServer).CompactRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gostore.proto.GoStoreAdmin/CompactRange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoStoreAdminServer).CompactRange(ctx, req.(*CompactRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GoStoreAdmin_ServiceDesc is the grpc.ServiceDesc for GoStoreAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GoStoreAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gostore.proto.GoStoreAdmin",
+	HandlerType: (*GoStoreAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Flush",
+			Handler:    _GoStoreAdmin_Flush_Handler,
+		},
+		{
+			MethodName: "CompactRange",
+			Handler:    _GoStoreAdmin_CompactRange_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "gostore.proto",
+}
@@ -9,6 +9,7 @@ package pb
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 )
@@ -76,6 +77,7 @@ const (
 	ManifestEntry_OP_ADDTABLE    ManifestEntry_Op = 1
 	ManifestEntry_OP_REMOVETABLE ManifestEntry_Op = 2
 	ManifestEntry_OP_CLEARTABLE  ManifestEntry_Op = 3
+	ManifestEntry_OP_VERSIONEDIT ManifestEntry_Op = 4
 )
 
 // Enum value maps for ManifestEntry_Op.
@@ -85,12 +87,14 @@ var (
 		1: "OP_ADDTABLE",
 		2: "OP_REMOVETABLE",
 		3: "OP_CLEARTABLE",
+		4: "OP_VERSIONEDIT",
 	}
 	ManifestEntry_Op_value = map[string]int32{
 		"OP_UNSPECIFIED": 0,
 		"OP_ADDTABLE":    1,
 		"OP_REMOVETABLE": 2,
 		"OP_CLEARTABLE":  3,
+		"OP_VERSIONEDIT": 4,
 	}
 )
 
@@ -126,13 +130,25 @@ type SSTable struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Entries   []*SSTable_Entry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
-	Name      *string          `protobuf:"bytes,2,opt,name=name,proto3,oneof" json:"name,omitempty"`
-	Filter    *SSTable_Filter  `protobuf:"bytes,3,opt,name=filter,proto3,oneof" json:"filter,omitempty"`
-	First     []byte           `protobuf:"bytes,4,opt,name=first,proto3,oneof" json:"first,omitempty"`
-	Last      []byte           `protobuf:"bytes,5,opt,name=last,proto3,oneof" json:"last,omitempty"`
-	CreatedOn []byte           `protobuf:"bytes,6,opt,name=created_on,json=createdOn,proto3,oneof" json:"created_on,omitempty"`
-	Size      *int64           `protobuf:"varint,7,opt,name=size,proto3,oneof" json:"size,omitempty"`
+	Entries         []*SSTable_Entry       `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	Name            *string                `protobuf:"bytes,2,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	Filter          *SSTable_Filter        `protobuf:"bytes,3,opt,name=filter,proto3,oneof" json:"filter,omitempty"`
+	First           []byte                 `protobuf:"bytes,4,opt,name=first,proto3,oneof" json:"first,omitempty"`
+	Last            []byte                 `protobuf:"bytes,5,opt,name=last,proto3,oneof" json:"last,omitempty"`
+	CreatedOn       []byte                 `protobuf:"bytes,6,opt,name=created_on,json=createdOn,proto3,oneof" json:"created_on,omitempty"`
+	Size            *int64                 `protobuf:"varint,7,opt,name=size,proto3,oneof" json:"size,omitempty"`
+	LastUpdated     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=last_updated,json=lastUpdated,proto3" json:"last_updated,omitempty"`
+	NumEntries      *int64                 `protobuf:"varint,9,opt,name=num_entries,json=numEntries,proto3,oneof" json:"num_entries,omitempty"`
+	NumTombstones   *int64                 `protobuf:"varint,10,opt,name=num_tombstones,json=numTombstones,proto3,oneof" json:"num_tombstones,omitempty"`
+	OldestTombstone []byte                 `protobuf:"bytes,11,opt,name=oldest_tombstone,json=oldestTombstone,proto3,oneof" json:"oldest_tombstone,omitempty"`
+	Run             *uint64                `protobuf:"varint,12,opt,name=run,proto3,oneof" json:"run,omitempty"`
+	FilterOffset    *uint64                `protobuf:"fixed64,13,opt,name=filter_offset,json=filterOffset,proto3,oneof" json:"filter_offset,omitempty"`
+	RangeFilter     []byte                 `protobuf:"bytes,14,opt,name=range_filter,json=rangeFilter,proto3,oneof" json:"range_filter,omitempty"`
+	Index           *SSTable_Index         `protobuf:"bytes,15,opt,name=index,proto3,oneof" json:"index,omitempty"`
+	Blocks          [][]byte               `protobuf:"bytes,16,rep,name=blocks,proto3" json:"blocks,omitempty"`
+	Properties      *SSTable_Properties    `protobuf:"bytes,17,opt,name=properties,proto3,oneof" json:"properties,omitempty"`
+	MinSequence     *uint64                `protobuf:"varint,18,opt,name=min_sequence,json=minSequence,proto3,oneof" json:"min_sequence,omitempty"`
+	MaxSequence     *uint64                `protobuf:"varint,19,opt,name=max_sequence,json=maxSequence,proto3,oneof" json:"max_sequence,omitempty"`
 }
 
 func (x *SSTable) Reset() {
@@ -216,6 +232,90 @@ func (x *SSTable) GetSize() int64 {
 	return 0
 }
 
+func (x *SSTable) GetLastUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastUpdated
+	}
+	return nil
+}
+
+func (x *SSTable) GetNumEntries() int64 {
+	if x != nil && x.NumEntries != nil {
+		return *x.NumEntries
+	}
+	return 0
+}
+
+func (x *SSTable) GetNumTombstones() int64 {
+	if x != nil && x.NumTombstones != nil {
+		return *x.NumTombstones
+	}
+	return 0
+}
+
+func (x *SSTable) GetOldestTombstone() []byte {
+	if x != nil {
+		return x.OldestTombstone
+	}
+	return nil
+}
+
+func (x *SSTable) GetRun() uint64 {
+	if x != nil && x.Run != nil {
+		return *x.Run
+	}
+	return 0
+}
+
+func (x *SSTable) GetFilterOffset() uint64 {
+	if x != nil && x.FilterOffset != nil {
+		return *x.FilterOffset
+	}
+	return 0
+}
+
+func (x *SSTable) GetRangeFilter() []byte {
+	if x != nil {
+		return x.RangeFilter
+	}
+	return nil
+}
+
+func (x *SSTable) GetIndex() *SSTable_Index {
+	if x != nil {
+		return x.Index
+	}
+	return nil
+}
+
+func (x *SSTable) GetBlocks() [][]byte {
+	if x != nil {
+		return x.Blocks
+	}
+	return nil
+}
+
+func (x *SSTable) GetProperties() *SSTable_Properties {
+	if x != nil {
+		return x.Properties
+	}
+	return nil
+}
+
+func (x *SSTable) GetMinSequence() uint64 {
+	if x != nil && x.MinSequence != nil {
+		return *x.MinSequence
+	}
+	return 0
+}
+
+func (x *SSTable) GetMaxSequence() uint64 {
+	if x != nil && x.MaxSequence != nil {
+		return *x.MaxSequence
+	}
+	return 0
+}
+
 type ManifestEntry struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -224,6 +324,7 @@ type ManifestEntry struct {
 	Op    ManifestEntry_Op `protobuf:"varint,1,opt,name=op,proto3,enum=gostore.proto.ManifestEntry_Op" json:"op,omitempty"`
 	Level int32            `protobuf:"varint,2,opt,name=level,proto3" json:"level,omitempty"`
 	Table *SSTable         `protobuf:"bytes,3,opt,name=table,proto3" json:"table,omitempty"`
+	Edit  *VersionEdit     `protobuf:"bytes,4,opt,name=edit,proto3" json:"edit,omitempty"`
 }
 
 func (x *ManifestEntry) Reset() {
@@ -279,6 +380,69 @@ func (x *ManifestEntry) GetTable() *SSTable {
 	return nil
 }
 
+func (x *ManifestEntry) GetEdit() *VersionEdit {
+	if x != nil {
+		return x.Edit
+	}
+	return nil
+}
+
+// Every table added and removed by one flush or compaction, applied atomically
+type VersionEdit struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Added   []*VersionEdit_LevelTable `protobuf:"bytes,1,rep,name=added,proto3" json:"added,omitempty"`
+	Removed []*VersionEdit_LevelTable `protobuf:"bytes,2,rep,name=removed,proto3" json:"removed,omitempty"`
+}
+
+func (x *VersionEdit) Reset() {
+	*x = VersionEdit{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sstable_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VersionEdit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionEdit) ProtoMessage() {}
+
+func (x *VersionEdit) ProtoReflect() protoreflect.Message {
+	mi := &file_sstable_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionEdit.ProtoReflect.Descriptor instead.
+func (*VersionEdit) Descriptor() ([]byte, []int) {
+	return file_sstable_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *VersionEdit) GetAdded() []*VersionEdit_LevelTable {
+	if x != nil {
+		return x.Added
+	}
+	return nil
+}
+
+func (x *VersionEdit) GetRemoved() []*VersionEdit_LevelTable {
+	if x != nil {
+		return x.Removed
+	}
+	return nil
+}
+
 type SSTable_Entry struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -292,7 +456,7 @@ type SSTable_Entry struct {
 func (x *SSTable_Entry) Reset() {
 	*x = SSTable_Entry{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sstable_proto_msgTypes[2]
+		mi := &file_sstable_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -305,7 +469,7 @@ func (x *SSTable_Entry) String() string {
 func (*SSTable_Entry) ProtoMessage() {}
 
 func (x *SSTable_Entry) ProtoReflect() protoreflect.Message {
-	mi := &file_sstable_proto_msgTypes[2]
+	mi := &file_sstable_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -347,14 +511,17 @@ type SSTable_Filter struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Size uint64 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Name   string  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size   uint64  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Hashes *uint32 `protobuf:"varint,3,opt,name=hashes,proto3,oneof" json:"hashes,omitempty"`
+	Type   *int32  `protobuf:"varint,4,opt,name=type,proto3,oneof" json:"type,omitempty"`
+	Data   []byte  `protobuf:"bytes,5,opt,name=data,proto3,oneof" json:"data,omitempty"`
 }
 
 func (x *SSTable_Filter) Reset() {
 	*x = SSTable_Filter{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sstable_proto_msgTypes[3]
+		mi := &file_sstable_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -367,7 +534,7 @@ func (x *SSTable_Filter) String() string {
 func (*SSTable_Filter) ProtoMessage() {}
 
 func (x *SSTable_Filter) ProtoReflect() protoreflect.Message {
-	mi := &file_sstable_proto_msgTypes[3]
+	mi := &file_sstable_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -397,41 +564,568 @@ func (x *SSTable_Filter) GetSize() uint64 {
 	return 0
 }
 
+func (x *SSTable_Filter) GetHashes() uint32 {
+	if x != nil && x.Hashes != nil {
+		return *x.Hashes
+	}
+	return 0
+}
+
+func (x *SSTable_Filter) GetType() int32 {
+	if x != nil && x.Type != nil {
+		return *x.Type
+	}
+	return 0
+}
+
+func (x *SSTable_Filter) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// Location of a block in the table file
+type SSTable_BlockHandle struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Offset uint64 `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length uint64 `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
+}
+
+func (x *SSTable_BlockHandle) Reset() {
+	*x = SSTable_BlockHandle{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sstable_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SSTable_BlockHandle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SSTable_BlockHandle) ProtoMessage() {}
+
+func (x *SSTable_BlockHandle) ProtoReflect() protoreflect.Message {
+	mi := &file_sstable_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SSTable_BlockHandle.ProtoReflect.Descriptor instead.
+func (*SSTable_BlockHandle) Descriptor() ([]byte, []int) {
+	return file_sstable_proto_rawDescGZIP(), []int{0, 2}
+}
+
+func (x *SSTable_BlockHandle) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SSTable_BlockHandle) GetLength() uint64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+// Index of the blocks of a table, ordered by the last key of each block
+type SSTable_Index struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Entries []*SSTable_Index_Entry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+}
+
+func (x *SSTable_Index) Reset() {
+	*x = SSTable_Index{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sstable_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SSTable_Index) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SSTable_Index) ProtoMessage() {}
+
+func (x *SSTable_Index) ProtoReflect() protoreflect.Message {
+	mi := &file_sstable_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SSTable_Index.ProtoReflect.Descriptor instead.
+func (*SSTable_Index) Descriptor() ([]byte, []int) {
+	return file_sstable_proto_rawDescGZIP(), []int{0, 3}
+}
+
+func (x *SSTable_Index) GetEntries() []*SSTable_Index_Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// Statistics of the contents of a table, and the properties added by its collectors
+type SSTable_Properties struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NumEntries    int64                              `protobuf:"varint,1,opt,name=num_entries,json=numEntries,proto3" json:"num_entries,omitempty"`
+	NumTombstones int64                              `protobuf:"varint,2,opt,name=num_tombstones,json=numTombstones,proto3" json:"num_tombstones,omitempty"`
+	RawKeySize    int64                              `protobuf:"varint,3,opt,name=raw_key_size,json=rawKeySize,proto3" json:"raw_key_size,omitempty"`
+	RawValueSize  int64                              `protobuf:"varint,4,opt,name=raw_value_size,json=rawValueSize,proto3" json:"raw_value_size,omitempty"`
+	DataSize      int64                              `protobuf:"varint,5,opt,name=data_size,json=dataSize,proto3" json:"data_size,omitempty"`
+	IndexSize     int64                              `protobuf:"varint,6,opt,name=index_size,json=indexSize,proto3" json:"index_size,omitempty"`
+	FilterSize    int64                              `protobuf:"varint,7,opt,name=filter_size,json=filterSize,proto3" json:"filter_size,omitempty"`
+	CreatedOn     []byte                             `protobuf:"bytes,8,opt,name=created_on,json=createdOn,proto3" json:"created_on,omitempty"`
+	User          []*SSTable_Properties_UserProperty `protobuf:"bytes,9,rep,name=user,proto3" json:"user,omitempty"`
+	MinSequence   uint64                             `protobuf:"varint,10,opt,name=min_sequence,json=minSequence,proto3" json:"min_sequence,omitempty"`
+	MaxSequence   uint64                             `protobuf:"varint,11,opt,name=max_sequence,json=maxSequence,proto3" json:"max_sequence,omitempty"`
+}
+
+func (x *SSTable_Properties) Reset() {
+	*x = SSTable_Properties{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sstable_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SSTable_Properties) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SSTable_Properties) ProtoMessage() {}
+
+func (x *SSTable_Properties) ProtoReflect() protoreflect.Message {
+	mi := &file_sstable_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SSTable_Properties.ProtoReflect.Descriptor instead.
+func (*SSTable_Properties) Descriptor() ([]byte, []int) {
+	return file_sstable_proto_rawDescGZIP(), []int{0, 4}
+}
+
+func (x *SSTable_Properties) GetNumEntries() int64 {
+	if x != nil {
+		return x.NumEntries
+	}
+	return 0
+}
+
+func (x *SSTable_Properties) GetNumTombstones() int64 {
+	if x != nil {
+		return x.NumTombstones
+	}
+	return 0
+}
+
+func (x *SSTable_Properties) GetRawKeySize() int64 {
+	if x != nil {
+		return x.RawKeySize
+	}
+	return 0
+}
+
+func (x *SSTable_Properties) GetRawValueSize() int64 {
+	if x != nil {
+		return x.RawValueSize
+	}
+	return 0
+}
+
+func (x *SSTable_Properties) GetDataSize() int64 {
+	if x != nil {
+		return x.DataSize
+	}
+	return 0
+}
+
+func (x *SSTable_Properties) GetIndexSize() int64 {
+	if x != nil {
+		return x.IndexSize
+	}
+	return 0
+}
+
+func (x *SSTable_Properties) GetFilterSize() int64 {
+	if x != nil {
+		return x.FilterSize
+	}
+	return 0
+}
+
+func (x *SSTable_Properties) GetCreatedOn() []byte {
+	if x != nil {
+		return x.CreatedOn
+	}
+	return nil
+}
+
+func (x *SSTable_Properties) GetUser() []*SSTable_Properties_UserProperty {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *SSTable_Properties) GetMinSequence() uint64 {
+	if x != nil {
+		return x.MinSequence
+	}
+	return 0
+}
+
+func (x *SSTable_Properties) GetMaxSequence() uint64 {
+	if x != nil {
+		return x.MaxSequence
+	}
+	return 0
+}
+
+type SSTable_Index_Entry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	LastKey []byte               `protobuf:"bytes,1,opt,name=last_key,json=lastKey,proto3" json:"last_key,omitempty"`
+	Block   *SSTable_BlockHandle `protobuf:"bytes,2,opt,name=block,proto3" json:"block,omitempty"`
+	Filter  *SSTable_BlockHandle `protobuf:"bytes,3,opt,name=filter,proto3,oneof" json:"filter,omitempty"` // Filter partition of the keys of an index partition
+}
+
+func (x *SSTable_Index_Entry) Reset() {
+	*x = SSTable_Index_Entry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sstable_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SSTable_Index_Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SSTable_Index_Entry) ProtoMessage() {}
+
+func (x *SSTable_Index_Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_sstable_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SSTable_Index_Entry.ProtoReflect.Descriptor instead.
+func (*SSTable_Index_Entry) Descriptor() ([]byte, []int) {
+	return file_sstable_proto_rawDescGZIP(), []int{0, 3, 0}
+}
+
+func (x *SSTable_Index_Entry) GetLastKey() []byte {
+	if x != nil {
+		return x.LastKey
+	}
+	return nil
+}
+
+func (x *SSTable_Index_Entry) GetBlock() *SSTable_BlockHandle {
+	if x != nil {
+		return x.Block
+	}
+	return nil
+}
+
+func (x *SSTable_Index_Entry) GetFilter() *SSTable_BlockHandle {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+type SSTable_Properties_UserProperty struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *SSTable_Properties_UserProperty) Reset() {
+	*x = SSTable_Properties_UserProperty{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sstable_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SSTable_Properties_UserProperty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SSTable_Properties_UserProperty) ProtoMessage() {}
+
+func (x *SSTable_Properties_UserProperty) ProtoReflect() protoreflect.Message {
+	mi := &file_sstable_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SSTable_Properties_UserProperty.ProtoReflect.Descriptor instead.
+func (*SSTable_Properties_UserProperty) Descriptor() ([]byte, []int) {
+	return file_sstable_proto_rawDescGZIP(), []int{0, 4, 0}
+}
+
+func (x *SSTable_Properties_UserProperty) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SSTable_Properties_UserProperty) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type VersionEdit_LevelTable struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Level int32    `protobuf:"varint,1,opt,name=level,proto3" json:"level,omitempty"`
+	Table *SSTable `protobuf:"bytes,2,opt,name=table,proto3" json:"table,omitempty"`
+}
+
+func (x *VersionEdit_LevelTable) Reset() {
+	*x = VersionEdit_LevelTable{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sstable_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VersionEdit_LevelTable) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionEdit_LevelTable) ProtoMessage() {}
+
+func (x *VersionEdit_LevelTable) ProtoReflect() protoreflect.Message {
+	mi := &file_sstable_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionEdit_LevelTable.ProtoReflect.Descriptor instead.
+func (*VersionEdit_LevelTable) Descriptor() ([]byte, []int) {
+	return file_sstable_proto_rawDescGZIP(), []int{2, 0}
+}
+
+func (x *VersionEdit_LevelTable) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *VersionEdit_LevelTable) GetTable() *SSTable {
+	if x != nil {
+		return x.Table
+	}
+	return nil
+}
+
 var File_sstable_proto protoreflect.FileDescriptor
 
 var file_sstable_proto_rawDesc = []byte{
 	0x0a, 0x0d, 0x73, 0x73, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
-	0x0d, 0x67, 0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xd3,
-	0x03, 0x0a, 0x07, 0x53, 0x53, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x36, 0x0a, 0x07, 0x65, 0x6e,
-	0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f,
-	0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x53, 0x54, 0x61,
-	0x62, 0x6c, 0x65, 0x2e, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69,
-	0x65, 0x73, 0x12, 0x17, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
-	0x48, 0x00, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x88, 0x01, 0x01, 0x12, 0x3a, 0x0a, 0x06, 0x66,
-	0x69, 0x6c, 0x74, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x67, 0x6f,
-	0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x53, 0x54, 0x61,
-	0x62, 0x6c, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x48, 0x01, 0x52, 0x06, 0x66, 0x69,
-	0x6c, 0x74, 0x65, 0x72, 0x88, 0x01, 0x01, 0x12, 0x19, 0x0a, 0x05, 0x66, 0x69, 0x72, 0x73, 0x74,
-	0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x02, 0x52, 0x05, 0x66, 0x69, 0x72, 0x73, 0x74, 0x88,
-	0x01, 0x01, 0x12, 0x17, 0x0a, 0x04, 0x6c, 0x61, 0x73, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c,
-	0x48, 0x03, 0x52, 0x04, 0x6c, 0x61, 0x73, 0x74, 0x88, 0x01, 0x01, 0x12, 0x22, 0x0a, 0x0a, 0x63,
-	0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x48,
-	0x04, 0x52, 0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x4f, 0x6e, 0x88, 0x01, 0x01, 0x12,
-	0x17, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x48, 0x05, 0x52,
-	0x04, 0x73, 0x69, 0x7a, 0x65, 0x88, 0x01, 0x01, 0x1a, 0x59, 0x0a, 0x05, 0x45, 0x6e, 0x74, 0x72,
-	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x03,
-	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x28, 0x0a, 0x02, 0x6f, 0x70, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x67, 0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52,
-	0x02, 0x6f, 0x70, 0x1a, 0x30, 0x0a, 0x06, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x12, 0x12, 0x0a,
+	0x0d, 0x67, 0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1f,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f,
+	0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22,
+	0x82, 0x10, 0x0a, 0x07, 0x53, 0x53, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x36, 0x0a, 0x07, 0x65,
+	0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67,
+	0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x53, 0x54,
+	0x61, 0x62, 0x6c, 0x65, 0x2e, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x65, 0x6e, 0x74, 0x72,
+	0x69, 0x65, 0x73, 0x12, 0x17, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x48, 0x00, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x88, 0x01, 0x01, 0x12, 0x3a, 0x0a, 0x06,
+	0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x67,
+	0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x53, 0x54,
+	0x61, 0x62, 0x6c, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x48, 0x01, 0x52, 0x06, 0x66,
+	0x69, 0x6c, 0x74, 0x65, 0x72, 0x88, 0x01, 0x01, 0x12, 0x19, 0x0a, 0x05, 0x66, 0x69, 0x72, 0x73,
+	0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x02, 0x52, 0x05, 0x66, 0x69, 0x72, 0x73, 0x74,
+	0x88, 0x01, 0x01, 0x12, 0x17, 0x0a, 0x04, 0x6c, 0x61, 0x73, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x0c, 0x48, 0x03, 0x52, 0x04, 0x6c, 0x61, 0x73, 0x74, 0x88, 0x01, 0x01, 0x12, 0x22, 0x0a, 0x0a,
+	0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c,
+	0x48, 0x04, 0x52, 0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x4f, 0x6e, 0x88, 0x01, 0x01,
+	0x12, 0x17, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x48, 0x05,
+	0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x88, 0x01, 0x01, 0x12, 0x3d, 0x0a, 0x0c, 0x6c, 0x61, 0x73,
+	0x74, 0x5f, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0b, 0x6c, 0x61, 0x73,
+	0x74, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x12, 0x24, 0x0a, 0x0b, 0x6e, 0x75, 0x6d, 0x5f,
+	0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03, 0x48, 0x06, 0x52,
+	0x0a, 0x6e, 0x75, 0x6d, 0x45, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x88, 0x01, 0x01, 0x12, 0x2a,
+	0x0a, 0x0e, 0x6e, 0x75, 0x6d, 0x5f, 0x74, 0x6f, 0x6d, 0x62, 0x73, 0x74, 0x6f, 0x6e, 0x65, 0x73,
+	0x18, 0x0a, 0x20, 0x01, 0x28, 0x03, 0x48, 0x07, 0x52, 0x0d, 0x6e, 0x75, 0x6d, 0x54, 0x6f, 0x6d,
+	0x62, 0x73, 0x74, 0x6f, 0x6e, 0x65, 0x73, 0x88, 0x01, 0x01, 0x12, 0x2e, 0x0a, 0x10, 0x6f, 0x6c,
+	0x64, 0x65, 0x73, 0x74, 0x5f, 0x74, 0x6f, 0x6d, 0x62, 0x73, 0x74, 0x6f, 0x6e, 0x65, 0x18, 0x0b,
+	0x20, 0x01, 0x28, 0x0c, 0x48, 0x08, 0x52, 0x0f, 0x6f, 0x6c, 0x64, 0x65, 0x73, 0x74, 0x54, 0x6f,
+	0x6d, 0x62, 0x73, 0x74, 0x6f, 0x6e, 0x65, 0x88, 0x01, 0x01, 0x12, 0x15, 0x0a, 0x03, 0x72, 0x75,
+	0x6e, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x04, 0x48, 0x09, 0x52, 0x03, 0x72, 0x75, 0x6e, 0x88, 0x01,
+	0x01, 0x12, 0x28, 0x0a, 0x0d, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x5f, 0x6f, 0x66, 0x66, 0x73,
+	0x65, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x06, 0x48, 0x0a, 0x52, 0x0c, 0x66, 0x69, 0x6c, 0x74,
+	0x65, 0x72, 0x4f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x88, 0x01, 0x01, 0x12, 0x26, 0x0a, 0x0c, 0x72,
+	0x61, 0x6e, 0x67, 0x65, 0x5f, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x18, 0x0e, 0x20, 0x01, 0x28,
+	0x0c, 0x48, 0x0b, 0x52, 0x0b, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72,
+	0x88, 0x01, 0x01, 0x12, 0x37, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x0f, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x53, 0x53, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x2e, 0x49, 0x6e, 0x64, 0x65, 0x78,
+	0x48, 0x0c, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x88, 0x01, 0x01, 0x12, 0x16, 0x0a, 0x06,
+	0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18, 0x10, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x06, 0x62, 0x6c,
+	0x6f, 0x63, 0x6b, 0x73, 0x12, 0x46, 0x0a, 0x0a, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69,
+	0x65, 0x73, 0x18, 0x11, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x67, 0x6f, 0x73, 0x74, 0x6f,
+	0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x53, 0x54, 0x61, 0x62, 0x6c, 0x65,
+	0x2e, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x48, 0x0d, 0x52, 0x0a, 0x70,
+	0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x88, 0x01, 0x01, 0x12, 0x26, 0x0a, 0x0c,
+	0x6d, 0x69, 0x6e, 0x5f, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x12, 0x20, 0x01,
+	0x28, 0x04, 0x48, 0x0e, 0x52, 0x0b, 0x6d, 0x69, 0x6e, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63,
+	0x65, 0x88, 0x01, 0x01, 0x12, 0x26, 0x0a, 0x0c, 0x6d, 0x61, 0x78, 0x5f, 0x73, 0x65, 0x71, 0x75,
+	0x65, 0x6e, 0x63, 0x65, 0x18, 0x13, 0x20, 0x01, 0x28, 0x04, 0x48, 0x0f, 0x52, 0x0b, 0x6d, 0x61,
+	0x78, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x88, 0x01, 0x01, 0x1a, 0x59, 0x0a, 0x05,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x28, 0x0a,
+	0x02, 0x6f, 0x70, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x67, 0x6f, 0x73, 0x74,
+	0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x02, 0x6f, 0x70, 0x1a, 0x9c, 0x01, 0x0a, 0x06, 0x46, 0x69, 0x6c, 0x74,
+	0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x1b, 0x0a, 0x06, 0x68, 0x61,
+	0x73, 0x68, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x00, 0x52, 0x06, 0x68, 0x61,
+	0x73, 0x68, 0x65, 0x73, 0x88, 0x01, 0x01, 0x12, 0x17, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x05, 0x48, 0x01, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x88, 0x01, 0x01,
+	0x12, 0x17, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x02,
+	0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x88, 0x01, 0x01, 0x42, 0x09, 0x0a, 0x07, 0x5f, 0x68, 0x61,
+	0x73, 0x68, 0x65, 0x73, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x42, 0x07, 0x0a,
+	0x05, 0x5f, 0x64, 0x61, 0x74, 0x61, 0x1a, 0x3d, 0x0a, 0x0b, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48,
+	0x61, 0x6e, 0x64, 0x6c, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x12, 0x16, 0x0a,
+	0x06, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6c,
+	0x65, 0x6e, 0x67, 0x74, 0x68, 0x1a, 0xf0, 0x01, 0x0a, 0x05, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12,
+	0x3c, 0x0a, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x22, 0x2e, 0x67, 0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x53, 0x53, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x2e, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x2e, 0x45,
+	0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x1a, 0xa8, 0x01,
+	0x0a, 0x05, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x19, 0x0a, 0x08, 0x6c, 0x61, 0x73, 0x74, 0x5f,
+	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x6c, 0x61, 0x73, 0x74, 0x4b,
+	0x65, 0x79, 0x12, 0x38, 0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x22, 0x2e, 0x67, 0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x53, 0x53, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48,
+	0x61, 0x6e, 0x64, 0x6c, 0x65, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x3f, 0x0a, 0x06,
+	0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x67,
+	0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x53, 0x54,
+	0x61, 0x62, 0x6c, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x6e, 0x64, 0x6c, 0x65,
+	0x48, 0x00, 0x52, 0x06, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x88, 0x01, 0x01, 0x42, 0x09, 0x0a,
+	0x07, 0x5f, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x1a, 0xdc, 0x03, 0x0a, 0x0a, 0x50, 0x72, 0x6f,
+	0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6e, 0x75, 0x6d, 0x5f, 0x65,
+	0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x6e, 0x75,
+	0x6d, 0x45, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x6e, 0x75, 0x6d, 0x5f,
+	0x74, 0x6f, 0x6d, 0x62, 0x73, 0x74, 0x6f, 0x6e, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0d, 0x6e, 0x75, 0x6d, 0x54, 0x6f, 0x6d, 0x62, 0x73, 0x74, 0x6f, 0x6e, 0x65, 0x73, 0x12,
+	0x20, 0x0a, 0x0c, 0x72, 0x61, 0x77, 0x5f, 0x6b, 0x65, 0x79, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x72, 0x61, 0x77, 0x4b, 0x65, 0x79, 0x53, 0x69, 0x7a,
+	0x65, 0x12, 0x24, 0x0a, 0x0e, 0x72, 0x61, 0x77, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x5f, 0x73,
+	0x69, 0x7a, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x72, 0x61, 0x77, 0x56, 0x61,
+	0x6c, 0x75, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x64, 0x61, 0x74, 0x61, 0x5f,
+	0x73, 0x69, 0x7a, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x64, 0x61, 0x74, 0x61,
+	0x53, 0x69, 0x7a, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x69,
+	0x7a, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x53,
+	0x69, 0x7a, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x5f, 0x73, 0x69,
+	0x7a, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72,
+	0x53, 0x69, 0x7a, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f,
+	0x6f, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65,
+	0x64, 0x4f, 0x6e, 0x12, 0x42, 0x0a, 0x04, 0x75, 0x73, 0x65, 0x72, 0x18, 0x09, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x2e, 0x2e, 0x67, 0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x53, 0x53, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x2e, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72,
+	0x74, 0x69, 0x65, 0x73, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74,
+	0x79, 0x52, 0x04, 0x75, 0x73, 0x65, 0x72, 0x12, 0x21, 0x0a, 0x0c, 0x6d, 0x69, 0x6e, 0x5f, 0x73,
+	0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x6d,
+	0x69, 0x6e, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x6d, 0x61,
+	0x78, 0x5f, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x0b, 0x6d, 0x61, 0x78, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x1a, 0x38, 0x0a,
+	0x0c, 0x55, 0x73, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x12, 0x12, 0x0a,
 	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d,
-	0x65, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52,
-	0x04, 0x73, 0x69, 0x7a, 0x65, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x42, 0x09,
-	0x0a, 0x07, 0x5f, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x66, 0x69,
-	0x72, 0x73, 0x74, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x6c, 0x61, 0x73, 0x74, 0x42, 0x0d, 0x0a, 0x0b,
-	0x5f, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x6f, 0x6e, 0x42, 0x07, 0x0a, 0x05, 0x5f,
-	0x73, 0x69, 0x7a, 0x65, 0x22, 0xd6, 0x01, 0x0a, 0x0d, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x6e, 0x61, 0x6d, 0x65,
+	0x42, 0x09, 0x0a, 0x07, 0x5f, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x42, 0x08, 0x0a, 0x06, 0x5f,
+	0x66, 0x69, 0x72, 0x73, 0x74, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x6c, 0x61, 0x73, 0x74, 0x42, 0x0d,
+	0x0a, 0x0b, 0x5f, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x6f, 0x6e, 0x42, 0x07, 0x0a,
+	0x05, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x42, 0x0e, 0x0a, 0x0c, 0x5f, 0x6e, 0x75, 0x6d, 0x5f, 0x65,
+	0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x42, 0x11, 0x0a, 0x0f, 0x5f, 0x6e, 0x75, 0x6d, 0x5f, 0x74,
+	0x6f, 0x6d, 0x62, 0x73, 0x74, 0x6f, 0x6e, 0x65, 0x73, 0x42, 0x13, 0x0a, 0x11, 0x5f, 0x6f, 0x6c,
+	0x64, 0x65, 0x73, 0x74, 0x5f, 0x74, 0x6f, 0x6d, 0x62, 0x73, 0x74, 0x6f, 0x6e, 0x65, 0x42, 0x06,
+	0x0a, 0x04, 0x5f, 0x72, 0x75, 0x6e, 0x42, 0x10, 0x0a, 0x0e, 0x5f, 0x66, 0x69, 0x6c, 0x74, 0x65,
+	0x72, 0x5f, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x42, 0x0f, 0x0a, 0x0d, 0x5f, 0x72, 0x61, 0x6e,
+	0x67, 0x65, 0x5f, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x69, 0x6e,
+	0x64, 0x65, 0x78, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69,
+	0x65, 0x73, 0x42, 0x0f, 0x0a, 0x0d, 0x5f, 0x6d, 0x69, 0x6e, 0x5f, 0x73, 0x65, 0x71, 0x75, 0x65,
+	0x6e, 0x63, 0x65, 0x42, 0x0f, 0x0a, 0x0d, 0x5f, 0x6d, 0x61, 0x78, 0x5f, 0x73, 0x65, 0x71, 0x75,
+	0x65, 0x6e, 0x63, 0x65, 0x22, 0x9a, 0x02, 0x0a, 0x0d, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73,
 	0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x2f, 0x0a, 0x02, 0x6f, 0x70, 0x18, 0x01, 0x20, 0x01,
 	0x28, 0x0e, 0x32, 0x1f, 0x2e, 0x67, 0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f,
 	0x74, 0x6f, 0x2e, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79,
@@ -439,21 +1133,39 @@ var file_sstable_proto_rawDesc = []byte{
 	0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x2c, 0x0a,
 	0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x67,
 	0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x53, 0x54,
-	0x61, 0x62, 0x6c, 0x65, 0x52, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x22, 0x50, 0x0a, 0x02, 0x4f,
+	0x61, 0x62, 0x6c, 0x65, 0x52, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x2e, 0x0a, 0x04, 0x65,
+	0x64, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x73, 0x74,
+	0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x45, 0x64, 0x69, 0x74, 0x52, 0x04, 0x65, 0x64, 0x69, 0x74, 0x22, 0x64, 0x0a, 0x02, 0x4f,
 	0x70, 0x12, 0x12, 0x0a, 0x0e, 0x4f, 0x50, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46,
 	0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x4f, 0x50, 0x5f, 0x41, 0x44, 0x44, 0x54,
 	0x41, 0x42, 0x4c, 0x45, 0x10, 0x01, 0x12, 0x12, 0x0a, 0x0e, 0x4f, 0x50, 0x5f, 0x52, 0x45, 0x4d,
 	0x4f, 0x56, 0x45, 0x54, 0x41, 0x42, 0x4c, 0x45, 0x10, 0x02, 0x12, 0x11, 0x0a, 0x0d, 0x4f, 0x50,
-	0x5f, 0x43, 0x4c, 0x45, 0x41, 0x52, 0x54, 0x41, 0x42, 0x4c, 0x45, 0x10, 0x03, 0x2a, 0x52, 0x0a,
-	0x09, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x19, 0x0a, 0x15, 0x4f, 0x50,
-	0x45, 0x52, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46,
-	0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x14, 0x0a, 0x10, 0x4f, 0x50, 0x45, 0x52, 0x41, 0x54, 0x49,
-	0x4f, 0x4e, 0x5f, 0x49, 0x4e, 0x53, 0x45, 0x52, 0x54, 0x10, 0x01, 0x12, 0x14, 0x0a, 0x10, 0x4f,
-	0x50, 0x45, 0x52, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x44, 0x45, 0x4c, 0x45, 0x54, 0x45, 0x10,
-	0x02, 0x42, 0x27, 0x5a, 0x25, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
-	0x64, 0x69, 0x6c, 0x6c, 0x6f, 0x6e, 0x6b, 0x6d, 0x63, 0x71, 0x75, 0x61, 0x64, 0x65, 0x2f, 0x69,
-	0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x5f, 0x43, 0x4c, 0x45, 0x41, 0x52, 0x54, 0x41, 0x42, 0x4c, 0x45, 0x10, 0x03, 0x12, 0x12, 0x0a,
+	0x0e, 0x4f, 0x50, 0x5f, 0x56, 0x45, 0x52, 0x53, 0x49, 0x4f, 0x4e, 0x45, 0x44, 0x49, 0x54, 0x10,
+	0x04, 0x22, 0xdd, 0x01, 0x0a, 0x0b, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x45, 0x64, 0x69,
+	0x74, 0x12, 0x3b, 0x0a, 0x05, 0x61, 0x64, 0x64, 0x65, 0x64, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x25, 0x2e, 0x67, 0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x45, 0x64, 0x69, 0x74, 0x2e, 0x4c, 0x65, 0x76,
+	0x65, 0x6c, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x52, 0x05, 0x61, 0x64, 0x64, 0x65, 0x64, 0x12, 0x3f,
+	0x0a, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x25, 0x2e, 0x67, 0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x45, 0x64, 0x69, 0x74, 0x2e, 0x4c, 0x65, 0x76, 0x65,
+	0x6c, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x52, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x1a,
+	0x50, 0x0a, 0x0a, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x14, 0x0a,
+	0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x65,
+	0x76, 0x65, 0x6c, 0x12, 0x2c, 0x0a, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x16, 0x2e, 0x67, 0x6f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x53, 0x53, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x52, 0x05, 0x74, 0x61, 0x62, 0x6c,
+	0x65, 0x2a, 0x52, 0x0a, 0x09, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x19,
+	0x0a, 0x15, 0x4f, 0x50, 0x45, 0x52, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x55, 0x4e, 0x53, 0x50,
+	0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x14, 0x0a, 0x10, 0x4f, 0x50, 0x45,
+	0x52, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x49, 0x4e, 0x53, 0x45, 0x52, 0x54, 0x10, 0x01, 0x12,
+	0x14, 0x0a, 0x10, 0x4f, 0x50, 0x45, 0x52, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x44, 0x45, 0x4c,
+	0x45, 0x54, 0x45, 0x10, 0x02, 0x42, 0x27, 0x5a, 0x25, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x64, 0x69, 0x6c, 0x6c, 0x6f, 0x6e, 0x6b, 0x6d, 0x63, 0x71, 0x75, 0x61,
+	0x64, 0x65, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x70, 0x62, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -469,26 +1181,45 @@ func file_sstable_proto_rawDescGZIP() []byte {
 }
 
 var file_sstable_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_sstable_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_sstable_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_sstable_proto_goTypes = []interface{}{
-	(Operation)(0),         // 0: gostore.proto.Operation
-	(ManifestEntry_Op)(0),  // 1: gostore.proto.ManifestEntry.Op
-	(*SSTable)(nil),        // 2: gostore.proto.SSTable
-	(*ManifestEntry)(nil),  // 3: gostore.proto.ManifestEntry
-	(*SSTable_Entry)(nil),  // 4: gostore.proto.SSTable.Entry
-	(*SSTable_Filter)(nil), // 5: gostore.proto.SSTable.Filter
+	(Operation)(0),                          // 0: gostore.proto.Operation
+	(ManifestEntry_Op)(0),                   // 1: gostore.proto.ManifestEntry.Op
+	(*SSTable)(nil),                         // 2: gostore.proto.SSTable
+	(*ManifestEntry)(nil),                   // 3: gostore.proto.ManifestEntry
+	(*VersionEdit)(nil),                     // 4: gostore.proto.VersionEdit
+	(*SSTable_Entry)(nil),                   // 5: gostore.proto.SSTable.Entry
+	(*SSTable_Filter)(nil),                  // 6: gostore.proto.SSTable.Filter
+	(*SSTable_BlockHandle)(nil),             // 7: gostore.proto.SSTable.BlockHandle
+	(*SSTable_Index)(nil),                   // 8: gostore.proto.SSTable.Index
+	(*SSTable_Properties)(nil),              // 9: gostore.proto.SSTable.Properties
+	(*SSTable_Index_Entry)(nil),             // 10: gostore.proto.SSTable.Index.Entry
+	(*SSTable_Properties_UserProperty)(nil), // 11: gostore.proto.SSTable.Properties.UserProperty
+	(*VersionEdit_LevelTable)(nil),          // 12: gostore.proto.VersionEdit.LevelTable
+	(*timestamppb.Timestamp)(nil),           // 13: google.protobuf.Timestamp
 }
 var file_sstable_proto_depIdxs = []int32{
-	4, // 0: gostore.proto.SSTable.entries:type_name -> gostore.proto.SSTable.Entry
-	5, // 1: gostore.proto.SSTable.filter:type_name -> gostore.proto.SSTable.Filter
-	1, // 2: gostore.proto.ManifestEntry.op:type_name -> gostore.proto.ManifestEntry.Op
-	2, // 3: gostore.proto.ManifestEntry.table:type_name -> gostore.proto.SSTable
-	0, // 4: gostore.proto.SSTable.Entry.op:type_name -> gostore.proto.Operation
-	5, // [5:5] is the sub-list for method output_type
-	5, // [5:5] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	5,  // 0: gostore.proto.SSTable.entries:type_name -> gostore.proto.SSTable.Entry
+	6,  // 1: gostore.proto.SSTable.filter:type_name -> gostore.proto.SSTable.Filter
+	13, // 2: gostore.proto.SSTable.last_updated:type_name -> google.protobuf.Timestamp
+	8,  // 3: gostore.proto.SSTable.index:type_name -> gostore.proto.SSTable.Index
+	9,  // 4: gostore.proto.SSTable.properties:type_name -> gostore.proto.SSTable.Properties
+	1,  // 5: gostore.proto.ManifestEntry.op:type_name -> gostore.proto.ManifestEntry.Op
+	2,  // 6: gostore.proto.ManifestEntry.table:type_name -> gostore.proto.SSTable
+	4,  // 7: gostore.proto.ManifestEntry.edit:type_name -> gostore.proto.VersionEdit
+	12, // 8: gostore.proto.VersionEdit.added:type_name -> gostore.proto.VersionEdit.LevelTable
+	12, // 9: gostore.proto.VersionEdit.removed:type_name -> gostore.proto.VersionEdit.LevelTable
+	0,  // 10: gostore.proto.SSTable.Entry.op:type_name -> gostore.proto.Operation
+	10, // 11: gostore.proto.SSTable.Index.entries:type_name -> gostore.proto.SSTable.Index.Entry
+	11, // 12: gostore.proto.SSTable.Properties.user:type_name -> gostore.proto.SSTable.Properties.UserProperty
+	7,  // 13: gostore.proto.SSTable.Index.Entry.block:type_name -> gostore.proto.SSTable.BlockHandle
+	7,  // 14: gostore.proto.SSTable.Index.Entry.filter:type_name -> gostore.proto.SSTable.BlockHandle
+	2,  // 15: gostore.proto.VersionEdit.LevelTable.table:type_name -> gostore.proto.SSTable
+	16, // [16:16] is the sub-list for method output_type
+	16, // [16:16] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
 }
 
 func init() { file_sstable_proto_init() }
@@ -522,7 +1253,7 @@ func file_sstable_proto_init() {
 			}
 		}
 		file_sstable_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SSTable_Entry); i {
+			switch v := v.(*VersionEdit); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -534,6 +1265,18 @@ func file_sstable_proto_init() {
 			}
 		}
 		file_sstable_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SSTable_Entry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sstable_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*SSTable_Filter); i {
 			case 0:
 				return &v.state
@@ -545,15 +1288,89 @@ func file_sstable_proto_init() {
 				return nil
 			}
 		}
+		file_sstable_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SSTable_BlockHandle); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sstable_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SSTable_Index); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sstable_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SSTable_Properties); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sstable_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SSTable_Index_Entry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sstable_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SSTable_Properties_UserProperty); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sstable_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VersionEdit_LevelTable); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_sstable_proto_msgTypes[0].OneofWrappers = []interface{}{}
+	file_sstable_proto_msgTypes[4].OneofWrappers = []interface{}{}
+	file_sstable_proto_msgTypes[8].OneofWrappers = []interface{}{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_sstable_proto_rawDesc,
 			NumEnums:      2,
-			NumMessages:   4,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
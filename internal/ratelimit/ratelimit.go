@@ -0,0 +1,203 @@
+package ratelimit
+
+import (
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Largest write passed through a limited writer at once, so that throttled writes are
+// spread over time rather than slept for up front
+const chunkSize = 32 << 10
+
+// Default interval between two auto-tune adjustments
+const defaultTuneInterval = time.Second
+
+// RateLimiter is a token bucket limiting background I/O to a number of bytes per second.
+//
+// Tokens accumulate at the configured rate, up to one second's worth. A request larger
+// than the available tokens takes them on credit and sleeps until the debt is repaid.
+// A nil *RateLimiter does not limit, so callers never need to check for one.
+type RateLimiter struct {
+	rate   int64      // Bytes per second, 0 disables the limit
+	tokens float64    // Available bytes, negative while requests are owed
+	last   time.Time  // Time tokens were last added
+	tuner  *autoTuner // nil unless auto-tune is enabled
+	mut    sync.Mutex
+}
+
+// AutoTuneOpts configures how the rate follows foreground latency
+type AutoTuneOpts struct {
+	Min_rate       int64         // Lowest rate in bytes per second
+	Max_rate       int64         // Highest rate in bytes per second
+	Target_latency time.Duration // Average foreground latency above which the rate is halved
+	Interval       time.Duration // Time between adjustments. Defaults to 1s
+}
+
+// Foreground latency samples of the current interval
+type autoTuner struct {
+	opts     AutoTuneOpts
+	total    time.Duration // Sum of the latencies recorded this interval
+	samples  int64         // Number of latencies recorded this interval
+	lastTune time.Time
+}
+
+// Create a limiter allowing bytesPerSecond bytes per second. 0 disables the limit.
+func New(bytesPerSecond int64) *RateLimiter {
+	return &RateLimiter{rate: bytesPerSecond, tokens: float64(bytesPerSecond), last: time.Now()}
+}
+
+// Returns the current rate in bytes per second
+func (r *RateLimiter) Rate() int64 {
+	if r == nil {
+		return 0
+	}
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.rate
+}
+
+// SetRate changes the rate in bytes per second, taking effect for the next request. 0 disables the limit.
+func (r *RateLimiter) SetRate(bytesPerSecond int64) {
+	if r == nil {
+		return
+	}
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.setRate(time.Now(), bytesPerSecond)
+}
+
+func (r *RateLimiter) setRate(now time.Time, bytesPerSecond int64) {
+	r.refill(now)
+	r.rate = bytesPerSecond
+	r.tokens = min(r.tokens, float64(bytesPerSecond))
+}
+
+// Add the tokens accumulated since the last refill
+func (r *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(r.last).Seconds()
+	r.last = now
+	r.tokens = min(r.tokens+elapsed*float64(r.rate), float64(r.rate))
+}
+
+// Request blocks until bytes of I/O are allowed
+func (r *RateLimiter) Request(bytes int64) {
+	if r == nil {
+		return
+	}
+	r.mut.Lock()
+	now := time.Now()
+	r.tune(now)
+	if r.rate <= 0 {
+		r.mut.Unlock()
+		return
+	}
+	r.refill(now)
+	r.tokens -= float64(bytes)
+	var wait time.Duration
+	if r.tokens < 0 {
+		wait = time.Duration(-r.tokens / float64(r.rate) * float64(time.Second))
+	}
+	r.mut.Unlock()
+	time.Sleep(wait)
+}
+
+// Writer returns a writer that requests each write from the limiter before passing it to w
+func (r *RateLimiter) Writer(w io.Writer) io.Writer {
+	if r == nil {
+		return w
+	}
+	return &limitedWriter{w: w, limiter: r}
+}
+
+type limitedWriter struct {
+	w       io.Writer
+	limiter *RateLimiter
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p[:min(len(p), chunkSize)]
+		lw.limiter.Request(int64(len(chunk)))
+		n, err := lw.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
+
+// Reader returns a reader that requests the bytes of each read from the limiter after reading them from rd
+func (r *RateLimiter) Reader(rd io.Reader) io.Reader {
+	if r == nil {
+		return rd
+	}
+	return &limitedReader{r: rd, limiter: r}
+}
+
+type limitedReader struct {
+	r       io.Reader
+	limiter *RateLimiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p[:min(len(p), chunkSize)])
+	lr.limiter.Request(int64(n))
+	return n, err
+}
+
+// EnableAutoTune makes the rate follow foreground latency reported with RecordLatency.
+//
+// Every interval the rate is halved if the average latency exceeded the target, and raised
+// by a tenth otherwise, staying between the minimum and maximum rates.
+func (r *RateLimiter) EnableAutoTune(opts AutoTuneOpts) {
+	if r == nil {
+		return
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultTuneInterval
+	}
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	now := time.Now()
+	r.tuner = &autoTuner{opts: opts, lastTune: now}
+	r.setRate(now, min(max(r.rate, opts.Min_rate), opts.Max_rate))
+}
+
+// RecordLatency reports the latency of a foreground operation to the auto-tuner
+func (r *RateLimiter) RecordLatency(latency time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if r.tuner == nil {
+		return
+	}
+	r.tuner.total += latency
+	r.tuner.samples++
+	r.tune(time.Now())
+}
+
+// Adjust the rate once per interval. Caller must hold r.mut.
+func (r *RateLimiter) tune(now time.Time) {
+	t := r.tuner
+	if t == nil || now.Sub(t.lastTune) < t.opts.Interval {
+		return
+	}
+	rate := r.rate
+	if t.samples > 0 && t.total/time.Duration(t.samples) > t.opts.Target_latency {
+		rate = max(rate/2, t.opts.Min_rate)
+	} else {
+		rate = min(rate+max(rate/10, 1), t.opts.Max_rate)
+	}
+	if rate != r.rate {
+		slog.Debug("Rate limit tuned", "from", r.rate, "to", rate, "samples", t.samples)
+		r.setRate(now, rate)
+	}
+	t.total, t.samples, t.lastTune = 0, 0, now
+}
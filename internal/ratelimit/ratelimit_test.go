@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRequest(t *testing.T) {
+	t.Run("Test nil limiter", func(t *testing.T) {
+		var limiter *RateLimiter
+		limiter.Request(1 << 30)
+		limiter.RecordLatency(time.Second)
+		limiter.SetRate(1 << 20)
+		limiter.EnableAutoTune(AutoTuneOpts{Min_rate: 1, Max_rate: 1 << 20})
+		if rate := limiter.Rate(); rate != 0 {
+			t.Errorf("Expected no limit, found %v", rate)
+		}
+	})
+
+	t.Run("Test burst", func(t *testing.T) {
+		limiter := New(1 << 20)
+		start := time.Now()
+		limiter.Request(1 << 20)
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Errorf("One second of tokens should be available at once, waited %v", elapsed)
+		}
+	})
+
+	t.Run("Test throttled", func(t *testing.T) {
+		limiter := New(1 << 20)
+		start := time.Now()
+		limiter.Request(1 << 20)
+		limiter.Request(1 << 19)
+		if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+			t.Errorf("Expected to wait about 500ms, waited %v", elapsed)
+		}
+	})
+
+	t.Run("Test set rate", func(t *testing.T) {
+		limiter := New(1 << 10)
+		limiter.SetRate(0)
+		start := time.Now()
+		limiter.Request(1 << 30)
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Errorf("A rate of 0 should not limit, waited %v", elapsed)
+		}
+		limiter.SetRate(1 << 20)
+		if rate := limiter.Rate(); rate != 1<<20 {
+			t.Errorf("Expected rate %v, found %v", 1<<20, rate)
+		}
+	})
+}
+
+func TestWriter(t *testing.T) {
+	limiter := New(1 << 20)
+	var buf bytes.Buffer
+	data := bytes.Repeat([]byte{1}, 3<<19)
+	start := time.Now()
+	n, err := limiter.Writer(&buf).Write(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(data) || !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("Expected %v bytes written, found %v", len(data), n)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected to wait about 500ms, waited %v", elapsed)
+	}
+}
+
+func TestReader(t *testing.T) {
+	limiter := New(1 << 20)
+	data := bytes.Repeat([]byte{1}, 3<<19)
+	start := time.Now()
+	read, err := io.ReadAll(limiter.Reader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(read, data) {
+		t.Errorf("Expected %v bytes read, found %v", len(data), len(read))
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected to wait about 500ms, waited %v", elapsed)
+	}
+}
+
+func TestAutoTune(t *testing.T) {
+	limiter := New(1000)
+	limiter.EnableAutoTune(AutoTuneOpts{Min_rate: 100, Max_rate: 2000, Target_latency: time.Millisecond, Interval: 10 * time.Millisecond})
+
+	// Latency above target halves the rate, down to the minimum
+	for i := 0; i < 5; i++ {
+		limiter.RecordLatency(5 * time.Millisecond)
+		time.Sleep(11 * time.Millisecond)
+	}
+	limiter.RecordLatency(5 * time.Millisecond)
+	if rate := limiter.Rate(); rate != 100 {
+		t.Errorf("Expected the rate to back off to 100, found %v", rate)
+	}
+
+	// Latency under target raises it again
+	time.Sleep(11 * time.Millisecond)
+	limiter.RecordLatency(0)
+	if rate := limiter.Rate(); rate != 110 {
+		t.Errorf("Expected the rate to rise to 110, found %v", rate)
+	}
+}
@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"context"
+
+	lsm "github.com/dillonkmcquade/gostore/internal/lsm"
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Maintenance operations on the tree served by a GoStoreRPC
+type AdminRPC struct {
+	pb.UnimplementedGoStoreAdminServer
+	tree lsm.LSM
+}
+
+// Returns the admin service of the tree served by r
+func (r *GoStoreRPC) Admin() *AdminRPC {
+	return &AdminRPC{tree: r.tree}
+}
+
+func (a *AdminRPC) Flush(ctx context.Context, in *pb.FlushRequest) (*pb.AdminReply, error) {
+	done := make(chan error, 1)
+	go func() { done <- a.tree.Flush() }()
+	return adminReply(ctx, done)
+}
+
+func (a *AdminRPC) CompactRange(ctx context.Context, in *pb.CompactRangeRequest) (*pb.AdminReply, error) {
+	// Empty bounds leave the range open
+	var start, end []byte
+	if len(in.Start) > 0 {
+		start = in.Start
+	}
+	if len(in.End) > 0 {
+		end = in.End
+	}
+	done := make(chan error, 1)
+	go func() { done <- a.tree.CompactRange(start, end) }()
+	return adminReply(ctx, done)
+}
+
+// Waits for an admin operation. The operation keeps running if ctx ends first.
+func adminReply(ctx context.Context, done <-chan error) (*pb.AdminReply, error) {
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return &pb.AdminReply{Status: int32(codes.OK), Message: "Success"}, nil
+	case <-ctx.Done():
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return nil, status.Error(codes.DeadlineExceeded, "Exceeded time limit")
+		case context.Canceled:
+			return nil, status.Error(codes.Canceled, "Request Cancelled")
+		}
+	}
+	return nil, nil
+}
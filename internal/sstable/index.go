@@ -0,0 +1,373 @@
+package sstable
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/dillonkmcquade/gostore/internal/cache"
+	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Default target sizes of a data block and of an index partition, in bytes
+const (
+	defaultBlockSize     = 4 << 10
+	defaultPartitionSize = 4 << 10
+)
+
+// Encodes the table file. The entries are grouped into data blocks, indexed by index
+// partitions, which are indexed in turn by a top level index in the filter block beside
+// the table properties. A table with more than one index partition stores a filter
+// partition of the keys of each index partition instead of one filter, so that a lookup
+// reads a few small blocks whatever the size of the table.
+//
+// Partitions are blocks fields of the table message, so the file still decodes as a table.
+func (table *SSTable) encode() ([]byte, *pb.SSTable_Index, error) {
+	if table.Filter == nil {
+		// There is no filter block to hold an index
+		b, err := proto.Marshal(&pb.SSTable{Entries: table.Entries})
+		return b, nil, err
+	}
+	var b []byte
+	blocks := []*pb.SSTable_Index_Entry{}
+	ends := []int{} // Number of entries up to the end of each data block
+	start := 0
+	for i, entry := range table.Entries {
+		encoded, err := proto.Marshal(entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		b = protowire.AppendTag(b, entriesField, protowire.BytesType)
+		b = protowire.AppendBytes(b, encoded)
+		if len(b)-start >= table.blockSize() || i == len(table.Entries)-1 {
+			blocks = append(blocks, &pb.SSTable_Index_Entry{
+				LastKey: entry.Key,
+				Block:   &pb.SSTable_BlockHandle{Offset: uint64(start), Length: uint64(len(b) - start)},
+			})
+			ends = append(ends, i+1)
+			start = len(b)
+		}
+	}
+
+	dataSize := len(b)
+	partitions := partitionIndex(blocks, table.partitionSize())
+	partitioned := len(partitions) > 1
+	top := &pb.SSTable_Index{}
+	first, numBlocks := 0, 0
+	indexSize, filterSize := 0, 0
+	for _, partition := range partitions {
+		encoded, err := proto.Marshal(&pb.SSTable_Index{Entries: partition})
+		if err != nil {
+			return nil, nil, err
+		}
+		entry := &pb.SSTable_Index_Entry{LastKey: partition[len(partition)-1].LastKey}
+		b, entry.Block = appendBlock(b, encoded)
+		indexSize += len(encoded)
+		numBlocks += len(partition)
+		last := ends[numBlocks-1]
+		if partitioned {
+			encoded, err = table.encodeFilterPartition(table.Entries[first:last])
+			if err != nil {
+				return nil, nil, err
+			}
+			b, entry.Filter = appendBlock(b, encoded)
+			filterSize += len(encoded)
+		}
+		first = last
+		top.Entries = append(top.Entries, entry)
+	}
+
+	var data []byte
+	var err error
+	if !partitioned {
+		data, err = table.Filter.MarshalBinary()
+		if err != nil {
+			return nil, nil, fmt.Errorf("filter.MarshalBinary: %w", err)
+		}
+	}
+	p := &pb.SSTable{Filter: filterProto(table.Filter.Metadata(), data), Index: top}
+	if table.ranges != nil {
+		p.RangeFilter, err = table.ranges.MarshalBinary()
+		if err != nil {
+			return nil, nil, fmt.Errorf("ranges.MarshalBinary: %w", err)
+		}
+	}
+	p.Properties, err = table.collectProperties(dataSize, indexSize+proto.Size(top), filterSize+len(data)+len(p.RangeFilter))
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err = appendFilterBlock(b, p)
+	return b, top, err
+}
+
+func (table *SSTable) blockSize() int {
+	if table.BlockSize <= 0 {
+		return defaultBlockSize
+	}
+	return table.BlockSize
+}
+
+func (table *SSTable) partitionSize() int {
+	if table.PartitionSize <= 0 {
+		return defaultPartitionSize
+	}
+	return table.PartitionSize
+}
+
+// Groups the index entries of the data blocks into partitions of about size encoded bytes
+func partitionIndex(blocks []*pb.SSTable_Index_Entry, size int) [][]*pb.SSTable_Index_Entry {
+	partitions := [][]*pb.SSTable_Index_Entry{}
+	partition := []*pb.SSTable_Index_Entry{}
+	partitionSize := 0
+	for _, block := range blocks {
+		partition = append(partition, block)
+		partitionSize += proto.Size(block)
+		if partitionSize >= size {
+			partitions = append(partitions, partition)
+			partition = []*pb.SSTable_Index_Entry{}
+			partitionSize = 0
+		}
+	}
+	if len(partition) > 0 {
+		partitions = append(partitions, partition)
+	}
+	return partitions
+}
+
+// Appends block as a blocks field and returns the handle of its contents
+func appendBlock(b []byte, block []byte) ([]byte, *pb.SSTable_BlockHandle) {
+	b = protowire.AppendTag(b, blocksField, protowire.BytesType)
+	b = protowire.AppendVarint(b, uint64(len(block)))
+	handle := &pb.SSTable_BlockHandle{Offset: uint64(len(b)), Length: uint64(len(block))}
+	return append(b, block...), handle
+}
+
+// Encodes a filter of the keys of entries, of the type and bits per key of the table filter
+func (table *SSTable) encodeFilterPartition(entries []*pb.SSTable_Entry) ([]byte, error) {
+	meta := table.Filter.Metadata()
+	opts := &filter.Opts{Type: meta.Type, Bits_per_key: max(int(meta.Size/uint64(len(table.Entries))), 1)}
+	partition := filter.NewForKeys(opts, len(entries))
+	for _, entry := range entries {
+		partition.Add(entry.Key)
+	}
+	data, err := partition.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("filter.MarshalBinary: %w", err)
+	}
+	return proto.Marshal(filterProto(partition.Metadata(), data))
+}
+
+// Reports whether the table has a filter partition per index partition instead of one filter
+func (table *SSTable) partitionedFilter() bool {
+	return table.index != nil && len(table.index.Entries) > 0 && table.index.Entries[0].Filter != nil
+}
+
+// Returns the position of the first entry of index whose last key is at least key, or
+// the number of entries if key is after every block
+func seekIndex(index *pb.SSTable_Index, key []byte) int {
+	return sort.Search(len(index.Entries), func(i int) bool { return bytes.Compare(index.Entries[i].LastKey, key) >= 0 })
+}
+
+// Searches the one data block that may hold key, found through the top level index and
+// an index partition
+func (table *SSTable) getIndexed(key []byte) ([]byte, bool, error) {
+	p := seekIndex(table.index, key)
+	if p == len(table.index.Entries) {
+		return []byte{}, false, nil
+	}
+	partition, err := table.readBlock(table.index.Entries[p].Block, decodeIndex)
+	if err != nil {
+		return nil, false, err
+	}
+	index := partition.(*pb.SSTable_Index)
+	i := seekIndex(index, key)
+	if i == len(index.Entries) {
+		return []byte{}, false, nil
+	}
+	block, err := table.readBlock(index.Entries[i].Block, decodeDataBlock)
+	if err != nil {
+		return nil, false, err
+	}
+	entries := block.([]*pb.SSTable_Entry)
+	idx, found := sort.Find(len(entries), func(i int) int { return slices.Compare(key, entries[i].Key) })
+	if found {
+		return entries[idx].Value, true, nil
+	}
+	return []byte{}, false, nil
+}
+
+// Checks the filter partition of the index partition that may hold key
+func (table *SSTable) partitionMayContain(key []byte) bool {
+	p := seekIndex(table.index, key)
+	if p == len(table.index.Entries) {
+		return false
+	}
+	partition, err := table.readBlock(table.index.Entries[p].Filter, decodeFilterPartition)
+	if err != nil {
+		slog.Warn("Failed to read filter partition", "filename", table.Name, "cause", err)
+		return true
+	}
+	return partition.(filter.Filter).Has(key)
+}
+
+// Reads the block at handle through the block cache, decoded by decode
+func (table *SSTable) readBlock(handle *pb.SSTable_BlockHandle, decode func([]byte) (any, error)) (any, error) {
+	key := cache.Key{File: table.Name, Offset: handle.GetOffset()}
+	return table.Cache.GetOrLoad(key, func() (any, int64, error) {
+		file, err := os.Open(table.Name)
+		if err != nil {
+			return nil, 0, fmt.Errorf("os.Open: %w", err)
+		}
+		defer file.Close()
+		b := make([]byte, handle.GetLength())
+		_, err = file.ReadAt(b, int64(handle.GetOffset()))
+		if err != nil {
+			return nil, 0, fmt.Errorf("file.ReadAt: %w", err)
+		}
+		value, err := decode(b)
+		return value, int64(len(b)), err
+	})
+}
+
+func decodeIndex(b []byte) (any, error) {
+	index := &pb.SSTable_Index{}
+	err := proto.Unmarshal(b, index)
+	if err != nil {
+		return nil, fmt.Errorf("proto.Unmarshal: %w", err)
+	}
+	return index, nil
+}
+
+// Decodes the entries fields of a data block
+func decodeDataBlock(b []byte) (any, error) {
+	entries := []*pb.SSTable_Entry{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		if num != entriesField || typ != protowire.BytesType {
+			return nil, fmt.Errorf("unexpected field %v in data block", num)
+		}
+		b = b[n:]
+		encoded, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		entry, err := decodeEntry(encoded)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func decodeFilterPartition(b []byte) (any, error) {
+	p := &pb.SSTable_Filter{}
+	err := proto.Unmarshal(b, p)
+	if err != nil {
+		return nil, fmt.Errorf("proto.Unmarshal: %w", err)
+	}
+	partition := filter.Open(filterMetadata(p))
+	err = partition.UnmarshalBinary(p.GetData())
+	if err != nil {
+		return nil, fmt.Errorf("filter.UnmarshalBinary: %w", err)
+	}
+	return partition, nil
+}
+
+// ApproximateRange estimates the bytes and the number of entries of the table in
+// [start, end) from its bounds and its index, without reading data blocks. A nil start or
+// end leaves that side open. A data block holding start or end counts for half, and tables
+// written without an index count in full when their bounds overlap the range.
+func (table *SSTable) ApproximateRange(start []byte, end []byte) (int64, int64, error) {
+	if (start != nil && bytes.Compare(start, table.Last) > 0) || (end != nil && bytes.Compare(end, table.First) <= 0) {
+		return 0, 0, nil
+	}
+	if bytes.Compare(start, table.First) <= 0 && (end == nil || bytes.Compare(end, table.Last) > 0) {
+		return table.Size, table.NumEntries, nil
+	}
+	table.ensureFilters()
+	if table.index == nil || len(table.index.Entries) == 0 {
+		return table.Size, table.NumEntries, nil
+	}
+	dataSize, err := table.blockOffset(nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	from, to := uint64(0), dataSize
+	if bytes.Compare(start, table.First) > 0 {
+		from, err = table.blockOffset(start)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if end != nil && bytes.Compare(end, table.Last) <= 0 {
+		to, err = table.blockOffset(end)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if to <= from || dataSize == 0 {
+		return 0, 0, nil
+	}
+	// Index and filter bytes are charged in proportion to the data
+	fraction := float64(to-from) / float64(dataSize)
+	return int64(fraction * float64(table.Size)), int64(fraction * float64(table.NumEntries)), nil
+}
+
+// Returns the middle of the data block that would hold key, or the end of the data blocks
+// if key is nil or after every block
+func (table *SSTable) blockOffset(key []byte) (uint64, error) {
+	p := len(table.index.Entries) - 1
+	if key != nil {
+		p = seekIndex(table.index, key)
+	}
+	if p == len(table.index.Entries) {
+		key, p = nil, p-1
+	}
+	partition, err := table.readBlock(table.index.Entries[p].Block, decodeIndex)
+	if err != nil {
+		return 0, err
+	}
+	index := partition.(*pb.SSTable_Index)
+	i := len(index.Entries)
+	if key != nil {
+		i = seekIndex(index, key)
+	}
+	if i == len(index.Entries) {
+		last := index.Entries[len(index.Entries)-1].Block
+		return last.GetOffset() + last.GetLength(), nil
+	}
+	block := index.Entries[i].Block
+	return block.GetOffset() + block.GetLength()/2, nil
+}
+
+// BlockKeys returns the last key of each data block in key order, read from the index
+// partitions without reading data blocks. Tables written without an index return their
+// last key. Blocks hold a similar number of bytes, so the keys split the table evenly.
+func (table *SSTable) BlockKeys() ([][]byte, error) {
+	table.ensureFilters()
+	if table.index == nil || len(table.index.Entries) == 0 {
+		return [][]byte{table.Last}, nil
+	}
+	keys := [][]byte{}
+	for _, entry := range table.index.Entries {
+		partition, err := table.readBlock(entry.Block, decodeIndex)
+		if err != nil {
+			return nil, err
+		}
+		for _, block := range partition.(*pb.SSTable_Index).Entries {
+			keys = append(keys, block.LastKey)
+		}
+	}
+	return keys, nil
+}
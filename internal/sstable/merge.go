@@ -0,0 +1,126 @@
+package sstable
+
+import (
+	"bytes"
+	"container/heap"
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/ratelimit"
+)
+
+// Return sorted output stream of SSTable_Entry from an arbitrary number of tables.
+//
+// Tables are given oldest first. When several tables hold a key, only the entry of the
+// newest table is returned.
+func Merge(tables ...*SSTable) <-chan *pb.SSTable_Entry {
+	return MergeRange(nil, nil, nil, tables...)
+}
+
+// MergeLimited is Merge with the tables read from disk throttled by limiter
+func MergeLimited(limiter *ratelimit.RateLimiter, tables ...*SSTable) <-chan *pb.SSTable_Entry {
+	return MergeRange(limiter, nil, nil, tables...)
+}
+
+// MergeRange is MergeLimited restricted to the keys in [start, end). A nil bound leaves
+// that side open.
+//
+// Tables are streamed through a TableReader each and merged with a heap holding the
+// smallest unmerged entry of every table, so memory use depends on the number of tables
+// rather than their size.
+func MergeRange(limiter *ratelimit.RateLimiter, start []byte, end []byte, tables ...*SSTable) <-chan *pb.SSTable_Entry {
+	sources := make(mergeHeap, 0, len(tables))
+	for age, table := range tables {
+		reader, err := table.NewReader(limiter)
+		if err != nil {
+			slog.Error("merge: error opening table", "filename", table.Name)
+			panic(err)
+		}
+		if start != nil {
+			err = reader.Seek(start)
+			if err != nil {
+				slog.Error("merge: error reading table", "filename", table.Name)
+				panic(err)
+			}
+		}
+		source := &mergeSource{reader: reader, age: age, name: table.Name}
+		if source.advance(end) {
+			sources = append(sources, source)
+		}
+	}
+	heap.Init(&sources)
+
+	out := make(chan *pb.SSTable_Entry)
+	go func() {
+		defer close(out)
+		var last *pb.SSTable_Entry
+		for sources.Len() > 0 {
+			top := sources[0]
+			// Older entries of a key follow the newest one
+			if last == nil || !bytes.Equal(top.entry.Key, last.Key) {
+				out <- top.entry
+				last = top.entry
+			}
+			if top.advance(end) {
+				heap.Fix(&sources, 0)
+			} else {
+				heap.Pop(&sources)
+			}
+		}
+	}()
+	return out
+}
+
+// Table being merged
+type mergeSource struct {
+	reader *TableReader
+	entry  *pb.SSTable_Entry // Smallest entry of the table not yet merged
+	age    int               // Position of the table in the merge, higher is newer
+	name   string
+}
+
+// Read the next entry of the source. Returns false and closes the reader once the table
+// has no entries left before end.
+func (s *mergeSource) advance(end []byte) bool {
+	entry, err := s.reader.Next()
+	if err != nil && !errors.Is(err, io.EOF) {
+		slog.Error("merge: error reading table", "filename", s.name)
+		panic(err)
+	}
+	if err != nil || (end != nil && bytes.Compare(entry.Key, end) >= 0) {
+		s.entry = nil
+		err = s.reader.Close()
+		if err != nil {
+			slog.Error("merge: error closing table", "filename", s.name)
+			panic(err)
+		}
+		return false
+	}
+	s.entry = entry
+	return true
+}
+
+// Min-heap of sources by key, the newest source first among equal keys
+type mergeHeap []*mergeSource
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	if c := bytes.Compare(h[i].entry.Key, h[j].entry.Key); c != 0 {
+		return c < 0
+	}
+	return h[i].age > h[j].age
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x any) { *h = append(*h, x.(*mergeSource)) }
+
+func (h *mergeHeap) Pop() any {
+	old := *h
+	source := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return source
+}
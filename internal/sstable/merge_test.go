@@ -0,0 +1,91 @@
+package sstable
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/dillonkmcquade/gostore/internal/pb"
+)
+
+func TestMerge(t *testing.T) {
+	total := 0
+	for _, tbl := range []*SSTable{t1, t2} {
+		total += len(tbl.Entries)
+	}
+
+	merged := Merge(t1, t2)
+	count := 0
+
+	for range merged {
+		count++
+	}
+	if count != total {
+		t.Errorf("%v should equal %v", count, total)
+	}
+}
+
+// Returns entries for keys [first, first+count) with values tagged by version
+func versionedEntries(first int, count int, version string) []*pb.SSTable_Entry {
+	entries := []*pb.SSTable_Entry{}
+	for key := first; key < first+count; key++ {
+		entries = append(entries, &pb.SSTable_Entry{
+			Op:    pb.Operation_OPERATION_INSERT,
+			Key:   []byte(fmt.Sprintf("%08d", key)),
+			Value: []byte(version),
+		})
+	}
+	return entries
+}
+
+func TestMergeRecency(t *testing.T) {
+	tmp := t.TempDir()
+	oldest := newSyncedTable(t, tmp, versionedEntries(0, 100, "old"))
+	middle := &SSTable{Entries: versionedEntries(50, 100, "middle")}
+	newest := newSyncedTable(t, tmp, versionedEntries(90, 20, "new"))
+
+	count := 0
+	var last []byte
+	for entry := range Merge(oldest, middle, newest) {
+		if last != nil && bytes.Compare(last, entry.Key) >= 0 {
+			t.Fatalf("Expected increasing keys, found %s after %s", entry.Key, last)
+		}
+		last = entry.Key
+
+		var key int
+		fmt.Sscanf(string(entry.Key), "%d", &key)
+		expected := "old"
+		if key >= 90 && key < 110 {
+			expected = "new"
+		} else if key >= 50 {
+			expected = "middle"
+		}
+		if string(entry.Value) != expected {
+			t.Errorf("Expected %v to come from the %v table, found %s", key, expected, entry.Value)
+		}
+		count++
+	}
+	if count != 150 {
+		t.Errorf("Expected 150 distinct keys, found %v", count)
+	}
+}
+
+func TestMergeRange(t *testing.T) {
+	tmp := t.TempDir()
+	tables := []*SSTable{
+		newSyncedTable(t, tmp, versionedEntries(0, 100, "a")),
+		newSyncedTable(t, tmp, versionedEntries(40, 100, "b")),
+	}
+	start, end := []byte(fmt.Sprintf("%08d", 30)), []byte(fmt.Sprintf("%08d", 60))
+
+	count := 0
+	for entry := range MergeRange(nil, start, end, tables...) {
+		if bytes.Compare(entry.Key, start) < 0 || bytes.Compare(entry.Key, end) >= 0 {
+			t.Errorf("Expected keys in [%s, %s), found %s", start, end, entry.Key)
+		}
+		count++
+	}
+	if count != 30 {
+		t.Errorf("Expected 30 entries, found %v", count)
+	}
+}
@@ -0,0 +1,146 @@
+package sstable
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/dillonkmcquade/gostore/internal/pb"
+)
+
+// Properties describes the contents of a table, read from its properties block without
+// scanning its entries
+type Properties struct {
+	NumEntries    int64             // Number of entries, deletes included
+	NumTombstones int64             // Number of deletes
+	RawKeySize    int64             // Bytes of the keys
+	RawValueSize  int64             // Bytes of the values
+	DataSize      int64             // Bytes of the encoded entries
+	IndexSize     int64             // Bytes of the index partitions and the top level index
+	FilterSize    int64             // Bytes of the filter or filter partitions, and of the range filter
+	FileSize      int64             // Bytes of the table file
+	MinSequence   uint64            // Oldest run whose writes the table holds
+	MaxSequence   uint64            // Newest run whose writes the table holds
+	CreatedOn     time.Time         // Time the table was written
+	User          map[string]string // Properties added by the collectors of the table
+}
+
+// Returns the raw key and value bytes per byte of encoded entries. Entries are not
+// compressed, so the ratio is below 1 by the encoding overhead.
+func (p *Properties) CompressionRatio() float64 {
+	if p.DataSize == 0 {
+		return 0
+	}
+	return float64(p.RawKeySize+p.RawValueSize) / float64(p.DataSize)
+}
+
+// TablePropertiesCollector gathers custom properties of a table while it is written. The
+// properties it returns are stored in the table file with the table properties.
+type TablePropertiesCollector interface {
+	Add(key []byte, value []byte, deleted bool) // Called for each entry, in key order
+	Finish() map[string]string                  // Called once every entry is added
+}
+
+// TablePropertiesCollectorFactory creates the collector of one table
+type TablePropertiesCollectorFactory func() TablePropertiesCollector
+
+// Computes the properties block of the table from its entries, passing them through the
+// collectors of the table
+func (table *SSTable) collectProperties(dataSize int, indexSize int, filterSize int) (*pb.SSTable_Properties, error) {
+	createdOn, err := table.CreatedOn.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	p := &pb.SSTable_Properties{
+		NumEntries: int64(len(table.Entries)),
+		DataSize:   int64(dataSize),
+		IndexSize:  int64(indexSize),
+		FilterSize: int64(filterSize),
+		CreatedOn:  createdOn,
+	}
+	p.MinSequence, p.MaxSequence = table.Sequences()
+	collectors := make([]TablePropertiesCollector, 0, len(table.Collectors))
+	for _, factory := range table.Collectors {
+		collectors = append(collectors, factory())
+	}
+	for _, entry := range table.Entries {
+		deleted := entry.Op == pb.Operation_OPERATION_DELETE
+		if deleted {
+			p.NumTombstones++
+		}
+		p.RawKeySize += int64(len(entry.Key))
+		p.RawValueSize += int64(len(entry.Value))
+		for _, collector := range collectors {
+			collector.Add(entry.Key, entry.Value, deleted)
+		}
+	}
+	user := map[string]string{}
+	for _, collector := range collectors {
+		// Later collectors win a property set by several
+		maps.Copy(user, collector.Finish())
+	}
+	names := make([]string, 0, len(user))
+	for name := range user {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		p.User = append(p.User, &pb.SSTable_Properties_UserProperty{Name: name, Value: user[name]})
+	}
+	return p, nil
+}
+
+// ReadProperties reads the properties block of the table file. Tables written before
+// properties blocks report the counts recorded in the manifest, and tables written before
+// their run was known, e.g. by an SSTWriter, the sequences recorded in the manifest.
+func (table *SSTable) ReadProperties() (*Properties, error) {
+	props := &Properties{
+		NumEntries:    table.NumEntries,
+		NumTombstones: table.NumTombstones,
+		FileSize:      table.Size,
+		CreatedOn:     table.CreatedOn,
+		User:          map[string]string{},
+	}
+	props.MinSequence, props.MaxSequence = table.Sequences()
+	block, err := table.readFilterBlock()
+	if errors.Is(err, ErrNoFilter) {
+		return props, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := block.Properties
+	if p == nil {
+		return props, nil
+	}
+	props.NumEntries = p.NumEntries
+	props.NumTombstones = p.NumTombstones
+	props.RawKeySize = p.RawKeySize
+	props.RawValueSize = p.RawValueSize
+	props.DataSize = p.DataSize
+	props.IndexSize = p.IndexSize
+	props.FilterSize = p.FilterSize
+	err = props.CreatedOn.UnmarshalBinary(p.CreatedOn)
+	if err != nil {
+		return nil, fmt.Errorf("CreatedOn.UnmarshalBinary: %w", err)
+	}
+	if p.MaxSequence > 0 {
+		props.MinSequence, props.MaxSequence = p.MinSequence, p.MaxSequence
+	}
+	for _, user := range p.User {
+		props.User[user.Name] = user.Value
+	}
+	return props, nil
+}
+
+// Sequences returns the oldest and newest run whose writes the table holds, 0 if its run
+// is not assigned yet. A flushed table holds the writes of its own run, a compacted table
+// those of its inputs.
+func (table *SSTable) Sequences() (uint64, uint64) {
+	if table.MaxSequence == 0 {
+		return table.Run, table.Run
+	}
+	return table.MinSequence, table.MaxSequence
+}
@@ -0,0 +1,117 @@
+package sstable
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/pb"
+)
+
+// Counts the deletes of a table and records its largest value
+type deleteCollector struct {
+	deletes  int
+	maxValue int
+}
+
+func (c *deleteCollector) Add(key []byte, value []byte, deleted bool) {
+	if deleted {
+		c.deletes++
+	}
+	c.maxValue = max(c.maxValue, len(value))
+}
+
+func (c *deleteCollector) Finish() map[string]string {
+	return map[string]string{"deletes": fmt.Sprint(c.deletes), "max_value": fmt.Sprint(c.maxValue)}
+}
+
+func TestProperties(t *testing.T) {
+	t.Run("Test properties block", func(t *testing.T) {
+		entries := append(testEntries(), &pb.SSTable_Entry{Op: pb.Operation_OPERATION_DELETE, Key: []byte{200}})
+		table := New(&Opts{
+			BloomOpts:  &filter.Opts{},
+			DestDir:    t.TempDir(),
+			Entries:    entries,
+			Collectors: []TablePropertiesCollectorFactory{func() TablePropertiesCollector { return &deleteCollector{} }},
+		})
+		if _, err := table.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		pto, err := table.ToProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := FromProto(pto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		props, err := decoded.ReadProperties()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var keySize, valueSize, deletes int64
+		maxValue := 0
+		for _, entry := range entries {
+			keySize += int64(len(entry.Key))
+			valueSize += int64(len(entry.Value))
+			maxValue = max(maxValue, len(entry.Value))
+			if entry.Op == pb.Operation_OPERATION_DELETE {
+				deletes++
+			}
+		}
+		if props.NumEntries != int64(len(entries)) || props.NumTombstones != deletes {
+			t.Errorf("Expected %v entries and %v tombstones, found %v and %v", len(entries), deletes, props.NumEntries, props.NumTombstones)
+		}
+		if props.RawKeySize != keySize || props.RawValueSize != valueSize {
+			t.Errorf("Expected %v key and %v value bytes, found %v and %v", keySize, valueSize, props.RawKeySize, props.RawValueSize)
+		}
+		if props.DataSize <= keySize+valueSize || props.IndexSize == 0 || props.FilterSize == 0 {
+			t.Errorf("Expected the sizes of the data, index and filter, found %+v", props)
+		}
+		if props.FileSize != table.Size || !props.CreatedOn.Equal(table.CreatedOn) {
+			t.Errorf("Expected the size and creation time of the table, found %v and %v", props.FileSize, props.CreatedOn)
+		}
+		if ratio := props.CompressionRatio(); ratio <= 0 || ratio >= 1 {
+			t.Errorf("Expected uncompressed entries to have a ratio in (0, 1), found %v", ratio)
+		}
+		if props.User["deletes"] != fmt.Sprint(deletes) || props.User["max_value"] != fmt.Sprint(maxValue) {
+			t.Errorf("Expected the collected properties, found %v", props.User)
+		}
+	})
+
+	t.Run("Test table without properties block", func(t *testing.T) {
+		// Tables written without a filter have no filter block to hold properties
+		table := &SSTable{Name: filepath.Join(t.TempDir(), "old.segment"), Entries: testEntries(), Run: 3}
+		if _, err := table.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		props, err := table.ReadProperties()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if props.NumEntries != table.NumEntries || props.NumTombstones != table.NumTombstones {
+			t.Errorf("Expected the recorded counts, found %+v", props)
+		}
+		if props.MinSequence != 3 || props.MaxSequence != 3 {
+			t.Errorf("Expected the run of the table as its sequences, found %v and %v", props.MinSequence, props.MaxSequence)
+		}
+	})
+	t.Run("Test sequences read from the file", func(t *testing.T) {
+		table := New(&Opts{BloomOpts: &filter.Opts{}, DestDir: t.TempDir(), Entries: testEntries()})
+		table.MinSequence, table.MaxSequence = 2, 5
+		if _, err := table.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		// A table opened from its file alone knows nothing of its manifest fields
+		file := &SSTable{Name: table.Name, Size: table.Size}
+		props, err := file.ReadProperties()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if props.MinSequence != 2 || props.MaxSequence != 5 {
+			t.Errorf("Expected sequences 2 and 5 from the properties block, found %v and %v", props.MinSequence, props.MaxSequence)
+		}
+	})
+}
@@ -0,0 +1,302 @@
+package sstable
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/ratelimit"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Size of the buffer a TableReader reads the file through
+const readBufferSize = 64 << 10
+
+// Field numbers of the encoded table and entry messages
+const (
+	entriesField      protowire.Number = 1  // SSTable.entries
+	keyField          protowire.Number = 1  // SSTable.Entry.key
+	filterOffsetField protowire.Number = 13 // SSTable.filter_offset
+	blocksField       protowire.Number = 16 // SSTable.blocks
+)
+
+// A table file ends with a footer, the filter_offset field: a one byte tag and a fixed64
+// holding the offset of the filter block
+const footerSize = 1 + 8
+
+// ErrNoFilter is returned when reading the filter of a table file written without one
+var ErrNoFilter = errors.New("table file has no filter block")
+
+// TableReader streams the entries of a table in key order, decoding one entry at a time
+// instead of loading the whole table. Readers of the same table may be used concurrently.
+type TableReader struct {
+	entries []*pb.SSTable_Entry // In-memory entries, read instead of the file if the table has them
+	pos     int                 // Index of the next in-memory entry
+	file    *os.File
+	reader  *bufio.Reader
+	buf     []byte            // Encoded entry, reused between reads
+	pending *pb.SSTable_Entry // Entry found by Seek, returned by the next call to Next
+}
+
+// NewReader returns a reader over the entries of table. Bytes read from disk are requested
+// from limiter, which may be nil.
+//
+// *** You must call Close() when done with the reader
+func (table *SSTable) NewReader(limiter *ratelimit.RateLimiter) (*TableReader, error) {
+	if len(table.Entries) > 0 {
+		return &TableReader{entries: table.Entries}, nil
+	}
+	file, err := os.Open(table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	return &TableReader{file: file, reader: bufio.NewReaderSize(limiter.Reader(file), readBufferSize)}, nil
+}
+
+// Next returns the next entry, or io.EOF once every entry has been read
+func (r *TableReader) Next() (*pb.SSTable_Entry, error) {
+	if r.pending != nil {
+		entry := r.pending
+		r.pending = nil
+		return entry, nil
+	}
+	if r.file == nil {
+		if r.pos >= len(r.entries) {
+			return nil, io.EOF
+		}
+		r.pos++
+		return r.entries[r.pos-1], nil
+	}
+	b, err := r.nextEncoded()
+	if err != nil {
+		return nil, err
+	}
+	return decodeEntry(b)
+}
+
+// NextKey returns the key of the next entry without decoding its value, or io.EOF once
+// every entry has been read
+func (r *TableReader) NextKey() ([]byte, error) {
+	if r.pending != nil || r.file == nil {
+		entry, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		return entry.Key, nil
+	}
+	b, err := r.nextEncoded()
+	if err != nil {
+		return nil, err
+	}
+	key, err := entryKey(b)
+	// The encoded entry is overwritten by the next read
+	return slices.Clone(key), err
+}
+
+// Seek skips the entries with keys less than key, so that Next returns the first entry
+// with a key greater than or equal to key
+func (r *TableReader) Seek(key []byte) error {
+	if r.pending != nil && bytes.Compare(r.pending.Key, key) >= 0 {
+		return nil
+	}
+	r.pending = nil
+	if r.file == nil {
+		r.pos += sort.Search(len(r.entries)-r.pos, func(i int) bool { return bytes.Compare(r.entries[r.pos+i].Key, key) >= 0 })
+		return nil
+	}
+	for {
+		b, err := r.nextEncoded()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		entryKey, err := entryKey(b)
+		if err != nil {
+			return err
+		}
+		if bytes.Compare(entryKey, key) >= 0 {
+			r.pending, err = decodeEntry(b)
+			return err
+		}
+	}
+}
+
+// Close closes the table file if the reader opened it
+func (r *TableReader) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	if err != nil {
+		return fmt.Errorf("file.Close: %w", err)
+	}
+	return nil
+}
+
+// Returns the next encoded entry, skipping the other fields of the table message.
+// The returned slice is only valid until the next read.
+func (r *TableReader) nextEncoded() ([]byte, error) {
+	for {
+		tag, err := binary.ReadUvarint(r.reader)
+		if err != nil {
+			// A clean end of file can only fall between two fields
+			return nil, err
+		}
+		num, typ := protowire.DecodeTag(tag)
+		switch typ {
+		case protowire.BytesType:
+			var length uint64
+			length, err = binary.ReadUvarint(r.reader)
+			if err != nil {
+				break
+			}
+			if num == entriesField {
+				r.buf = slices.Grow(r.buf[:0], int(length))[:length]
+				_, err = io.ReadFull(r.reader, r.buf)
+				if err == nil {
+					return r.buf, nil
+				}
+				break
+			}
+			_, err = r.reader.Discard(int(length))
+		case protowire.VarintType:
+			_, err = binary.ReadUvarint(r.reader)
+		case protowire.Fixed32Type:
+			_, err = r.reader.Discard(4)
+		case protowire.Fixed64Type:
+			_, err = r.reader.Discard(8)
+		default:
+			return nil, fmt.Errorf("unsupported wire type %v for field %v", typ, num)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func decodeEntry(b []byte) (*pb.SSTable_Entry, error) {
+	entry := &pb.SSTable_Entry{}
+	err := proto.Unmarshal(b, entry)
+	if err != nil {
+		return nil, fmt.Errorf("proto.Unmarshal: %w", err)
+	}
+	return entry, nil
+}
+
+// Returns the key of an encoded entry without decoding the rest of it. The key aliases b.
+func entryKey(b []byte) ([]byte, error) {
+	var key []byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == keyField && typ == protowire.BytesType {
+			// The last occurrence of a field wins
+			key, n = protowire.ConsumeBytes(b)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return key, nil
+}
+
+// Contents of the filter block of a table file
+type filterBlock struct {
+	filter filter.Filter       // Unloaded if the table has filter partitions instead
+	ranges *filter.RangeFilter // nil if the table has none
+	index  *pb.SSTable_Index   // Top level index, nil for tables written before indexes
+}
+
+// Reads the filters and the top level index from the filter block of the table file
+func (table *SSTable) readFilters() (*filterBlock, error) {
+	p, err := table.readFilterBlock()
+	if err != nil {
+		return nil, err
+	}
+	if p.Filter == nil {
+		return nil, ErrNoFilter
+	}
+	meta := filterMetadata(p.Filter)
+	meta.Name = ""
+	block := &filterBlock{filter: filter.Open(meta), index: p.Index}
+	partitioned := len(p.Index.GetEntries()) > 0 && p.Index.Entries[0].Filter != nil
+	if !partitioned {
+		err = block.filter.UnmarshalBinary(p.Filter.GetData())
+		if err != nil {
+			return nil, fmt.Errorf("filter.UnmarshalBinary: %w", err)
+		}
+	}
+	block.filter.SetLimiter(table.Limiter)
+	if p.RangeFilter == nil {
+		return block, nil
+	}
+	block.ranges = &filter.RangeFilter{}
+	err = block.ranges.UnmarshalBinary(p.RangeFilter)
+	if err != nil {
+		return nil, fmt.Errorf("ranges.UnmarshalBinary: %w", err)
+	}
+	return block, nil
+}
+
+// Reads the filter block at the end of the table file, located by the footer. Its fields
+// are those of the table message.
+func (table *SSTable) readFilterBlock() (*pb.SSTable, error) {
+	file, err := os.Open(table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("file.Stat: %w", err)
+	}
+	end := info.Size() - footerSize
+	if end < 0 {
+		return nil, ErrNoFilter
+	}
+	footer := make([]byte, footerSize)
+	_, err = file.ReadAt(footer, end)
+	if err != nil {
+		return nil, fmt.Errorf("file.ReadAt: %w", err)
+	}
+	num, typ, n := protowire.ConsumeTag(footer)
+	if n != 1 || num != filterOffsetField || typ != protowire.Fixed64Type {
+		return nil, ErrNoFilter
+	}
+	offset, _ := protowire.ConsumeFixed64(footer[n:])
+	if offset >= uint64(end) {
+		return nil, ErrNoFilter
+	}
+
+	encoded := make([]byte, uint64(end)-offset)
+	_, err = io.ReadFull(table.Limiter.Reader(io.NewSectionReader(file, int64(offset), int64(len(encoded)))), encoded)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadFull: %w", err)
+	}
+	p := &pb.SSTable{}
+	err = proto.Unmarshal(encoded, p)
+	if err != nil {
+		return nil, fmt.Errorf("proto.Unmarshal: %w", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,136 @@
+package sstable
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dillonkmcquade/gostore/internal/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Returns a table written to dir holding entries
+func newSyncedTable(t *testing.T, dir string, entries []*pb.SSTable_Entry) *SSTable {
+	table := &SSTable{
+		Entries:   entries,
+		Name:      filepath.Join(dir, GenerateUniqueSegmentName(time.Now())),
+		First:     entries[0].Key,
+		Last:      entries[len(entries)-1].Key,
+		CreatedOn: time.Now(),
+	}
+	if _, err := table.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+// Reads every remaining entry of reader
+func readAll(t *testing.T, reader *TableReader) []*pb.SSTable_Entry {
+	entries := []*pb.SSTable_Entry{}
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func TestTableReader(t *testing.T) {
+	tmp := t.TempDir()
+	synced := newSyncedTable(t, tmp, testEntries())
+	inMemory := &SSTable{Entries: testEntries()}
+
+	for name, table := range map[string]*SSTable{"file": synced, "in-memory": inMemory} {
+		t.Run("Test next "+name, func(t *testing.T) {
+			reader, err := table.NewReader(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+			entries := readAll(t, reader)
+			expected := testEntries()
+			if len(entries) != len(expected) {
+				t.Fatalf("Expected %v entries, found %v", len(expected), len(entries))
+			}
+			for i := range entries {
+				if !proto.Equal(entries[i], expected[i]) {
+					t.Errorf("Expected %v, found %v", expected[i], entries[i])
+				}
+			}
+		})
+
+		t.Run("Test seek "+name, func(t *testing.T) {
+			reader, err := table.NewReader(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+			if err := reader.Seek([]byte{2}); err != nil {
+				t.Fatal(err)
+			}
+			key, err := reader.NextKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(key, []byte{3}) {
+				t.Errorf("Expected key 3 after seeking to 2, found %v", key)
+			}
+			if err := reader.Seek([]byte{101}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := reader.Next(); !errors.Is(err, io.EOF) {
+				t.Errorf("Expected io.EOF after seeking past the last key, found %v", err)
+			}
+		})
+	}
+
+	t.Run("Test skips other fields", func(t *testing.T) {
+		run := uint64(7)
+		b, err := proto.Marshal(&pb.SSTable{Entries: testEntries(), Name: &synced.Name, Run: &run, First: []byte{0}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join(tmp, "fields.segment")
+		if err := os.WriteFile(name, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+		reader, err := (&SSTable{Name: name}).NewReader(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		if entries := readAll(t, reader); len(entries) != len(testEntries()) {
+			t.Errorf("Expected %v entries, found %v", len(testEntries()), len(entries))
+		}
+	})
+
+	t.Run("Test truncated", func(t *testing.T) {
+		b, err := os.ReadFile(synced.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join(tmp, "truncated.segment")
+		if err := os.WriteFile(name, b[:len(b)-3], 0600); err != nil {
+			t.Fatal(err)
+		}
+		reader, err := (&SSTable{Name: name}).NewReader(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		for err == nil {
+			_, err = reader.Next()
+		}
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Expected io.ErrUnexpectedEOF, found %v", err)
+		}
+	})
+}
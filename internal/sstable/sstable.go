@@ -1,60 +1,127 @@
 package sstable
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"slices"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dillonkmcquade/gostore/internal/assert"
+	"github.com/dillonkmcquade/gostore/internal/cache"
 	"github.com/dillonkmcquade/gostore/internal/filter"
 	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/ratelimit"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
 // SSTable represents a Sorted String Table. Entries are sorted by key.
 type SSTable struct {
 	Entries   []*pb.SSTable_Entry // A list of entries sorted by key
-	Filter    *filter.BloomFilter // Check if key could be in table
+	Filter    filter.Filter       // Check if key could be in table. Tables read from the manifest load it on first use
+	ranges    *filter.RangeFilter // Check if keys in an interval could be in table, nil if the table has none. Loaded with Filter
+	index     *pb.SSTable_Index   // Top level index of the index partitions, loaded with Filter. nil for tables written without one
 	file      *os.File            // pointer to os.File
 	Size      int64               // Size of file in bytes
 	Name      string              // full filename
 	First     []byte              // First key in range
 	Last      []byte              // Last key in range
 	CreatedOn time.Time           // Timestamp
+
+	NumEntries      int64        // Number of entries written to the table
+	NumTombstones   int64        // Number of delete entries written to the table
+	OldestTombstone time.Time    // Approximate time of the oldest delete in the table, zero if it has none
+	Run             uint64       // Sorted run the table belongs to, higher runs hold newer data
+	MinSequence     uint64       // Oldest run whose writes a compacted table holds, see Sequences
+	MaxSequence     uint64       // Newest run whose writes a compacted table holds, 0 for flushed tables
+	lastRead        atomic.Int64 // Unix nanoseconds of the last read, 0 if never read
+
+	filterLoad    sync.Once  // Loads Filter on the first lookup
+	filterMut     sync.Mutex // Guards replacing Filter, ranges and index while they load
+	filterMissing bool       // The filter could neither be loaded nor rebuilt, every key may be present
+
+	Limiter       *ratelimit.RateLimiter // Throttles Sync, may be nil
+	Cache         *cache.Cache           // Caches the blocks read by lookups, may be nil
+	BlockSize     int                    // Target bytes of a data block written by Sync. Defaults to 4KB
+	PartitionSize int                    // Target bytes of an index partition written by Sync. Defaults to 4KB
+
+	Collectors []TablePropertiesCollectorFactory // Add custom properties to the properties block written by Sync
 }
 
 type Opts struct {
-	BloomOpts *filter.Opts
-	DestDir   string
-	Entries   []*pb.SSTable_Entry
+	BloomOpts     *filter.Opts      // Filter of the table. Sized from Entries unless BloomOpts.Size is set
+	RangeOpts     *filter.RangeOpts // Range filter of the table, nil for none
+	DestDir       string
+	Entries       []*pb.SSTable_Entry
+	Limiter       *ratelimit.RateLimiter // Throttles Sync, may be nil
+	Cache         *cache.Cache           // Caches the blocks read by lookups, may be nil
+	BlockSize     int                    // Target bytes of a data block. Defaults to 4KB
+	PartitionSize int                    // Target bytes of an index partition. Tables with several partitions also partition their filter. Defaults to 4KB
+
+	Collectors []TablePropertiesCollectorFactory // Add custom properties to the properties block of the table
 }
 
+// New creates a table holding opts.Entries, with a filter of their keys. The filter is
+// written into the table file by Sync.
 func New(opts *Opts) *SSTable {
 	timestamp := time.Now()
-	return &SSTable{
+	table := &SSTable{
 		Name:      filepath.Join(opts.DestDir, GenerateUniqueSegmentName(timestamp)),
 		Entries:   opts.Entries,
-		Filter:    filter.New(opts.BloomOpts),
 		CreatedOn: timestamp,
+		Limiter:   opts.Limiter,
+		Cache:     opts.Cache,
+
+		BlockSize:     opts.BlockSize,
+		PartitionSize: opts.PartitionSize,
+		Collectors:    opts.Collectors,
+	}
+	table.filterLoad.Do(func() {})
+	bloomOpts := *opts.BloomOpts
+	bloomOpts.Path = ""
+	if bloomOpts.Size > 0 {
+		table.Filter = filter.New(&bloomOpts)
+	} else {
+		table.Filter = filter.NewForKeys(&bloomOpts, len(opts.Entries))
 	}
+	for _, entry := range opts.Entries {
+		table.Filter.Add(entry.Key)
+	}
+	if opts.RangeOpts != nil && len(opts.Entries) > 0 {
+		table.ranges = filter.NewRange(opts.RangeOpts, opts.Entries[0].Key, opts.Entries[len(opts.Entries)-1].Key, len(opts.Entries))
+		for _, entry := range opts.Entries {
+			table.ranges.Add(entry.Key)
+		}
+	}
+	if opts.Limiter != nil {
+		table.Filter.SetLimiter(opts.Limiter)
+	}
+	return table
 }
 
 // Test if table key range overlaps the key range of another
 func (table *SSTable) Overlaps(anotherTable *SSTable) bool {
-	case1 := (slices.Compare(table.First, anotherTable.First) >= 0 && slices.Compare(table.First, anotherTable.Last) <= 0)
-	case2 := (slices.Compare(table.Last, anotherTable.First) >= 0 && slices.Compare(table.Last, anotherTable.Last) <= 0)
-	return case1 || case2
+	return slices.Compare(table.First, anotherTable.Last) <= 0 && slices.Compare(anotherTable.First, table.Last) <= 0
 }
 
+// WriteTo writes the entries and their index and filter partitions, followed by the
+// filter block and the footer locating it
 func (table *SSTable) WriteTo(writer io.Writer) (int64, error) {
-	b, err := proto.Marshal(&pb.SSTable{Entries: table.Entries})
+	b, _, err := table.encode()
 	if err != nil {
 		return -1, err
 	}
+	return write(writer, b)
+}
+
+func write(writer io.Writer, b []byte) (int64, error) {
 	byteLength, err := writer.Write(b)
 	if err != nil {
 		return 0, fmt.Errorf("writer.Write: %w", err)
@@ -79,10 +146,20 @@ func (table *SSTable) Sync() (int64, error) {
 	}
 	defer fd.Close()
 
-	size, err := table.WriteTo(fd)
+	b, index, err := table.encode()
 	if err != nil {
 		return 0, err
 	}
+	size, err := write(table.Limiter.Writer(fd), b)
+	if err != nil {
+		return 0, err
+	}
+	table.index = index
+	if table.partitionedFilter() {
+		// Lookups read the filter partitions, the whole filter is no longer needed
+		table.setFilter(filter.Open(table.Filter.Metadata()))
+	}
+	table.countEntries()
 	err = fd.Sync()
 	if err != nil {
 		return 0, err
@@ -92,6 +169,41 @@ func (table *SSTable) Sync() (int64, error) {
 	return table.Size, err
 }
 
+// Record entry and tombstone counts before the entries are cleared
+func (table *SSTable) countEntries() {
+	table.NumEntries = int64(len(table.Entries))
+	table.NumTombstones = 0
+	for _, entry := range table.Entries {
+		if entry.Op == pb.Operation_OPERATION_DELETE {
+			table.NumTombstones++
+		}
+	}
+	if table.NumTombstones > 0 && table.OldestTombstone.IsZero() {
+		// Deletes were written before the table was created, use its creation time unless the caller set an older one
+		table.OldestTombstone = table.CreatedOn
+	}
+	if table.NumTombstones == 0 {
+		table.OldestTombstone = time.Time{}
+	}
+}
+
+// Returns the fraction of entries that are deletes
+func (table *SSTable) TombstoneDensity() float64 {
+	if table.NumEntries == 0 {
+		return 0
+	}
+	return float64(table.NumTombstones) / float64(table.NumEntries)
+}
+
+// Returns the time the table was last read, zero if it has not been read since it was loaded
+func (table *SSTable) LastRead() time.Time {
+	nanos := table.lastRead.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
 func (table *SSTable) updateSize(size int64) error {
 	table.Size += size
 	return nil
@@ -101,12 +213,186 @@ func (table *SSTable) clearEntries() {
 	table.Entries = []*pb.SSTable_Entry{}
 }
 
-func (table *SSTable) SaveFilter() error {
-	return table.Filter.Save()
+// Appends the filter block p, holding the filter, the range filter if any and the top level
+// index, and the footer to an encoded table. Both are fields of the table message, so the
+// file still decodes as a table.
+func appendFilterBlock(b []byte, p *pb.SSTable) ([]byte, error) {
+	block, err := proto.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	offset := len(b)
+	b = append(b, block...)
+	b = protowire.AppendTag(b, filterOffsetField, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, uint64(offset)), nil
 }
 
+// MayContain reports whether key may be in the table. The filter of a table read from the
+// manifest is loaded on the first call.
+func (table *SSTable) MayContain(key []byte) bool {
+	table.ensureFilters()
+	if table.filterMissing {
+		return true
+	}
+	if table.partitionedFilter() {
+		return table.partitionMayContain(key)
+	}
+	return table.Filter.Has(key)
+}
+
+// MayContainRange reports whether a key in [start, end) may be in the table, from its
+// bounds and its range filter. A nil end leaves the interval open.
+func (table *SSTable) MayContainRange(start []byte, end []byte) bool {
+	if slices.Compare(start, table.Last) > 0 || (end != nil && slices.Compare(end, table.First) <= 0) {
+		return false
+	}
+	table.ensureFilters()
+	return table.ranges == nil || table.ranges.MayContainRange(start, end)
+}
+
+// Loads the filters of a table read from the manifest, rebuilding the filter from the keys
+// if it cannot be read
+func (table *SSTable) ensureFilters() {
+	table.filterLoad.Do(func() {
+		err := table.LoadFilter()
+		if err == nil {
+			return
+		}
+		slog.Warn("Rebuilding table filter", "filename", table.Name, "cause", err)
+		err = table.RebuildFilter()
+		if err != nil {
+			slog.Error("Failed to rebuild table filter", "filename", table.Name, "cause", err)
+			table.filterMissing = true
+		}
+	})
+}
+
+// LoadFilter reads the filters from the table file, or the filter from its own file for
+// tables written before filters were embedded
 func (table *SSTable) LoadFilter() error {
-	return table.Filter.Load()
+	meta := table.FilterMetadata()
+	if meta.Name != "" {
+		loaded := filter.Open(meta)
+		err := loaded.Load()
+		if err != nil {
+			return err
+		}
+		table.setFilter(loaded)
+		return nil
+	}
+	block, err := table.readFilters()
+	if err != nil {
+		return err
+	}
+	table.filterMut.Lock()
+	defer table.filterMut.Unlock()
+	table.Filter, table.ranges, table.index = block.filter, block.ranges, block.index
+	return nil
+}
+
+// RebuildFilter creates the filter from the keys in the table file, keeping the type and
+// bits per key of the recorded filter
+func (table *SSTable) RebuildFilter() error {
+	keys, err := table.readKeys()
+	if err != nil {
+		return err
+	}
+	meta := table.FilterMetadata()
+	opts := &filter.Opts{Type: meta.Type, Size: meta.Size, Limiter: table.Limiter}
+	if len(keys) > 0 && meta.Size > 0 {
+		opts.Bits_per_key = max(int(meta.Size/uint64(len(keys))), 1)
+	}
+	rebuilt := filter.NewForKeys(opts, len(keys))
+	for _, key := range keys {
+		rebuilt.Add(key)
+	}
+	table.setFilter(rebuilt)
+	return nil
+}
+
+// Returns every key in the table file
+func (table *SSTable) readKeys() ([][]byte, error) {
+	reader, err := table.NewReader(table.Limiter)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	keys := [][]byte{}
+	for {
+		key, err := reader.NextKey()
+		if errors.Is(err, io.EOF) {
+			return keys, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+}
+
+// FilterMetadata describes the filter of the table, safe to call while the filter loads
+func (table *SSTable) FilterMetadata() filter.Metadata {
+	table.filterMut.Lock()
+	defer table.filterMut.Unlock()
+	if table.Filter == nil {
+		return filter.Metadata{}
+	}
+	return table.Filter.Metadata()
+}
+
+func (table *SSTable) setFilter(f filter.Filter) {
+	table.filterMut.Lock()
+	defer table.filterMut.Unlock()
+	table.Filter = f
+}
+
+// EmbedFilter copies a table whose filter is in its own file to a new file beside it, with
+// the filter embedded. The filter is rebuilt from the keys if its file cannot be read.
+func (table *SSTable) EmbedFilter() (*SSTable, error) {
+	err := table.LoadFilter()
+	if err != nil {
+		slog.Warn("Rebuilding table filter", "filename", table.Name, "cause", err)
+		err = table.RebuildFilter()
+		if err != nil {
+			return nil, err
+		}
+	}
+	entries, err := table.ReadEntries()
+	if err != nil {
+		return nil, err
+	}
+	data, err := table.Filter.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("filter.MarshalBinary: %w", err)
+	}
+	meta := table.Filter.Metadata()
+	meta.Name = ""
+	embedded := filter.Open(meta)
+	err = embedded.UnmarshalBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("filter.UnmarshalBinary: %w", err)
+	}
+
+	migrated := &SSTable{
+		Entries:         entries,
+		Filter:          embedded,
+		Name:            filepath.Join(filepath.Dir(table.Name), GenerateUniqueSegmentName(table.CreatedOn)),
+		First:           table.First,
+		Last:            table.Last,
+		CreatedOn:       table.CreatedOn,
+		OldestTombstone: table.OldestTombstone,
+		Run:             table.Run,
+		MinSequence:     table.MinSequence,
+		MaxSequence:     table.MaxSequence,
+		Limiter:         table.Limiter,
+		Cache:           table.Cache,
+	}
+	migrated.filterLoad.Do(func() {})
+	_, err = migrated.Sync()
+	if err != nil {
+		return nil, fmt.Errorf("table.Sync: %w", err)
+	}
+	return migrated, nil
 }
 
 // Read entries into memory & locks table
@@ -149,6 +435,39 @@ func (table *SSTable) Close() error {
 	return nil
 }
 
+// Reads every entry from disk without storing them on the table. Safe for concurrent use.
+func (table *SSTable) ReadEntries() ([]*pb.SSTable_Entry, error) {
+	b, err := os.ReadFile(table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	tbl := &pb.SSTable{}
+	err = proto.Unmarshal(b, tbl)
+	if err != nil {
+		return nil, fmt.Errorf("proto.Unmarshal: %w", err)
+	}
+	return tbl.Entries, nil
+}
+
+// Get searches the table file for key, reading the one data block that may hold it, or
+// the whole file for tables written without an index. Safe for concurrent use.
+func (table *SSTable) Get(key []byte) ([]byte, bool, error) {
+	table.lastRead.Store(time.Now().UnixNano())
+	table.ensureFilters()
+	if table.index != nil {
+		return table.getIndexed(key)
+	}
+	entries, err := table.ReadEntries()
+	if err != nil {
+		return nil, false, err
+	}
+	idx, found := sort.Find(len(entries), func(i int) int { return slices.Compare(key, entries[i].Key) })
+	if found {
+		return entries[idx].Value, true, nil
+	}
+	return []byte{}, false, nil
+}
+
 // Search searches for a key in the SSTable.
 //
 // Panics if attempt to search empty entries array
@@ -168,19 +487,46 @@ func (table *SSTable) ToProto() (*pb.SSTable, error) {
 		return nil, err
 	}
 	p := &pb.SSTable{
-		Entries:   []*pb.SSTable_Entry{},
-		Name:      &table.Name,
-		First:     table.First,
-		Last:      table.Last,
-		Size:      &table.Size,
-		CreatedOn: createdOn,
+		Entries:       []*pb.SSTable_Entry{},
+		Name:          &table.Name,
+		First:         table.First,
+		Last:          table.Last,
+		Size:          &table.Size,
+		CreatedOn:     createdOn,
+		NumEntries:    &table.NumEntries,
+		NumTombstones: &table.NumTombstones,
+		Run:           &table.Run,
 	}
-	if table.Filter == nil {
-		return p, nil
+	if table.MaxSequence > 0 {
+		p.MinSequence, p.MaxSequence = &table.MinSequence, &table.MaxSequence
 	}
-	p.Filter = &pb.SSTable_Filter{
-		Name: table.Filter.Name,
-		Size: table.Filter.Size,
+	if !table.OldestTombstone.IsZero() {
+		p.OldestTombstone, err = table.OldestTombstone.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+	}
+	table.filterMut.Lock()
+	defer table.filterMut.Unlock()
+	if table.Filter != nil {
+		p.Filter = filterProto(table.Filter.Metadata(), nil)
 	}
 	return p, nil
 }
+
+// Describes a filter in the manifest, or in the filter block of a table file with its data
+func filterProto(meta filter.Metadata, data []byte) *pb.SSTable_Filter {
+	p := &pb.SSTable_Filter{
+		Name: meta.Name,
+		Size: meta.Size,
+		Data: data,
+	}
+	if meta.Hashes > 0 {
+		p.Hashes = &meta.Hashes
+	}
+	if meta.Type != filter.BLOOM {
+		filterType := int32(meta.Type)
+		p.Type = &filterType
+	}
+	return p
+}
@@ -1,13 +1,18 @@
 package sstable
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"path/filepath"
 	"slices"
 	"testing"
 	"time"
 
+	"github.com/dillonkmcquade/gostore/internal/cache"
 	"github.com/dillonkmcquade/gostore/internal/filter"
 	"github.com/dillonkmcquade/gostore/internal/pb"
+	"github.com/dillonkmcquade/gostore/internal/ratelimit"
 )
 
 func TestSSTableOverlaps(t *testing.T) {
@@ -106,6 +111,30 @@ func TestSSTableIO(t *testing.T) {
 		}
 	})
 
+	t.Run("Rate limited sync", func(t *testing.T) {
+		tmp := t.TempDir()
+		limiter := ratelimit.New(50)
+		t1 := New(&Opts{
+			BloomOpts: &filter.Opts{Size: 100, Path: tmp},
+			DestDir:   tmp,
+			Entries:   testEntries(),
+			Limiter:   limiter,
+		})
+		if t1.Filter.(*filter.BloomFilter).Limiter != limiter {
+			t.Error("Filter should share the table limiter")
+		}
+		start := time.Now()
+		size, err := t1.Sync()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The first 50 bytes are available at once
+		expected := time.Duration(float64(size-50) / 50 * float64(time.Second))
+		if elapsed := time.Since(start); elapsed < expected*8/10 {
+			t.Errorf("Expected sync to take about %v, took %v", expected, elapsed)
+		}
+	})
+
 	t.Run("Open/Close", func(t *testing.T) {
 		tmp := t.TempDir()
 		filename := filepath.Join(tmp, "loadtest")
@@ -115,7 +144,6 @@ func TestSSTableIO(t *testing.T) {
 			Name:    filename,
 			Filter: filter.New(&filter.Opts{
 				Size: 1000,
-				Path: tmp,
 			}),
 			First:     []byte{0},
 			Last:      []byte{100},
@@ -128,10 +156,6 @@ func TestSSTableIO(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
-		err = t1.SaveFilter()
-		if err != nil {
-			t.Error(err)
-		}
 		t1.Filter.Clear()
 		err = t1.LoadFilter()
 		if err != nil {
@@ -165,6 +189,325 @@ func TestSSTableIO(t *testing.T) {
 	})
 }
 
+func TestSSTableFilter(t *testing.T) {
+	for _, filterType := range []filter.Type{filter.BLOOM, filter.BLOCKED_BLOOM, filter.XOR} {
+		t.Run(fmt.Sprintf("Test filter type %v", filterType), func(t *testing.T) {
+			tmp := t.TempDir()
+			table := New(&Opts{
+				BloomOpts: &filter.Opts{Type: filterType, Bits_per_key: 16},
+				DestDir:   tmp,
+				Entries:   testEntries(),
+			})
+			for _, entry := range testEntries() {
+				if !table.Filter.Has(entry.Key) {
+					t.Errorf("Expected the filter to hold key %v", entry.Key)
+				}
+			}
+			if _, err := table.Sync(); err != nil {
+				t.Fatal(err)
+			}
+
+			pto, err := table.ToProto()
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := FromProto(pto)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded.Filter.Metadata() != table.Filter.Metadata() {
+				t.Errorf("Expected filter %+v, found %+v", table.Filter.Metadata(), decoded.Filter.Metadata())
+			}
+			if err := decoded.LoadFilter(); err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range testEntries() {
+				if !decoded.Filter.Has(entry.Key) {
+					t.Errorf("Expected the loaded filter to hold key %v", entry.Key)
+				}
+			}
+		})
+	}
+
+	t.Run("Test sized from entries", func(t *testing.T) {
+		table := New(&Opts{BloomOpts: &filter.Opts{Bits_per_key: 16}, Entries: testEntries()})
+		if size := table.Filter.Metadata().Size; size != uint64(16*len(testEntries())) {
+			t.Errorf("Expected the filter sized from the entries, found %v bits", size)
+		}
+	})
+}
+
+func TestEmbeddedFilter(t *testing.T) {
+	t.Run("Test loaded on first lookup", func(t *testing.T) {
+		tmp := t.TempDir()
+		table := New(&Opts{BloomOpts: &filter.Opts{}, DestDir: tmp, Entries: testEntries()})
+		if _, err := table.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		pto, err := table.ToProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name := pto.GetFilter().GetName(); name != "" {
+			t.Errorf("Expected no filter file, found %v", name)
+		}
+		decoded, err := FromProto(pto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range testEntries() {
+			if !decoded.MayContain(entry.Key) {
+				t.Errorf("Expected the embedded filter to hold key %v", entry.Key)
+			}
+		}
+		if decoded.filterMissing {
+			t.Error("Expected the embedded filter to be read from the table file")
+		}
+		// The filter block does not change the entries read from the file
+		entries, err := decoded.ReadEntries()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != len(testEntries()) {
+			t.Errorf("Expected %v entries, found %v", len(testEntries()), len(entries))
+		}
+	})
+
+	t.Run("Test filter file", func(t *testing.T) {
+		tmp := t.TempDir()
+		table := newSyncedTable(t, tmp, testEntries())
+		sidecar := filter.New(&filter.Opts{Size: 1000, Path: tmp})
+		for _, entry := range testEntries() {
+			sidecar.Add(entry.Key)
+		}
+		if err := sidecar.Save(); err != nil {
+			t.Fatal(err)
+		}
+		table.Filter = filter.Open(sidecar.Metadata())
+
+		migrated, err := table.EmbedFilter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if migrated.Name == table.Name || migrated.FilterMetadata().Name != "" {
+			t.Errorf("Expected a new table file with an embedded filter, found %v with filter %+v", migrated.Name, migrated.FilterMetadata())
+		}
+		if err := migrated.LoadFilter(); err != nil {
+			t.Fatal(err)
+		}
+		if migrated.FilterMetadata().Size != 1000 {
+			t.Errorf("Expected the filter of 1000 bits to be kept, found %+v", migrated.FilterMetadata())
+		}
+		for _, entry := range testEntries() {
+			if !migrated.MayContain(entry.Key) {
+				t.Errorf("Expected the migrated filter to hold key %v", entry.Key)
+			}
+		}
+	})
+
+	t.Run("Test rebuilt when missing", func(t *testing.T) {
+		tmp := t.TempDir()
+		table := newSyncedTable(t, tmp, testEntries())
+		table.Filter = filter.Open(filter.Metadata{Type: filter.BLOCKED_BLOOM, Name: filepath.Join(tmp, "missing.dat"), Size: 512, Hashes: 7})
+		if err := table.LoadFilter(); err == nil {
+			t.Fatal("Expected an error loading a missing filter")
+		}
+		for _, entry := range testEntries() {
+			if !table.MayContain(entry.Key) {
+				t.Errorf("Expected the rebuilt filter to hold key %v", entry.Key)
+			}
+		}
+		if table.filterMissing || table.FilterMetadata().Type != filter.BLOCKED_BLOOM {
+			t.Errorf("Expected a rebuilt blocked bloom filter, found %+v", table.FilterMetadata())
+		}
+	})
+
+	t.Run("Test no filter", func(t *testing.T) {
+		table := newSyncedTable(t, t.TempDir(), testEntries())
+		if _, err := table.readFilters(); !errors.Is(err, ErrNoFilter) {
+			t.Errorf("Expected ErrNoFilter, found %v", err)
+		}
+	})
+}
+
+func TestTableRangeFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    []byte
+		end      []byte
+		expected bool
+	}{
+		{name: "Test holding a key", start: []byte{2}, end: []byte{4}, expected: true},
+		{name: "Test gap", start: []byte{6}, end: []byte{100}, expected: false},
+		{name: "Test last key", start: []byte{100}, end: nil, expected: true},
+		{name: "Test after last key", start: []byte{101}, end: nil, expected: false},
+		{name: "Test before first key", start: []byte{}, end: []byte{0}, expected: false},
+	}
+	tmp := t.TempDir()
+	table := New(&Opts{BloomOpts: &filter.Opts{}, RangeOpts: &filter.RangeOpts{}, DestDir: tmp, Entries: testEntries()})
+	table.First, table.Last = []byte{0}, []byte{100}
+	if _, err := table.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	pto, err := table.ToProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := FromProto(pto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if found := decoded.MayContainRange(tt.start, tt.end); found != tt.expected {
+				t.Errorf("Expected %v for [%v, %v), found %v", tt.expected, tt.start, tt.end, found)
+			}
+		})
+	}
+
+	t.Run("Test without range filter", func(t *testing.T) {
+		table := New(&Opts{BloomOpts: &filter.Opts{}, DestDir: tmp, Entries: testEntries()})
+		table.First, table.Last = []byte{0}, []byte{100}
+		if !table.MayContainRange([]byte{6}, []byte{100}) {
+			t.Error("Expected a table without range filter to match within its bounds")
+		}
+		if table.MayContainRange([]byte{101}, nil) {
+			t.Error("Expected a table not to match after its last key")
+		}
+	})
+}
+
+func TestPartitionedIndex(t *testing.T) {
+	entries := []*pb.SSTable_Entry{}
+	for i := 0; i < 2000; i++ {
+		entries = append(entries, &pb.SSTable_Entry{
+			Op:    pb.Operation_OPERATION_INSERT,
+			Key:   []byte(fmt.Sprintf("key%06d", i*2)),
+			Value: []byte(fmt.Sprintf("value%06d", i*2)),
+		})
+	}
+	for _, filterType := range []filter.Type{filter.BLOOM, filter.XOR} {
+		t.Run(fmt.Sprintf("Test filter type %v", filterType), func(t *testing.T) {
+			blockCache := cache.New(1 << 20)
+			table := New(&Opts{
+				BloomOpts:     &filter.Opts{Type: filterType},
+				DestDir:       t.TempDir(),
+				Entries:       entries,
+				Cache:         blockCache,
+				BlockSize:     256,
+				PartitionSize: 256,
+			})
+			table.First, table.Last = entries[0].Key, entries[len(entries)-1].Key
+			if _, err := table.Sync(); err != nil {
+				t.Fatal(err)
+			}
+			pto, err := table.ToProto()
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := FromProto(pto)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded.Cache = blockCache
+
+			for _, tbl := range []*SSTable{table, decoded} {
+				for _, entry := range entries {
+					if !tbl.MayContain(entry.Key) {
+						t.Fatalf("Expected the filter partitions to hold key %s", entry.Key)
+					}
+					val, found, err := tbl.Get(entry.Key)
+					if err != nil || !found || !slices.Equal(val, entry.Value) {
+						t.Fatalf("Expected %s for key %s, found %s, %v, %v", entry.Value, entry.Key, val, found, err)
+					}
+				}
+				for _, key := range []string{"a", "key000001", "key003999", "z"} {
+					if _, found, err := tbl.Get([]byte(key)); err != nil || found {
+						t.Errorf("Expected key %v not to be found, found %v, %v", key, found, err)
+					}
+				}
+			}
+			if !decoded.partitionedFilter() || len(decoded.index.Entries) < 2 {
+				t.Fatal("Expected the table to have several index and filter partitions")
+			}
+			if blockCache.Hits() == 0 {
+				t.Error("Expected lookups to share cached blocks")
+			}
+			read, err := decoded.ReadEntries()
+			if err != nil || len(read) != len(entries) {
+				t.Errorf("Expected the table file to decode to its %v entries, found %v, %v", len(entries), len(read), err)
+			}
+		})
+	}
+
+	t.Run("Test small tables keep one filter", func(t *testing.T) {
+		table := New(&Opts{BloomOpts: &filter.Opts{}, DestDir: t.TempDir(), Entries: testEntries()})
+		if _, err := table.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		if table.index == nil || table.partitionedFilter() {
+			t.Fatal("Expected an index with a single partition")
+		}
+		if val, found, err := table.Get([]byte{5}); err != nil || !found || string(val) != "TESTVALUE5" {
+			t.Errorf("Expected TESTVALUE5, found %s, %v, %v", val, found, err)
+		}
+	})
+}
+
+func TestApproximateRange(t *testing.T) {
+	entries := []*pb.SSTable_Entry{}
+	for i := 0; i < 2000; i++ {
+		entries = append(entries, &pb.SSTable_Entry{
+			Op:    pb.Operation_OPERATION_INSERT,
+			Key:   []byte(fmt.Sprintf("key%06d", i*2)),
+			Value: []byte(fmt.Sprintf("value%06d", i*2)),
+		})
+	}
+	table := New(&Opts{BloomOpts: &filter.Opts{}, DestDir: t.TempDir(), Entries: entries, BlockSize: 256, PartitionSize: 256})
+	table.First, table.Last = entries[0].Key, entries[len(entries)-1].Key
+	if _, err := table.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	pto, err := table.ToProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := FromProto(pto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		start []byte
+		end   []byte
+		count int64 // Entries in the range
+	}{
+		{name: "Test whole table", start: nil, end: nil, count: 2000},
+		{name: "Test first half", start: nil, end: []byte("key002000"), count: 1000},
+		{name: "Test second half", start: []byte("key002000"), end: nil, count: 1000},
+		{name: "Test middle", start: []byte("key001000"), end: []byte("key003000"), count: 1000},
+		{name: "Test before first key", start: []byte("a"), end: []byte("key"), count: 0},
+		{name: "Test after last key", start: []byte("key004000"), end: nil, count: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, count, err := decoded.ApproximateRange(tt.start, tt.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Partial ranges are measured to the data block, about 10 entries
+			if count < tt.count-20 || count > tt.count+20 {
+				t.Errorf("Expected about %v entries, found %v", tt.count, count)
+			}
+			expectedSize := decoded.Size * tt.count / 2000
+			if size < expectedSize*9/10 || size > expectedSize*11/10 {
+				t.Errorf("Expected about %v bytes, found %v", expectedSize, size)
+			}
+		})
+	}
+}
+
 func TestSSTableSearch(t *testing.T) {
 	tmp := t.TempDir()
 	filename := filepath.Join(tmp, "loadtest")
@@ -243,3 +586,42 @@ func testEntries() []*pb.SSTable_Entry {
 		},
 	}
 }
+
+func TestBlockKeys(t *testing.T) {
+	entries := []*pb.SSTable_Entry{}
+	for i := 0; i < 2000; i++ {
+		entries = append(entries, &pb.SSTable_Entry{
+			Op:    pb.Operation_OPERATION_INSERT,
+			Key:   []byte(fmt.Sprintf("key%06d", i)),
+			Value: []byte(fmt.Sprintf("value%06d", i)),
+		})
+	}
+	table := New(&Opts{BloomOpts: &filter.Opts{}, DestDir: t.TempDir(), Entries: entries, BlockSize: 256, PartitionSize: 256})
+	table.First, table.Last = entries[0].Key, entries[len(entries)-1].Key
+	if _, err := table.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	pto, err := table.ToProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := FromProto(pto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := decoded.BlockKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Blocks of 256 bytes hold about 10 entries
+	if len(keys) < 50 || len(keys) > 400 {
+		t.Errorf("Expected a key per data block, found %v keys", len(keys))
+	}
+	if !slices.IsSortedFunc(keys, bytes.Compare) {
+		t.Error("Expected block keys in key order")
+	}
+	if !bytes.Equal(keys[len(keys)-1], table.Last) {
+		t.Errorf("Expected the last block key to be the last key, found %s", keys[len(keys)-1])
+	}
+}
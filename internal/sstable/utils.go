@@ -3,38 +3,14 @@ package sstable
 import (
 	"fmt"
 	"log/slog"
-	"slices"
 	"time"
 
 	"github.com/dillonkmcquade/gostore/internal"
 	"github.com/dillonkmcquade/gostore/internal/assert"
 	"github.com/dillonkmcquade/gostore/internal/filter"
-	"github.com/dillonkmcquade/gostore/internal/ordered"
 	"github.com/dillonkmcquade/gostore/internal/pb"
 )
 
-// Return sorted output stream of SSTable_Entry from an arbitrary number of tables
-func Merge(tables ...*SSTable) <-chan *pb.SSTable_Entry {
-	tree := ordered.Rbt[[]byte, *pb.SSTable_Entry](slices.Compare[[]byte])
-	for _, table := range tables {
-		if len(table.Entries) == 0 {
-			err := table.Open()
-			defer table.Close()
-			if err != nil {
-				slog.Error("merge: error opening table", "filename", table.Name)
-				panic(err)
-			}
-		}
-		assert.True(len(table.Entries) > 0, "Expected table with entries, found %v entries", len(table.Entries))
-
-		for _, entry := range table.Entries {
-			tree.Put(entry.Key, entry)
-		}
-	}
-
-	return tree.Values()
-}
-
 // Find and return the oldest table
 func Oldest(tables []*SSTable) *SSTable {
 	// Tables should never be empty if it triggered compaction
@@ -50,30 +26,31 @@ func Oldest(tables []*SSTable) *SSTable {
 	return oldest
 }
 
-// Form SSTables of maxSize from input stream
+// Form SSTables of maxSize from input stream. Each table is created once its entries are
+// known, so that its filter is sized for them.
 func Split(in <-chan *pb.SSTable_Entry, maxSize int, tableOpts *Opts) <-chan *SSTable {
 	ch := make(chan *SSTable)
 
 	go func() {
-		tbl := New(tableOpts)
 		defer close(ch)
+		emit := func(entries []*pb.SSTable_Entry) {
+			opts := *tableOpts
+			opts.Entries = entries
+			tbl := New(&opts)
+			tbl.First = entries[0].Key
+			tbl.Last = entries[len(entries)-1].Key
+			ch <- tbl
+		}
+		entries := []*pb.SSTable_Entry{}
 		for entry := range in {
-			tbl.Entries = append(tbl.Entries, entry)
-			if len(tbl.Entries) == 1 {
-				tbl.First = entry.Key
-			}
-
-			tbl.Filter.Add(entry.Key)
-
-			if len(tbl.Entries) >= maxSize {
-				tbl.Last = tbl.Entries[len(tbl.Entries)-1].Key
-				ch <- tbl
-				tbl = New(tableOpts)
+			entries = append(entries, entry)
+			if len(entries) >= maxSize {
+				emit(entries)
+				entries = []*pb.SSTable_Entry{}
 			}
 		}
-		if len(tbl.Entries) > 0 {
-			tbl.Last = tbl.Entries[len(tbl.Entries)-1].Key
-			ch <- tbl
+		if len(entries) > 0 {
+			emit(entries)
 		}
 	}()
 
@@ -111,15 +88,33 @@ func FromProto(p *pb.SSTable) (*SSTable, error) {
 		return nil, err
 	}
 	t := &SSTable{
-		Entries: p.GetEntries(),
-		Filter: &filter.BloomFilter{
-			Name: p.GetFilter().GetName(),
-			Size: p.GetFilter().GetSize(),
-		},
-		Size:  p.GetSize(),
-		Name:  p.GetName(),
-		First: p.GetFirst(),
-		Last:  p.GetLast(),
+		Entries:       p.GetEntries(),
+		Filter:        filter.Open(filterMetadata(p.GetFilter())),
+		Size:          p.GetSize(),
+		Name:          p.GetName(),
+		First:         p.GetFirst(),
+		Last:          p.GetLast(),
+		CreatedOn:     tm,
+		NumEntries:    p.GetNumEntries(),
+		NumTombstones: p.GetNumTombstones(),
+		Run:           p.GetRun(),
+		MinSequence:   p.GetMinSequence(),
+		MaxSequence:   p.GetMaxSequence(),
+	}
+	if p.OldestTombstone != nil {
+		err = t.OldestTombstone.UnmarshalBinary(p.GetOldestTombstone())
+		if err != nil {
+			return nil, err
+		}
 	}
 	return t, nil
 }
+
+func filterMetadata(p *pb.SSTable_Filter) filter.Metadata {
+	return filter.Metadata{
+		Type:   filter.Type(p.GetType()),
+		Name:   p.GetName(),
+		Size:   p.GetSize(),
+		Hashes: p.GetHashes(),
+	}
+}
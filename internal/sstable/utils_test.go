@@ -111,23 +111,6 @@ func TestFindOldestTable(t *testing.T) {
 	})
 }
 
-func TestMerge(t *testing.T) {
-	total := 0
-	for _, tbl := range []*SSTable{t1, t2} {
-		total += len(tbl.Entries)
-	}
-
-	merged := Merge(t1, t2)
-	count := 0
-
-	for range merged {
-		count++
-	}
-	if count != total {
-		t.Errorf("%v should equal %v", count, total)
-	}
-}
-
 func TestSplit(t *testing.T) {
 	tmp := t.TempDir()
 	tbl := &SSTable{
@@ -0,0 +1,145 @@
+package sstable
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/dillonkmcquade/gostore/internal/filter"
+	"github.com/dillonkmcquade/gostore/internal/pb"
+)
+
+var (
+	// ErrOutOfOrder is returned when the keys of a table are not strictly increasing
+	ErrOutOfOrder = errors.New("keys are not in increasing order")
+
+	// ErrEmptyTable is returned when finishing or ingesting a table without entries
+	ErrEmptyTable = errors.New("table has no entries")
+)
+
+// SSTWriter builds a table file offline, e.g. for a bulk load ingested with
+// GoStore.IngestExternalFiles instead of written through the memtable.
+//
+// Keys must be added in strictly increasing order. Entries are held in memory until
+// Finish, so large loads should be split across several writers.
+type SSTWriter struct {
+	path    string
+	opts    Opts
+	entries []*pb.SSTable_Entry
+}
+
+// NewSSTWriter returns a writer of the table file at path. The filter, range filter, block
+// and collector options of opts apply, DestDir and Entries are ignored.
+func NewSSTWriter(path string, opts *Opts) *SSTWriter {
+	w := &SSTWriter{path: path}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.BloomOpts == nil {
+		w.opts.BloomOpts = &filter.Opts{}
+	}
+	return w
+}
+
+// Put adds the key-value pair to the table
+func (w *SSTWriter) Put(key []byte, value []byte) error {
+	return w.add(&pb.SSTable_Entry{Op: pb.Operation_OPERATION_INSERT, Key: slices.Clone(key), Value: slices.Clone(value)})
+}
+
+// Delete adds a delete of key to the table, hiding the older values of key once ingested
+func (w *SSTWriter) Delete(key []byte) error {
+	return w.add(&pb.SSTable_Entry{Op: pb.Operation_OPERATION_DELETE, Key: slices.Clone(key)})
+}
+
+func (w *SSTWriter) add(entry *pb.SSTable_Entry) error {
+	if n := len(w.entries); n > 0 && bytes.Compare(entry.Key, w.entries[n-1].Key) <= 0 {
+		return fmt.Errorf("%w: %q after %q", ErrOutOfOrder, entry.Key, w.entries[n-1].Key)
+	}
+	w.entries = append(w.entries, entry)
+	return nil
+}
+
+// Finish writes the table file, replacing any file at its path, and returns the table
+func (w *SSTWriter) Finish() (*SSTable, error) {
+	if len(w.entries) == 0 {
+		return nil, ErrEmptyTable
+	}
+	opts := w.opts
+	opts.Entries = w.entries
+	table := New(&opts)
+	table.Name = w.path
+	table.First = w.entries[0].Key
+	table.Last = w.entries[len(w.entries)-1].Key
+
+	var err error
+	table.file, err = os.OpenFile(w.path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("os.OpenFile: %w", err)
+	}
+	_, err = table.Sync()
+	if err != nil {
+		return nil, fmt.Errorf("table.Sync: %w", err)
+	}
+	w.entries = nil
+	return table, nil
+}
+
+// OpenExternal validates a table file written outside the store, such as by an SSTWriter,
+// and returns the table it holds. Its entries must be in strictly increasing key order and
+// it must have a filter block. The table has no run until it is added to a manifest.
+func OpenExternal(path string) (*SSTable, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Stat: %w", err)
+	}
+	table := &SSTable{Name: path, Size: info.Size(), CreatedOn: time.Now()}
+	reader, err := table.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: reader.Next: %w", path, err)
+		}
+		if table.NumEntries > 0 && bytes.Compare(entry.Key, table.Last) <= 0 {
+			return nil, fmt.Errorf("%v: %w", path, ErrOutOfOrder)
+		}
+		if table.NumEntries == 0 {
+			table.First = entry.Key
+		}
+		table.Last = entry.Key
+		table.NumEntries++
+		if entry.Op == pb.Operation_OPERATION_DELETE {
+			table.NumTombstones++
+		}
+	}
+	if table.NumEntries == 0 {
+		return nil, fmt.Errorf("%v: %w", path, ErrEmptyTable)
+	}
+	if table.NumTombstones > 0 {
+		table.OldestTombstone = table.CreatedOn
+	}
+
+	err = table.LoadFilter()
+	if err != nil {
+		return nil, fmt.Errorf("%v: table.LoadFilter: %w", path, err)
+	}
+	table.filterLoad.Do(func() {})
+	props, err := table.ReadProperties()
+	if err != nil {
+		return nil, fmt.Errorf("%v: table.ReadProperties: %w", path, err)
+	}
+	if props.NumEntries != table.NumEntries || props.NumTombstones != table.NumTombstones {
+		return nil, fmt.Errorf("%v: properties record %v entries and %v tombstones, found %v and %v", path, props.NumEntries, props.NumTombstones, table.NumEntries, table.NumTombstones)
+	}
+	return table, nil
+}
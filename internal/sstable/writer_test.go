@@ -0,0 +1,80 @@
+package sstable
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dillonkmcquade/gostore/internal/filter"
+)
+
+func TestSSTWriter(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Test write and open", func(t *testing.T) {
+		path := filepath.Join(dir, "bulk.segment")
+		w := NewSSTWriter(path, &Opts{BloomOpts: &filter.Opts{Type: filter.XOR}, BlockSize: 128, PartitionSize: 64})
+		for i := 0; i < 500; i++ {
+			if err := w.Put([]byte(fmt.Sprintf("%04d", i)), []byte(fmt.Sprintf("value%v", i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Delete([]byte("0500")); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Put([]byte("0100"), []byte("value")); !errors.Is(err, ErrOutOfOrder) {
+			t.Errorf("Expected ErrOutOfOrder, found %v", err)
+		}
+		if _, err := w.Finish(); err != nil {
+			t.Fatal(err)
+		}
+
+		table, err := OpenExternal(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(table.First) != "0000" || string(table.Last) != "0500" {
+			t.Errorf("Expected bounds 0000 and 0500, found %s and %s", table.First, table.Last)
+		}
+		if table.NumEntries != 501 || table.NumTombstones != 1 || table.Run != 0 {
+			t.Errorf("Expected 501 entries, 1 tombstone and no run, found %v, %v and %v", table.NumEntries, table.NumTombstones, table.Run)
+		}
+		if table.FilterMetadata().Type != filter.XOR || !table.MayContain([]byte("0250")) {
+			t.Error("Expected the filter of the file")
+		}
+		value, found, err := table.Get([]byte("0250"))
+		if err != nil || !found || string(value) != "value250" {
+			t.Errorf("Expected value250, found %s, %v, %v", value, found, err)
+		}
+	})
+
+	t.Run("Test empty table", func(t *testing.T) {
+		if _, err := NewSSTWriter(filepath.Join(dir, "empty.segment"), nil).Finish(); !errors.Is(err, ErrEmptyTable) {
+			t.Errorf("Expected ErrEmptyTable, found %v", err)
+		}
+	})
+
+	t.Run("Test invalid files", func(t *testing.T) {
+		// Tables written without a filter have no filter block to validate
+		noFilter := &SSTable{Name: filepath.Join(dir, "nofilter.segment"), Entries: testEntries()}
+		if _, err := noFilter.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenExternal(noFilter.Name); !errors.Is(err, ErrNoFilter) {
+			t.Errorf("Expected ErrNoFilter, found %v", err)
+		}
+
+		garbage := filepath.Join(dir, "garbage.segment")
+		if err := os.WriteFile(garbage, []byte("not a table"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenExternal(garbage); err == nil {
+			t.Error("Expected an error opening a file that is not a table")
+		}
+		if _, err := OpenExternal(filepath.Join(dir, "missing.segment")); err == nil {
+			t.Error("Expected an error opening a missing file")
+		}
+	})
+}
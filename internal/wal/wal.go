@@ -145,6 +145,24 @@ func (self *WAL[T]) Write(entry T) error {
 	return nil
 }
 
+// WriteSync writes a log entry and waits until it has been synced to stable storage.
+//
+// Entries still queued by Write may be persisted after entries written with WriteSync,
+// so a log should use one or the other.
+func (self *WAL[T]) WriteSync(entry T) error {
+	self.mut.Lock()
+	defer self.mut.Unlock()
+	writer := NewBatchWriter(self.file)
+	writer.Write(entry)
+	if err := writer.Err(); err != nil {
+		return fmt.Errorf("writer.Write: %w", err)
+	}
+	if err := self.file.Sync(); err != nil {
+		return fmt.Errorf("file.Sync: %w", err)
+	}
+	return nil
+}
+
 // Close closes the writeChan, and waits for the queued writes to finish.
 func (self *WAL[T]) Close() error {
 	close(self.writeChan)
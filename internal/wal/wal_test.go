@@ -60,6 +60,37 @@ func TestWALDecode(t *testing.T) {
 	}
 }
 
+func TestWALWriteSync(t *testing.T) {
+	tmpdir := t.TempDir()
+	wal, err := New[*testProtobuf.TestEntry](filepath.Join(tmpdir, "wal.db"), 10)
+	if err != nil {
+		t.Error(err)
+	}
+	defer wal.Close()
+	for i := 0; i < 3; i++ {
+		err = wal.WriteSync(&testProtobuf.TestEntry{Name: "TEST"})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Entries should be readable without waiting for a full batch
+	file, err := os.Open(filepath.Join(tmpdir, "wal.db"))
+	if err != nil {
+		t.Error(err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Split(SplitProtobuf)
+	count := 0
+	for scanner.Scan() {
+		count++
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 entries, found %v", count)
+	}
+}
+
 func TestWALDiscard(t *testing.T) {
 	tmpdir := t.TempDir()
 	wal, err := New[*testProtobuf.TestEntry](filepath.Join(tmpdir, "wal.dat"), 10)